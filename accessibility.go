@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+var (
+	// accessible is a flag to show text markers for signals that are otherwise only shown with colors.
+	accessible = flag.Bool("accessible", false, "Show +/- markers for the size of price changes instead of relying on colors.")
+
+	// plain is a flag to print a plain text line per stock for screen readers and exit.
+	plain = flag.Bool("plain", false, "Print one plain text line per stock suitable for screen readers and exit.")
+)
+
+// colorLevel returns the index into colorLevels of the percent change.
+func colorLevel(percentChange float64) int {
+	c := 0
+	absChange := math.Abs(percentChange)
+	for ; c < len(colorLevels)-1; c++ {
+		if absChange < colorLevels[c+1] {
+			break
+		}
+	}
+	return c
+}
+
+// changeMarker returns a marker like "+", "++", or "---" conveying the direction and size of the change
+// that the background color otherwise shows.
+func changeMarker(ts stockTradingSession) string {
+	n := colorLevel(ts.percentChange) + 1
+	if n > 3 {
+		n = 3
+	}
+	switch {
+	case ts.change > 0:
+		return strings.Repeat("+", n)
+	case ts.change < 0:
+		return strings.Repeat("-", n)
+	default:
+		return "="
+	}
+}
+
+// describeChange returns words describing the session like "up 1.25 or 0.69 percent".
+func describeChange(ts stockTradingSession) string {
+	switch {
+	case ts.change > 0:
+		return fmt.Sprintf("up %.2f or %.2f percent", ts.change, ts.percentChange*100)
+	case ts.change < 0:
+		return fmt.Sprintf("down %.2f or %.2f percent", -ts.change, -ts.percentChange*100)
+	default:
+		return "unchanged"
+	}
+}
+
+// printPlain prints the indices and one line per stock with their latest sessions.
+// The caller must hold the stockData read lock.
+func printPlain(w io.Writer, sd *stockData) {
+	fmt.Fprintf(w, "Refreshed %s.\n", formatDisplayTime(sd.refreshTime, "Monday, January 2 3:04 PM"))
+
+	for _, idx := range []struct {
+		name string
+		ts   stockTradingSession
+	}{
+		{"Dow", sd.dow},
+		{"S&P 500", sd.sap},
+		{"Nasdaq", sd.nasdaq},
+	} {
+		fmt.Fprintf(w, "%s %.2f, %s.\n", idx.name, idx.ts.close, describeChange(idx.ts))
+	}
+
+	for _, s := range sd.stocks {
+		ts, ok := latestTradingSession(s.tradingSessionMap)
+		if !ok {
+			fmt.Fprintf(w, "%s, no data.\n", s.symbol)
+			continue
+		}
+		fmt.Fprintf(w, "%s %.2f, %s, volume %s, on %s.\n", s.symbol, ts.close, describeChange(ts), shortenInt(ts.volume), ts.date.Format("Monday, January 2"))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// aggregation is how the daily trading sessions are combined into the grid's columns.
+type aggregation int
+
+// List of possible aggregation values.
+const (
+	dailyAggregation aggregation = iota
+	weeklyAggregation
+	monthlyAggregation
+	aggregationCount
+)
+
+// next returns the aggregation to cycle to after this one.
+func (a aggregation) next() aggregation {
+	return (a + 1) % aggregationCount
+}
+
+// periodStart returns the first date of the period containing the date.
+func (a aggregation) periodStart(date time.Time) time.Time {
+	switch a {
+	case weeklyAggregation:
+		// Weeks start on Monday since there are no sessions on the weekend.
+		return date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+	case monthlyAggregation:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	default:
+		return date
+	}
+}
+
+// historyStart returns how far back history is needed to fill the columns as of the end date.
+func (a aggregation) historyStart(end time.Time) time.Time {
+	switch a {
+	case weeklyAggregation:
+		return end.AddDate(0, -6, 0)
+	case monthlyAggregation:
+		return end.AddDate(-3, 0, 0)
+	default:
+		return end.AddDate(0, 0, -30)
+	}
+}
+
+// dateLabels returns the two header lines of the period's column.
+func (a aggregation) dateLabels(date time.Time) (string, string) {
+	switch a {
+	case weeklyAggregation:
+		return date.Format("1/2"), "Wk"
+	case monthlyAggregation:
+		return date.Format("Jan"), date.Format("2006")
+	default:
+		return date.Format("1/2"), date.Format("Mon")
+	}
+}
+
+// aggregateSessions combines the daily sessions into sessions keyed by the start of their period.
+// Each period has the close of its last day and the summed volume. The change is from the close
+// before the period's first day, which is derived from that day's change.
+func aggregateSessions(tsm map[time.Time]stockTradingSession, a aggregation) map[time.Time]stockTradingSession {
+	if a == dailyAggregation {
+		return tsm
+	}
+
+	var dates []time.Time
+	for date := range tsm {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	am := map[time.Time]stockTradingSession{}
+	prevClose := map[time.Time]float64{}
+	for _, date := range dates {
+		ts := tsm[date]
+		p := a.periodStart(date)
+
+		pts, ok := am[p]
+		if !ok {
+			pts.date = p
+			pts.open = ts.open
+			pts.high, pts.low = ts.high, ts.low
+			prevClose[p] = ts.close - ts.change
+		}
+		pts.high = math.Max(pts.high, ts.high)
+		if ts.low > 0 && (pts.low == 0 || ts.low < pts.low) {
+			pts.low = ts.low
+		}
+		pts.close = ts.close
+		pts.volume += ts.volume
+		pts.change = pts.close - prevClose[p]
+		if prevClose[p] != 0 {
+			pts.percentChange = pts.change / prevClose[p]
+		}
+		am[p] = pts
+	}
+	return am
+}
+
+// aggregateDates returns the chronological period starts of the stocks' aggregated sessions.
+func aggregateDates(stocks []stock) []time.Time {
+	dm := map[time.Time]bool{}
+	for _, s := range stocks {
+		for date := range s.tradingSessionMap {
+			dm[date] = true
+		}
+	}
+
+	var dates []time.Time
+	for date := range dm {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates
+}
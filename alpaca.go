@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	// alpacaURL is a flag to set the Alpaca trading API endpoint.
+	alpacaURL = flag.String("alpaca_url", "https://paper-api.alpaca.markets", "Alpaca trading API endpoint. Use https://api.alpaca.markets for live trading.")
+
+	// alpacaOrders is a flag to allow submitting orders to Alpaca.
+	alpacaOrders = flag.Bool("alpaca_orders", false, "Allow submitting orders to Alpaca with Ctrl+O.")
+)
+
+// Environment variables with the Alpaca API credentials.
+const (
+	alpacaKeyIDEnv     = "APCA_API_KEY_ID"
+	alpacaSecretKeyEnv = "APCA_API_SECRET_KEY"
+)
+
+// alpacaConfigKeyID and alpacaConfigSecretKey are the credentials from the config that take
+// precedence over the environment.
+var alpacaConfigKeyID, alpacaConfigSecretKey string
+
+// setAlpacaCredentials sets the credentials from the config if both are set and the endpoint if set.
+func setAlpacaCredentials(keyID, secretKey, url string) {
+	if keyID != "" && secretKey != "" {
+		alpacaConfigKeyID, alpacaConfigSecretKey = keyID, secretKey
+	}
+	if url != "" {
+		*alpacaURL = url
+	}
+}
+
+// alpacaCredentials returns the credentials from the config or the environment.
+func alpacaCredentials() (keyID, secretKey string) {
+	if alpacaConfigKeyID != "" {
+		return alpacaConfigKeyID, alpacaConfigSecretKey
+	}
+	return os.Getenv(alpacaKeyIDEnv), os.Getenv(alpacaSecretKeyEnv)
+}
+
+// hasAlpacaCredentials returns true if the Alpaca credentials are set in the config or environment.
+func hasAlpacaCredentials() bool {
+	keyID, secretKey := alpacaCredentials()
+	return keyID != "" && secretKey != ""
+}
+
+// alpacaAccount has the balances of an Alpaca account.
+type alpacaAccount struct {
+	buyingPower    float64
+	cash           float64
+	portfolioValue float64
+}
+
+// alpacaPosition is an open position in an Alpaca account.
+type alpacaPosition struct {
+	symbol        string
+	quantity      float64
+	avgEntryPrice float64
+	marketValue   float64
+	unrealizedPL  float64
+}
+
+// tradeSide is the side of an order or transaction.
+type tradeSide string
+
+// List of possible tradeSide values.
+const (
+	buy  tradeSide = "buy"
+	sell tradeSide = "sell"
+)
+
+// alpacaOrder is an order to submit to Alpaca.
+type alpacaOrder struct {
+	symbol   string
+	side     tradeSide
+	quantity int64
+
+	// limitPrice is the limit price of the order or zero for a market order.
+	limitPrice float64
+}
+
+// String returns a description of the order for the confirmation popup.
+func (o alpacaOrder) String() string {
+	if o.limitPrice != 0 {
+		return fmt.Sprintf("%s %d %s LMT %.2f", o.side, o.quantity, o.symbol, o.limitPrice)
+	}
+	return fmt.Sprintf("%s %d %s MKT", o.side, o.quantity, o.symbol)
+}
+
+func getAlpacaAccount(ctx context.Context) (*alpacaAccount, error) {
+	parsed := struct {
+		Buying_power    string
+		Cash            string
+		Portfolio_value string
+	}{}
+	if err := doAlpacaRequest(ctx, "GET", "/v2/account", nil, &parsed); err != nil {
+		return nil, err
+	}
+
+	buyingPower, err := parseFloat(parsed.Buying_power)
+	if err != nil {
+		return nil, fmt.Errorf("buying power: %v", err)
+	}
+
+	cash, err := parseFloat(parsed.Cash)
+	if err != nil {
+		return nil, fmt.Errorf("cash: %v", err)
+	}
+
+	portfolioValue, err := parseFloat(parsed.Portfolio_value)
+	if err != nil {
+		return nil, fmt.Errorf("portfolio value: %v", err)
+	}
+
+	return &alpacaAccount{
+		buyingPower:    buyingPower,
+		cash:           cash,
+		portfolioValue: portfolioValue,
+	}, nil
+}
+
+func getAlpacaPositions(ctx context.Context) ([]alpacaPosition, error) {
+	parsed := []struct {
+		Symbol          string
+		Qty             string
+		Avg_entry_price string
+		Market_value    string
+		Unrealized_pl   string
+	}{}
+	if err := doAlpacaRequest(ctx, "GET", "/v2/positions", nil, &parsed); err != nil {
+		return nil, err
+	}
+
+	var ps []alpacaPosition
+	for _, p := range parsed {
+		quantity, err := parseFloat(p.Qty)
+		if err != nil {
+			return nil, fmt.Errorf("p: %+v quantity: %v", p, err)
+		}
+
+		avgEntryPrice, err := parseFloat(p.Avg_entry_price)
+		if err != nil {
+			return nil, fmt.Errorf("p: %+v avgEntryPrice: %v", p, err)
+		}
+
+		marketValue, err := parseFloat(p.Market_value)
+		if err != nil {
+			return nil, fmt.Errorf("p: %+v marketValue: %v", p, err)
+		}
+
+		unrealizedPL, err := parseFloat(p.Unrealized_pl)
+		if err != nil {
+			return nil, fmt.Errorf("p: %+v unrealizedPL: %v", p, err)
+		}
+
+		ps = append(ps, alpacaPosition{
+			symbol:        p.Symbol,
+			quantity:      quantity,
+			avgEntryPrice: avgEntryPrice,
+			marketValue:   marketValue,
+			unrealizedPL:  unrealizedPL,
+		})
+	}
+	return ps, nil
+}
+
+func submitAlpacaOrder(ctx context.Context, o alpacaOrder) error {
+	if !*alpacaOrders {
+		return errors.New("order submission is disabled, see -alpaca_orders")
+	}
+
+	req := struct {
+		Symbol      string `json:"symbol"`
+		Qty         string `json:"qty"`
+		Side        string `json:"side"`
+		Type        string `json:"type"`
+		TimeInForce string `json:"time_in_force"`
+		LimitPrice  string `json:"limit_price,omitempty"`
+	}{
+		Symbol:      o.symbol,
+		Qty:         strconv.FormatInt(o.quantity, 10),
+		Side:        string(o.side),
+		Type:        "market",
+		TimeInForce: "day",
+	}
+	if o.limitPrice != 0 {
+		req.Type = "limit"
+		req.LimitPrice = strconv.FormatFloat(o.limitPrice, 'f', -1, 64)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return doAlpacaRequest(ctx, "POST", "/v2/orders", body, nil)
+}
+
+// doAlpacaRequest makes an authenticated request and decodes the JSON response into v if not nil.
+// The request is canceled if the context is done.
+func doAlpacaRequest(ctx context.Context, method, path string, body []byte, v interface{}) error {
+	if !hasAlpacaCredentials() {
+		return fmt.Errorf("%s and %s must be set", alpacaKeyIDEnv, alpacaSecretKeyEnv)
+	}
+
+	req, err := http.NewRequest(method, *alpacaURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	keyID, secretKey := alpacaCredentials()
+	req.Header.Set("APCA-API-KEY-ID", keyID)
+	req.Header.Set("APCA-API-SECRET-KEY", secretKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := doPrivateHTTPRequest(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		// Alpaca returns errors like {"code": 40310000, "message": "insufficient buying power"}.
+		parsed := struct {
+			Message string
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&parsed); err == nil && parsed.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, parsed.Message)
+		}
+		return errors.New(resp.Status)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// parseAlpacaOrderInput parses order input like "10" for a market order or "10@182.50" for a limit order.
+func parseAlpacaOrderInput(symbol string, side tradeSide, input string) (alpacaOrder, error) {
+	o := alpacaOrder{symbol: symbol, side: side}
+
+	qty, price := input, ""
+	if i := strings.IndexByte(input, '@'); i >= 0 {
+		qty, price = input[:i], input[i+1:]
+	}
+
+	var err error
+	o.quantity, err = strconv.ParseInt(qty, 10, 64)
+	if err != nil || o.quantity <= 0 {
+		return alpacaOrder{}, fmt.Errorf("bad quantity: %q", qty)
+	}
+
+	if price != "" {
+		o.limitPrice, err = parseFloat(price)
+		if err != nil || o.limitPrice <= 0 {
+			return alpacaOrder{}, fmt.Errorf("bad limit price: %q", price)
+		}
+
+		// Alpaca rejects prices with more decimals than the penny increments, which are a hundredth of a penny under a dollar.
+		maxDecimals := 2
+		if o.limitPrice < 1 {
+			maxDecimals = 4
+		}
+		if p := o.limitPrice * math.Pow10(maxDecimals); math.Abs(p-math.Round(p)) > 1e-6 {
+			return alpacaOrder{}, fmt.Errorf("limit price %s has more than %d decimals", price, maxDecimals)
+		}
+	}
+
+	return o, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestParseAlpacaOrderInput(t *testing.T) {
+	for _, tt := range []struct {
+		input   string
+		want    alpacaOrder
+		wantErr bool
+	}{
+		{input: "10", want: alpacaOrder{symbol: "AAPL", side: buy, quantity: 10}},
+		{input: "10@182.50", want: alpacaOrder{symbol: "AAPL", side: buy, quantity: 10, limitPrice: 182.50}},
+		{input: "10@0.1234", want: alpacaOrder{symbol: "AAPL", side: buy, quantity: 10, limitPrice: 0.1234}},
+		{input: "10@182.505", wantErr: true},
+		{input: "10@0.12345", wantErr: true},
+		{input: "10@1e-5", wantErr: true},
+		{input: "10@0", wantErr: true},
+		{input: "0", wantErr: true},
+		{input: "ten", wantErr: true},
+	} {
+		got, err := parseAlpacaOrderInput("AAPL", buy, tt.input)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("parseAlpacaOrderInput(%q) error = %v, want error %t", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("parseAlpacaOrderInput(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
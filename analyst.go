@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// analystMaxAge is how long the analyst summary is kept before fetching it again.
+const analystMaxAge = 24 * time.Hour
+
+// analystSummary is the analysts' consensus rating and price target of a stock.
+type analystSummary struct {
+	// rating is the consensus like "buy" or "hold".
+	rating string
+
+	// analysts is the number of analysts with an opinion.
+	analysts int
+
+	// targetMean is the mean price target.
+	targetMean float64
+
+	// fetchTime is when the summary was fetched.
+	fetchTime time.Time
+}
+
+// getQuoteSummary gets the modules like "financialData" of the symbol's quote summary from Yahoo
+// and decodes the result into v.
+func getQuoteSummary(ctx context.Context, symbol, modules string, v interface{}) error {
+	q := url.Values{}
+	q.Set("modules", modules)
+
+	u, err := url.Parse("https://query1.finance.yahoo.com/v10/finance/quoteSummary/" + url.PathEscape(providerSymbol(yahoo, symbol)))
+	if err != nil {
+		return err
+	}
+	u.RawQuery = q.Encode()
+	resp, err := httpGet(ctx, u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := readQuoteSummary(resp.Body, v); err != nil {
+		return fmt.Errorf("%s: %v", symbol, err)
+	}
+	return nil
+}
+
+// readQuoteSummary decodes the first result of a quote summary response into v.
+func readQuoteSummary(r io.Reader, v interface{}) error {
+	var parsed struct {
+		QuoteSummary struct {
+			Error  *yahooError
+			Result []json.RawMessage
+		}
+	}
+	if err := json.NewDecoder(r).Decode(&parsed); err != nil {
+		return err
+	}
+	if parsed.QuoteSummary.Error != nil {
+		return parsed.QuoteSummary.Error
+	}
+	if len(parsed.QuoteSummary.Result) == 0 {
+		return errors.New("no quote summary")
+	}
+	return json.Unmarshal(parsed.QuoteSummary.Result[0], v)
+}
+
+// getAnalystSummary gets the symbol's consensus rating and mean price target from Yahoo.
+func getAnalystSummary(ctx context.Context, symbol string) (*analystSummary, error) {
+	type rawValue struct {
+		Raw float64
+	}
+	var result struct {
+		FinancialData struct {
+			RecommendationKey       string
+			NumberOfAnalystOpinions rawValue
+			TargetMeanPrice         rawValue
+		}
+	}
+	if err := getQuoteSummary(ctx, symbol, "financialData", &result); err != nil {
+		return nil, err
+	}
+
+	fd := result.FinancialData
+	return &analystSummary{
+		rating:     fd.RecommendationKey,
+		analysts:   int(fd.NumberOfAnalystOpinions.Raw),
+		targetMean: fd.TargetMeanPrice.Raw,
+		fetchTime:  clk.now(),
+	}, nil
+}
+
+// refreshAnalystSummary fetches the stock's analyst summary if it has none or it is old.
+// It returns true if a new summary was fetched.
+func refreshAnalystSummary(ctx context.Context, sd *stockData, symbol string) bool {
+	if *offline || classifySymbol(symbol) != equityAsset {
+		return false
+	}
+
+	sd.RLock()
+	var fetchTime time.Time
+	for _, s := range sd.stocks {
+		if s.symbol == symbol && s.analyst != nil {
+			fetchTime = s.analyst.fetchTime
+		}
+	}
+	sd.RUnlock()
+	if !fetchTime.IsZero() && clk.now().Sub(fetchTime) < analystMaxAge {
+		return false
+	}
+
+	as, err := getAnalystSummary(ctx, symbol)
+	if err != nil {
+		log.Printf("getAnalystSummary(%s): %v", symbol, err)
+		return false
+	}
+
+	sd.Lock()
+	for i, s := range sd.stocks {
+		if s.symbol == symbol {
+			sd.stocks[i].analyst = as
+		}
+	}
+	sd.Unlock()
+	return true
+}
+
+// analystLabel returns a label like "Target 210.50 +12.3% Buy (32)" of the summary
+// with the upside or downside of the mean price target from the price.
+func analystLabel(as *analystSummary, price float64) string {
+	if as == nil || as.targetMean == 0 {
+		return ""
+	}
+
+	l := fmt.Sprintf("Target %.2f", as.targetMean)
+	if price != 0 {
+		l += fmt.Sprintf(" %+.1f%%", (as.targetMean-price)/price*100)
+	}
+	if as.rating != "" && as.rating != "none" {
+		l += " " + strings.Title(strings.Replace(as.rating, "_", " ", -1))
+	}
+	if as.analysts > 0 {
+		l += fmt.Sprintf(" (%d)", as.analysts)
+	}
+	return l
+}
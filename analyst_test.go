@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadQuoteSummary(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "yahoo_asset_profile.json"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	var result struct {
+		AssetProfile struct {
+			Sector, Industry string
+		}
+	}
+	if err := readQuoteSummary(f, &result); err != nil {
+		t.Fatalf("readQuoteSummary() error = %v", err)
+	}
+	if got, want := result.AssetProfile.Sector, "Technology"; got != want {
+		t.Errorf("sector = %q, want %q", got, want)
+	}
+	if got, want := result.AssetProfile.Industry, "Consumer Electronics"; got != want {
+		t.Errorf("industry = %q, want %q", got, want)
+	}
+}
+
+func TestReadQuoteSummaryErrors(t *testing.T) {
+	for _, tt := range []struct {
+		desc    string
+		data    string
+		wantErr string
+	}{
+		{
+			desc:    "unknown symbol",
+			data:    `{"quoteSummary":{"result":null,"error":{"code":"Not Found","description":"Quote not found for ticker symbol: XYZZY"}}}`,
+			wantErr: "Quote not found",
+		},
+		{
+			desc:    "no results",
+			data:    `{"quoteSummary":{"result":[],"error":null}}`,
+			wantErr: "no quote summary",
+		},
+		{
+			desc:    "not json",
+			data:    `<html>Too Many Requests</html>`,
+			wantErr: "invalid character",
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			var result struct{}
+			err := readQuoteSummary(strings.NewReader(tt.data), &result)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readQuoteSummary() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
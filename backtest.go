@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// backtestAllocation is a flag to backtest an allocation and print the results before exiting.
+	backtestAllocation = flag.String("backtest", "", "Allocation like AAPL=60,MSFT=40 to backtest and print the results before exiting.")
+
+	// backtestStart is a flag to set the start date of a headless backtest.
+	backtestStart = flag.String("backtest_start", "", "Start date of the -backtest history as YYYY-MM-DD. Defaults to one year ago.")
+)
+
+// parseAllocation parses an allocation like "AAPL=60,MSFT=40" or "AAPL=60 MSFT=40" into weights that sum to one.
+func parseAllocation(input string) (map[string]float64, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("expected SYMBOL=WEIGHT pairs, got %q", input)
+	}
+
+	wm := map[string]float64{}
+	var total float64
+	for _, f := range fields {
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("expected SYMBOL=WEIGHT, got %q", f)
+		}
+
+		w, err := parseFloat(strings.TrimSuffix(parts[1], "%"))
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("bad weight: %q", parts[1])
+		}
+
+		symbol := strings.ToUpper(parts[0])
+		wm[symbol] += w
+		total += w
+	}
+
+	for symbol := range wm {
+		wm[symbol] /= total
+	}
+	return wm, nil
+}
+
+// backtestResult is the performance of an allocation rebalanced daily.
+type backtestResult struct {
+	start, end  time.Time
+	days        int
+	totalReturn float64
+	volatility  float64
+	maxDrawdown float64
+}
+
+// backtest returns the performance of the allocation over the dates that all its stocks have data for.
+func backtest(tsms map[string]map[time.Time]stockTradingSession, weights map[string]float64) (backtestResult, error) {
+	stockReturns := map[string]map[time.Time]float64{}
+	for symbol := range weights {
+		tsm, ok := tsms[symbol]
+		if !ok || len(tsm) == 0 {
+			return backtestResult{}, fmt.Errorf("no history for %s", symbol)
+		}
+		stockReturns[symbol] = dailyReturns(tsm)
+	}
+
+	// Only use dates that every stock has a return for to keep the allocation static.
+	pm := map[time.Time]float64{}
+	for date, r := range portfolioReturns(stockReturns, weights) {
+		all := true
+		for _, rm := range stockReturns {
+			if _, ok := rm[date]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			pm[date] = r
+		}
+	}
+	if len(pm) < 2 {
+		return backtestResult{}, fmt.Errorf("not enough common history, got %d days", len(pm))
+	}
+
+	var dates sortableTimes
+	for date := range pm {
+		dates = append(dates, date)
+	}
+	sort.Sort(dates)
+
+	value := 1.0
+	for _, date := range dates {
+		value *= 1 + pm[date]
+	}
+
+	rm := calculateRisk(pm, nil)
+	return backtestResult{
+		start:       dates[0],
+		end:         dates[len(dates)-1],
+		days:        len(dates),
+		totalReturn: value - 1,
+		volatility:  rm.volatility,
+		maxDrawdown: rm.maxDrawdown,
+	}, nil
+}
+
+// lines returns the result formatted for printing.
+func (r backtestResult) lines() []string {
+	return []string{
+		fmt.Sprintf("%s - %s (%d days)", r.start.Format("1/2/06"), r.end.Format("1/2/06"), r.days),
+		fmt.Sprintf("Total Return  %+.2f%%", r.totalReturn*100),
+		fmt.Sprintf("Volatility    %.2f%%", r.volatility*100),
+		fmt.Sprintf("Max Drawdown  %.2f%%", -r.maxDrawdown*100),
+	}
+}
+
+// runBacktest fetches the history of the allocation's stocks and prints the backtest results.
+func runBacktest(ctx context.Context, allocation string, start time.Time) error {
+	weights, err := parseAllocation(allocation)
+	if err != nil {
+		return err
+	}
+
+	end := chartToday()
+
+	type result struct {
+		symbol string
+		tss    []tradingSession
+		err    error
+	}
+
+	ch := make(chan result)
+	for symbol := range weights {
+		go func(symbol string) {
+			tss, err := stockTradingSessionFunc("")(ctx, symbol, start, end)
+			ch <- result{symbol, tss, err}
+		}(symbol)
+	}
+
+	tsms := map[string]map[time.Time]stockTradingSession{}
+	for range weights {
+		r := <-ch
+		if r.err != nil {
+			log.Printf("getTradingSessions(%s): %v", r.symbol, r.err)
+			continue
+		}
+		tsm := map[time.Time]stockTradingSession{}
+		for _, ts := range convertTradingSessions(r.tss) {
+			tsm[ts.date] = ts
+		}
+		tsms[r.symbol] = tsm
+	}
+
+	res, err := backtest(tsms, weights)
+	if err != nil {
+		return err
+	}
+
+	var symbols []string
+	for symbol := range weights {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		fmt.Printf("%-8s %6.2f%%\n", symbol, weights[symbol]*100)
+	}
+	for _, l := range res.lines() {
+		fmt.Println(l)
+	}
+	return nil
+}
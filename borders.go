@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+)
+
+// borders is a flag to draw box-drawing borders between the cells.
+var borders = flag.Bool("borders", false, "Draw borders between cells with box-drawing characters.")
+
+// wideRanges are the East Asian wide and fullwidth rune ranges that take up two cells.
+var wideRanges = []struct{ lo, hi rune }{
+	{0x1100, 0x115F},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x1F300, 0x1F64F},
+	{0x1F900, 0x1F9FF},
+	{0x20000, 0x3FFFD},
+}
+
+// runeWidth returns the number of cells the rune takes up in the terminal.
+func runeWidth(r rune) int {
+	if r == 0 || unicode.Is(unicode.Mn, r) {
+		return 0
+	}
+	for _, wr := range wideRanges {
+		if r >= wr.lo && r <= wr.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// printBorders draws vertical lines at the xs and horizontal lines at the ys within the bounds
+// with the proper junctions where they cross.
+func printBorders(xs, ys []int, left, top, right, bottom int) {
+	isX, isY := map[int]bool{}, map[int]bool{}
+	for _, x := range xs {
+		isX[x] = true
+	}
+	for _, y := range ys {
+		isY[y] = true
+	}
+
+	for _, y := range ys {
+		for x := left; x < right; x++ {
+			if !isX[x] {
+				term.setCell(x, y, '─', termbox.ColorDefault, termbox.ColorDefault)
+			}
+		}
+	}
+
+	for _, x := range xs {
+		for y := top; y < bottom; y++ {
+			c := '│'
+			if isY[y] {
+				switch {
+				case y == top:
+					c = '┬'
+				case y == bottom-1:
+					c = '┴'
+				default:
+					c = '┼'
+				}
+			}
+			term.setCell(x, y, c, termbox.ColorDefault, termbox.ColorDefault)
+		}
+	}
+}
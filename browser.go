@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// defaultQuoteURL is the page opened for a symbol when the config has no URL template.
+const defaultQuoteURL = "https://finance.yahoo.com/quote/{symbol}"
+
+// quoteURL returns the URL of the symbol's page by replacing {symbol} in the template.
+func quoteURL(template, symbol string) string {
+	if template == "" {
+		template = defaultQuoteURL
+	}
+	return strings.Replace(template, "{symbol}", url.PathEscape(symbol), -1)
+}
+
+// openBrowser opens the URL in the default browser without waiting for it to exit.
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// screenDump is a flag to print the first screen as text instead of drawing it on the terminal.
+var screenDump = flag.String("screen_dump", "", "Print the first screen as text with a size like 120x40 and exit, to compare layouts against saved copies.")
+
+// bufferScreen is a screen that draws into memory rather than on a terminal.
+// Each flush writes the cells as lines of text, so layouts can be compared against saved copies.
+type bufferScreen struct {
+	// w and h are the fixed width and height of the screen.
+	w, h int
+
+	// cells are the runes drawn since the last clear by row and column.
+	cells [][]rune
+
+	// out is where each flushed screen is written.
+	out io.Writer
+
+	// events are the queued events returned by pollEvent.
+	events chan termbox.Event
+
+	// exitAfterFlush is whether to quit like with Ctrl+C after the first flush.
+	exitAfterFlush bool
+}
+
+// newBufferScreen returns a bufferScreen of the size that writes its screens to out.
+func newBufferScreen(w, h int, out io.Writer) *bufferScreen {
+	s := &bufferScreen{
+		w:      w,
+		h:      h,
+		out:    out,
+		events: make(chan termbox.Event, 64),
+	}
+	s.clear()
+	return s
+}
+
+// parseScreenSize parses a screen size like 120x40.
+func parseScreenSize(size string) (w, h int, err error) {
+	if _, err := fmt.Sscanf(size, "%dx%d", &w, &h); err != nil {
+		return 0, 0, fmt.Errorf("bad screen size %q: %v", size, err)
+	}
+	if w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("bad screen size %q", size)
+	}
+	return w, h, nil
+}
+
+func (*bufferScreen) init() error {
+	return nil
+}
+
+func (*bufferScreen) close() {}
+
+// setOutputMode always uses the normal mode, since only the runes are written.
+func (*bufferScreen) setOutputMode(mode termbox.OutputMode) termbox.OutputMode {
+	return termbox.OutputNormal
+}
+
+func (s *bufferScreen) size() (w, h int) {
+	return s.w, s.h
+}
+
+func (s *bufferScreen) clear() error {
+	s.cells = make([][]rune, s.h)
+	for y := range s.cells {
+		s.cells[y] = []rune(strings.Repeat(" ", s.w))
+	}
+	return nil
+}
+
+// setCell sets the cell's rune and ignores cells outside the screen like termbox does.
+func (s *bufferScreen) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	if x < 0 || x >= s.w || y < 0 || y >= s.h {
+		return
+	}
+	s.cells[y][x] = ch
+}
+
+// flush writes the screen with trailing spaces trimmed, so saved copies diff cleanly.
+func (s *bufferScreen) flush() error {
+	bw := bufio.NewWriter(s.out)
+	for _, row := range s.cells {
+		if _, err := fmt.Fprintln(bw, strings.TrimRight(string(row), " ")); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	// Only write the first screen, since repaints may come before the quit event.
+	if s.exitAfterFlush {
+		s.exitAfterFlush = false
+		s.out = ioutil.Discard
+		s.events <- termbox.Event{Type: termbox.EventKey, Key: termbox.KeyCtrlC}
+	}
+	return nil
+}
+
+func (s *bufferScreen) pollEvent() termbox.Event {
+	return <-s.events
+}
+
+func (s *bufferScreen) interrupt() {
+	select {
+	case s.events <- termbox.Event{Type: termbox.EventInterrupt}:
+	default:
+	}
+}
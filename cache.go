@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// staleAge is how old a stock's data can get before its age is shown under the symbol.
+const staleAge = 90 * time.Minute
+
+// cache is the latest data saved to disk to show at startup while fresh data is fetched.
+type cache struct {
+	// RefreshTime is when the data was refreshed. Capitalized for JSON decoding.
+	RefreshTime time.Time
+
+	// TradingDates are the dates shown at the top. Capitalized for JSON decoding.
+	TradingDates []time.Time
+
+	// Stocks are the trading sessions of the stocks. Capitalized for JSON decoding.
+	Stocks []cacheStock
+
+	// Indices is a map from index symbol to its latest session. Capitalized for JSON decoding.
+	Indices map[string]cacheSession
+}
+
+// cacheStock is a stock's trading sessions in the cache.
+type cacheStock struct {
+	// Symbol is the stock's symbol. Capitalized for JSON decoding.
+	Symbol string
+
+	// UpdateTime is when the sessions were last fetched. Capitalized for JSON decoding.
+	UpdateTime time.Time
+
+	// Sessions are the stock's trading sessions. Capitalized for JSON decoding.
+	Sessions []cacheSession
+
+	// HistoryStart and HistoryEnd are the range of the fetched sessions when all of them are sent
+	// like by the daemon or zero when only the grid's are. Capitalized for JSON decoding.
+	HistoryStart, HistoryEnd time.Time
+}
+
+// cacheSession is a stockTradingSession in the cache. Capitalized for JSON decoding.
+type cacheSession struct {
+	Date          time.Time
+	Open          float64
+	High          float64
+	Low           float64
+	Close         float64
+	Volume        int64
+	Change        float64
+	PercentChange float64
+}
+
+// cacheMutex prevents cache file reads and writes from conflicting.
+var cacheMutex sync.Mutex
+
+// loadCache loads the cached data into the stocks that don't have data yet and marks them as cached.
+func loadCache(sd *stockData) error {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	p, err := getUserCachePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var c cache
+	if err := json.NewDecoder(file).Decode(&c); err != nil && err != io.EOF {
+		return err
+	}
+
+	sd.Lock()
+	defer sd.Unlock()
+
+	// Don't overwrite data from a refresh that finished first.
+	if !sd.refreshTime.IsZero() {
+		return nil
+	}
+
+	applyCache(sd, c, true)
+	return nil
+}
+
+// applyCache sets the data of the stocks in the cache and marks whether it is from the disk cache.
+// The sessions are added to any the stocks already have. The caller must hold the write lock.
+func applyCache(sd *stockData, c cache, cached bool) {
+	convert := func(cs cacheSession) stockTradingSession {
+		return stockTradingSession{
+			date:          cs.Date,
+			open:          cs.Open,
+			high:          cs.High,
+			low:           cs.Low,
+			close:         cs.Close,
+			volume:        cs.Volume,
+			change:        cs.Change,
+			percentChange: cs.PercentChange,
+		}
+	}
+
+	sd.refreshTime = c.RefreshTime
+	sd.tradingDates = c.TradingDates
+	sd.dow = convert(c.Indices[dowSymbol])
+	sd.sap = convert(c.Indices[sapSymbol])
+	sd.nasdaq = convert(c.Indices[nasdaqSymbol])
+
+	cm := map[string]cacheStock{}
+	for _, cs := range c.Stocks {
+		cm[cs.Symbol] = cs
+	}
+	for i, s := range sd.stocks {
+		cs, ok := cm[s.symbol]
+		if !ok {
+			continue
+		}
+		if sd.stocks[i].tradingSessionMap == nil {
+			sd.stocks[i].tradingSessionMap = map[time.Time]stockTradingSession{}
+		}
+		for _, ts := range cs.Sessions {
+			sd.stocks[i].tradingSessionMap[ts.Date] = convert(ts)
+		}
+		sd.stocks[i].updateTime = cs.UpdateTime
+		sd.stocks[i].cached = cached
+
+		// Widen the history like loading the history database, since the sessions are added to the stock's.
+		if !cs.HistoryStart.IsZero() && (s.historyStart.IsZero() || cs.HistoryStart.Before(s.historyStart)) {
+			sd.stocks[i].historyStart = cs.HistoryStart
+		}
+		if cs.HistoryEnd.After(s.historyEnd) {
+			sd.stocks[i].historyEnd = cs.HistoryEnd
+		}
+		sd.stocks[i].volatility = volatility(sd.stocks[i].tradingSessionMap)
+	}
+}
+
+// saveCache saves the stocks' sessions shown in the grid to disk.
+func saveCache(sd *stockData) error {
+	c := newCache(sd, false)
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	p, err := getUserCachePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(c)
+}
+
+// newCache returns the stocks' sessions shown in the grid or all of them and their range if allSessions is true.
+func newCache(sd *stockData, allSessions bool) cache {
+	convert := func(ts stockTradingSession) cacheSession {
+		return cacheSession{
+			Date:          ts.date,
+			Open:          ts.open,
+			High:          ts.high,
+			Low:           ts.low,
+			Close:         ts.close,
+			Volume:        ts.volume,
+			Change:        ts.change,
+			PercentChange: ts.percentChange,
+		}
+	}
+
+	sd.RLock()
+	c := cache{
+		RefreshTime:  sd.refreshTime,
+		TradingDates: sd.tradingDates,
+		Indices: map[string]cacheSession{
+			dowSymbol:    convert(sd.dow),
+			sapSymbol:    convert(sd.sap),
+			nasdaqSymbol: convert(sd.nasdaq),
+		},
+	}
+	for _, s := range sd.stocks {
+		cs := cacheStock{
+			Symbol:     s.symbol,
+			UpdateTime: s.updateTime,
+		}
+		if allSessions {
+			cs.HistoryStart, cs.HistoryEnd = s.historyStart, s.historyEnd
+			for _, ts := range s.tradingSessionMap {
+				cs.Sessions = append(cs.Sessions, convert(ts))
+			}
+		} else {
+			// Only save the dates in the grid to keep the file small.
+			for _, date := range sd.tradingDates {
+				if ts, ok := s.tradingSessionMap[date]; ok {
+					cs.Sessions = append(cs.Sessions, convert(ts))
+				}
+			}
+		}
+		c.Stocks = append(c.Stocks, cs)
+	}
+	sd.RUnlock()
+	return c
+}
+
+func getUserCachePath() (string, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dirPath, "cache.json"), nil
+}
+
+// ageLabel returns a short label like "~45s" or "~3h" of how long ago the time was.
+func ageLabel(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("~%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("~%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("~%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("~%dd", int(d.Hours()/24))
+	}
+}
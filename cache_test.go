@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyCache(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 8, d, 0, 0, 0, 0, time.UTC) }
+
+	// The daemon has a session before the grid's dates.
+	daemon := fixtureStockData()
+	daemon.stocks = daemon.stocks[:1]
+	daemon.stocks[0].tradingSessionMap[day(21)] = stockTradingSession{date: day(21), close: 340}
+	daemon.stocks[0].historyStart = day(21)
+	daemon.stocks[0].historyEnd = day(29)
+
+	for _, tt := range []struct {
+		desc             string
+		allSessions      bool
+		wantSessions     int
+		wantStart        time.Time
+		wantEnd          time.Time
+		wantEarlySession bool
+	}{
+		{
+			desc:         "grid sessions",
+			wantSessions: 5,
+		},
+		{
+			desc:             "all sessions",
+			allSessions:      true,
+			wantSessions:     6,
+			wantStart:        day(21),
+			wantEnd:          day(29),
+			wantEarlySession: true,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			client := &stockData{stocks: []stock{{symbol: "SPY"}}}
+			applyCache(client, newCache(daemon, tt.allSessions), false)
+
+			s := client.stocks[0]
+			if got := len(s.tradingSessionMap); got != tt.wantSessions {
+				t.Errorf("got %d sessions, want %d", got, tt.wantSessions)
+			}
+			if _, ok := s.tradingSessionMap[day(21)]; ok != tt.wantEarlySession {
+				t.Errorf("has session before the grid = %t, want %t", ok, tt.wantEarlySession)
+			}
+			if !s.historyStart.Equal(tt.wantStart) || !s.historyEnd.Equal(tt.wantEnd) {
+				t.Errorf("history = %v to %v, want %v to %v", s.historyStart, s.historyEnd, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// assetClass is a kind of security that a symbol refers to.
+type assetClass string
+
+// List of possible assetClass values.
+const (
+	equityAsset   assetClass = "stock"
+	indexAsset               = "index"
+	currencyAsset            = "currency"
+	futureAsset              = "future"
+	optionAsset              = "option"
+)
+
+// sourceCapabilities describes what a trading session source can serve.
+type sourceCapabilities struct {
+	// assetClasses are the kinds of symbols the source has history for.
+	assetClasses []assetClass
+
+	// intraday is whether the source has intraday sessions rather than only daily ones.
+	intraday bool
+
+	// adjusted is whether the source has closes adjusted for splits and dividends.
+	adjusted bool
+}
+
+// sourceCapabilityMatrix is a map from trading session source to what it can serve.
+var sourceCapabilityMatrix = map[tradingSessionSource]sourceCapabilities{
+	google: {
+		assetClasses: []assetClass{equityAsset},
+	},
+	yahoo: {
+		assetClasses: []assetClass{equityAsset, indexAsset, currencyAsset, futureAsset},
+		adjusted:     true,
+	},
+}
+
+// supports returns whether the source has history for the asset class.
+func (sc sourceCapabilities) supports(ac assetClass) bool {
+	for _, c := range sc.assetClasses {
+		if c == ac {
+			return true
+		}
+	}
+	return false
+}
+
+// classifySymbol returns the asset class of the symbol based on its format.
+func classifySymbol(symbol string) assetClass {
+	switch {
+	case strings.HasPrefix(symbol, ".") || strings.HasPrefix(symbol, "^"):
+		return indexAsset
+	case strings.HasSuffix(symbol, "=X"):
+		return currencyAsset
+	case strings.HasSuffix(symbol, "=F"):
+		return futureAsset
+	case len(symbol) > 15 && strings.IndexFunc(symbol, unicode.IsDigit) >= 0:
+		// OCC option symbols like AAPL170120C00100000 have a date, type, and strike after the root.
+		return optionAsset
+	default:
+		return equityAsset
+	}
+}
+
+// checkSymbol returns an error if none of the source's underlying sources can serve the symbol.
+func checkSymbol(symbol string, source tradingSessionSource) error {
+	// Plugins decide for themselves what they can serve.
+	if _, ok := getPluginSource(source); ok {
+		return nil
+	}
+
+	sources := []tradingSessionSource{source}
+	if source == random {
+		sources = randomSources
+	}
+
+	ac := classifySymbol(symbol)
+	for _, s := range sources {
+		if sourceCapabilityMatrix[s].supports(ac) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is an unsupported %s symbol for the %s source", symbol, ac, source)
+}
+
+// validateSymbol returns an error if the symbol is unsupported or the data source has no recent
+// trading sessions for it. Only the support check is done when offline or attached to the daemon,
+// since the daemon does the fetching then.
+func validateSymbol(ctx context.Context, symbol string) error {
+	source := stockSource(symbol, "")
+	if err := checkSymbol(symbol, source); err != nil {
+		return err
+	}
+	if *offline || *attach {
+		return nil
+	}
+
+	end := chartToday()
+	tss, err := stockTradingSessionFunc(source)(ctx, symbol, end.AddDate(0, 0, -14), end)
+	if err != nil {
+		return fmt.Errorf("%s not found: %v", symbol, err)
+	}
+	if len(tss) == 0 {
+		return fmt.Errorf("%s has no recent trading sessions", symbol)
+	}
+	return nil
+}
+
+// parseSymbolList parses the symbols separated by commas or spaces like "AAPL, MSFT GOOG".
+// Repeated symbols are only returned once.
+func parseSymbolList(input string) []string {
+	var symbols []string
+	seen := map[string]bool{}
+	for _, f := range strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	}) {
+		symbol := strings.ToUpper(f)
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// chartRange is a preset range of dates that the chart can show.
+type chartRange struct {
+	// label is the short name of the range like "1Y".
+	label string
+
+	// key is the key that selects the range in the detail view.
+	key rune
+
+	// months is how many months back the range starts or zero for all available data.
+	months int
+}
+
+// chartRanges are the preset chart ranges.
+var chartRanges = []chartRange{
+	{"1M", 'm', 1},
+	{"3M", 'q', 3},
+	{"6M", 'h', 6},
+	{"1Y", 'y', 12},
+	{"5Y", 'f', 60},
+	{"MAX", 'a', 0},
+}
+
+// findChartRange returns the preset chart range with the label.
+func findChartRange(label string) (chartRange, bool) {
+	for _, cr := range chartRanges {
+		if cr.label == label {
+			return cr, true
+		}
+	}
+	return chartRange{}, false
+}
+
+// chartOptions are the indicators and scale of the chart.
+type chartOptions struct {
+	// movingAverages are the periods of the simple moving averages to draw.
+	movingAverages []int
+
+	// logScale is whether to use a logarithmic price scale.
+	logScale bool
+}
+
+// movingAveragePresets are the sets of moving averages cycled through in the detail view.
+var movingAveragePresets = [][]int{
+	nil,
+	{20},
+	{50},
+	{20, 50},
+	{50, 200},
+}
+
+// nextMovingAverages returns the preset after the given moving averages.
+func nextMovingAverages(mas []int) []int {
+	for i, p := range movingAveragePresets {
+		if fmt.Sprint(p) == fmt.Sprint(mas) {
+			return movingAveragePresets[(i+1)%len(movingAveragePresets)]
+		}
+	}
+	return movingAveragePresets[0]
+}
+
+// movingAverageColors are the colors of the moving average lines.
+var movingAverageColors = []termbox.Attribute{
+	termbox.ColorYellow,
+	termbox.ColorCyan,
+	termbox.ColorMagenta,
+}
+
+// chartLayout is a named chart configuration that can be applied to any stock.
+type chartLayout struct {
+	name string
+
+	// rangeLabel is the label of a preset chart range or "Custom".
+	rangeLabel string
+
+	// start and end are the dates of a custom range.
+	start, end time.Time
+
+	options chartOptions
+}
+
+// describe returns a short description of the chart options like "SMA 50/200 Log".
+func (co chartOptions) describe() string {
+	var parts []string
+	if len(co.movingAverages) > 0 {
+		var ps []string
+		for _, p := range co.movingAverages {
+			ps = append(ps, fmt.Sprint(p))
+		}
+		parts = append(parts, "SMA "+strings.Join(ps, "/"))
+	}
+	if co.logScale {
+		parts = append(parts, "Log")
+	}
+	return strings.Join(parts, " ")
+}
+
+// warmupStart returns how far back to fetch data before the start so moving averages can be drawn from the start.
+func (co chartOptions) warmupStart(start time.Time) time.Time {
+	max := 0
+	for _, p := range co.movingAverages {
+		if p > max {
+			max = p
+		}
+	}
+	// Trading days are roughly two thirds of calendar days.
+	return start.AddDate(0, 0, -max*3/2)
+}
+
+// maxChartStart is the start date requested for the MAX range.
+var maxChartStart = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// chartToday returns today's date in New York as midnight UTC to match the trading session dates.
+func chartToday() time.Time {
+	t := clk.now().In(newYorkLoc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// start returns the start date of the range ending at the given date.
+func (cr chartRange) start(end time.Time) time.Time {
+	if cr.months == 0 {
+		return maxChartStart
+	}
+	return end.AddDate(0, -cr.months, 0)
+}
+
+// parseChartRangeInput parses custom range input like "2016-01-04 2016-06-30".
+// The end date is optional and defaults to today.
+func parseChartRangeInput(input string) (start, end time.Time, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected start and optional end date, got %q", input)
+	}
+
+	start, err = time.Parse("2006-01-02", fields[0])
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end = chartToday()
+	if len(fields) == 2 {
+		end, err = time.Parse("2006-01-02", fields[1])
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start %s should be before end %s", fields[0], end.Format("2006-01-02"))
+	}
+
+	return start, end, nil
+}
+
+// chartTradingSessions returns the sessions in the map between start and end inclusive in chronological order.
+func chartTradingSessions(tsm map[time.Time]stockTradingSession, start, end time.Time) []stockTradingSession {
+	var tss []stockTradingSession
+	for date, ts := range tsm {
+		if !date.Before(start) && !date.After(end) {
+			tss = append(tss, ts)
+		}
+	}
+	sort.Slice(tss, func(i, j int) bool {
+		return tss[i].date.Before(tss[j].date)
+	})
+	return tss
+}
+
+// movingAverage returns the simple moving average of the closing prices of the chronological sessions.
+// Values without enough prior sessions are NaN.
+func movingAverage(tss []stockTradingSession, period int) []float64 {
+	mas := make([]float64, len(tss))
+	var sum float64
+	for i, ts := range tss {
+		sum += ts.close
+		if i >= period {
+			sum -= tss[i-period].close
+		}
+		if i+1 < period {
+			mas[i] = math.NaN()
+			continue
+		}
+		mas[i] = sum / float64(period)
+	}
+	return mas
+}
+
+// chartData returns the sessions between start and end and their moving averages.
+func chartData(tsm map[time.Time]stockTradingSession, start, end time.Time, co chartOptions) (tss []stockTradingSession, mas [][]float64) {
+	// Include all earlier sessions to calculate the moving averages at the start.
+	all := chartTradingSessions(tsm, time.Time{}, end)
+
+	i := sort.Search(len(all), func(i int) bool {
+		return !all[i].date.Before(start)
+	})
+
+	for _, p := range co.movingAverages {
+		mas = append(mas, movingAverage(all, p)[i:])
+	}
+	return all[i:], mas
+}
+
+// printChart prints a chart of the closing prices and moving averages within the given bounds.
+// The column of the session on or after markDate is highlighted if markDate is not zero.
+// The price labels have the precision's decimal places.
+func printChart(x, y, w, h int, tss []stockTradingSession, mas [][]float64, precision int, logScale bool, markDate time.Time) {
+	print := func(x, y int, fg termbox.Attribute, format string, a ...interface{}) {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, termbox.ColorDefault)
+			x++
+		}
+	}
+
+	if len(tss) == 0 {
+		print(x, y, termbox.ColorDefault, "No data")
+		return
+	}
+
+	// labelWidth is the width of the price labels on the left side.
+	const labelWidth = 10
+
+	// Leave room for the price labels and the date labels at the bottom.
+	cw, ch := w-labelWidth-padding, h-1
+	if cw <= 0 || ch <= 0 {
+		return
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, ts := range tss {
+		min = math.Min(min, ts.close)
+		max = math.Max(max, ts.close)
+	}
+	for _, ma := range mas {
+		for _, v := range ma {
+			if !math.IsNaN(v) {
+				min = math.Min(min, v)
+				max = math.Max(max, v)
+			}
+		}
+	}
+
+	// Prices at or below zero can't be drawn on a log scale.
+	if min <= 0 {
+		logScale = false
+	}
+
+	scale := func(price float64) float64 {
+		if logScale {
+			return math.Log(price)
+		}
+		return price
+	}
+
+	// Color the chart green or red depending on the change over the range.
+	fg := upColor
+	if tss[len(tss)-1].close < tss[0].close {
+		fg = downColor
+	}
+
+	// getRow returns the chart row of the price with the max at the top.
+	getRow := func(price float64) int {
+		if max == min {
+			return ch / 2
+		}
+		return int(math.Round((scale(max) - scale(price)) / (scale(max) - scale(min)) * float64(ch-1)))
+	}
+
+	print(x, y, termbox.ColorDefault, "%[1]*s", labelWidth, fitNumber(formatNumber(max, precision), labelWidth))
+	print(x, y+ch-1, termbox.ColorDefault, "%[1]*s", labelWidth, fitNumber(formatNumber(min, precision), labelWidth))
+
+	// markIndex is the index of the session to highlight or -1 if none.
+	markIndex := -1
+	if !markDate.IsZero() {
+		markIndex = sort.Search(len(tss), func(i int) bool {
+			return !tss[i].date.Before(markDate)
+		})
+	}
+
+	// Print a column for each screen column by sampling the sessions.
+	cx := x + labelWidth + padding
+	markColumn := -1
+	for i := 0; i < cw; i++ {
+		j := i * len(tss) / cw
+		if len(tss) < cw {
+			if i >= len(tss) {
+				break
+			}
+			j = i
+		}
+
+		// Highlight the first column that reaches the marked session.
+		cfg := fg
+		if markIndex != -1 && markColumn == -1 && j >= markIndex {
+			cfg, markColumn = termbox.ColorYellow|termbox.AttrBold, i
+		}
+
+		for r := getRow(tss[j].close); r < ch; r++ {
+			c := '│'
+			if r == getRow(tss[j].close) {
+				c = '•'
+			}
+			term.setCell(cx+i, y+r, c, cfg, termbox.ColorDefault)
+		}
+
+		for k, ma := range mas {
+			if !math.IsNaN(ma[j]) {
+				term.setCell(cx+i, y+getRow(ma[j]), '·', movingAverageColors[k%len(movingAverageColors)], termbox.ColorDefault)
+			}
+		}
+	}
+
+	start, end := tss[0].date.Format("1/2/06"), tss[len(tss)-1].date.Format("1/2/06")
+	last := cw
+	if len(tss) < cw {
+		last = len(tss)
+	}
+	print(cx, y+ch, termbox.ColorDefault, start)
+	print(cx+last-len(end), y+ch, termbox.ColorDefault, end)
+
+	if markColumn != -1 {
+		term.setCell(cx+markColumn, y+ch, '▲', termbox.ColorYellow|termbox.AttrBold, termbox.ColorDefault)
+	}
+}
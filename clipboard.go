@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/atotto/clipboard"
+)
+
+// errClipboardUnsupported is returned when the system has no clipboard utility like xclip or xsel.
+var errClipboardUnsupported = errors.New("clipboard unsupported")
+
+// copyToClipboard copies the text to the system clipboard.
+func copyToClipboard(text string) error {
+	if clipboard.Unsupported {
+		return errClipboardUnsupported
+	}
+	return clipboard.WriteAll(text)
+}
+
+// pasteFromClipboard returns the text in the system clipboard.
+func pasteFromClipboard() (string, error) {
+	if clipboard.Unsupported {
+		return "", errClipboardUnsupported
+	}
+	return clipboard.ReadAll()
+}
+
+// quoteLine returns the stock's latest quote as a line of text to copy like
+// "AAPL 112.34 +1.23 +1.11% 32.1M 10/14/16". It returns just the symbol if there is no data.
+func quoteLine(s stock) string {
+	ts, ok := latestTradingSession(s.tradingSessionMap)
+	if !ok {
+		return s.symbol
+	}
+	return fmt.Sprintf("%s %.2f %+.2f %+.2f%% %s %s", s.symbol, ts.close, ts.change, ts.percentChange*100.0, shortenInt(ts.volume), ts.date.Format("1/2/06"))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"os"
+
+	"github.com/nsf/termbox-go"
+)
+
+// trueColor is a flag to control whether 24-bit colors are used.
+var trueColor = flag.String("true_color", "auto", "Whether to use 24-bit colors: auto, on, or off. Auto checks if COLORTERM is truecolor or 24bit or if running in Windows Terminal.")
+
+// colorMode is the color output mode that the terminal supports.
+type colorMode int
+
+// List of possible colorMode values.
+const (
+	normalColors colorMode = iota
+	palette256Colors
+	trueColors
+)
+
+// setColorMode sets the best output mode the terminal supports, falling back from 24-bit to 256 to normal colors.
+func setColorMode() colorMode {
+	want := *trueColor == "on"
+	if *trueColor == "auto" {
+		want = terminalHasTrueColor()
+	}
+
+	if want && term.setOutputMode(termbox.OutputRGB) == termbox.OutputRGB {
+		return trueColors
+	}
+	if term.setOutputMode(termbox.Output256) == termbox.Output256 {
+		return palette256Colors
+	}
+	return normalColors
+}
+
+// textAttributes are the attributes like bold that can be combined with a color.
+const textAttributes = termbox.AttrBold | termbox.AttrBlink | termbox.AttrHidden | termbox.AttrDim |
+	termbox.AttrUnderline | termbox.AttrCursive | termbox.AttrReverse
+
+// terminalHasTrueColor returns whether the terminal says it supports 24-bit colors.
+// Windows Terminal doesn't set COLORTERM but sets WT_SESSION in its shells.
+func terminalHasTrueColor() bool {
+	ct := os.Getenv("COLORTERM")
+	return ct == "truecolor" || ct == "24bit" || os.Getenv("WT_SESSION") != ""
+}
+
+// color converts a color from the 256 color palette into one the mode can show.
+func (m colorMode) color(a termbox.Attribute) termbox.Attribute {
+	switch m {
+	case trueColors:
+		return trueColorAttribute(a)
+	case palette256Colors:
+		return a
+	default:
+		return termbox.ColorDefault
+	}
+}
+
+// trueColorAttribute converts a named color like ColorGreen or a palette color into a 24-bit color
+// and keeps attributes like bold. termbox reads every color as 24-bit in its RGB mode,
+// so the named colors would show as black. Default and 24-bit colors are returned as is.
+func trueColorAttribute(a termbox.Attribute) termbox.Attribute {
+	// The 24-bit colors are stored above the attribute bits.
+	c := a &^ textAttributes
+	if c == termbox.ColorDefault || c > textAttributes {
+		return a
+	}
+	r, g, b := paletteRGB(c)
+	return termbox.RGBToAttribute(r, g, b) | a&textAttributes
+}
+
+// changeColor returns the background color of the session's price change.
+// True color modes blend between the palette steps for a smooth gradient.
+func (m colorMode) changeColor(ts stockTradingSession) termbox.Attribute {
+	var colors [colorCount]termbox.Attribute
+	switch {
+	case ts.change > 0:
+		colors = positiveColors
+	case ts.change < 0:
+		colors = negativeColors
+	default:
+		return termbox.ColorDefault
+	}
+
+	c := colorLevel(ts.percentChange)
+	if m != trueColors || c == colorCount-1 {
+		return m.color(colors[c])
+	}
+
+	// Blend the color of this level with the next based on how far the change is between them.
+	f := (math.Abs(ts.percentChange) - colorLevels[c]) / (colorLevels[c+1] - colorLevels[c])
+	r1, g1, b1 := paletteRGB(colors[c])
+	r2, g2, b2 := paletteRGB(colors[c+1])
+	blend := func(v1, v2 uint8) uint8 {
+		return uint8(float64(v1) + (float64(v2)-float64(v1))*f + 0.5)
+	}
+	return termbox.RGBToAttribute(blend(r1, r2), blend(g1, g2), blend(b1, b2))
+}
+
+// paletteRGB returns the red, green, and blue values of a color in the 256 color palette.
+func paletteRGB(a termbox.Attribute) (r, g, b uint8) {
+	// Output256 attributes are offset by one, because zero is the default color.
+	i := int(a) - 1
+
+	switch {
+	case i < 0:
+		return 0, 0, 0
+
+	case i < 16:
+		// Use the xterm defaults for the system colors.
+		system := [16][3]uint8{
+			{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+			{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+			{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+			{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+		}
+		return system[i][0], system[i][1], system[i][2]
+
+	case i < 232:
+		// Colors 16 to 231 are a 6x6x6 cube.
+		levels := [6]uint8{0, 95, 135, 175, 215, 255}
+		i -= 16
+		return levels[i/36], levels[i/6%6], levels[i%6]
+
+	default:
+		// Colors 232 to 255 are a grayscale ramp.
+		v := uint8(8 + 10*(i-232))
+		return v, v, v
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestTrueColorAttribute(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		attr termbox.Attribute
+		want termbox.Attribute
+	}{
+		{"default", termbox.ColorDefault, termbox.ColorDefault},
+		{"default with attributes", termbox.ColorDefault | termbox.AttrBold, termbox.ColorDefault | termbox.AttrBold},
+		{"named color", termbox.ColorGreen, termbox.RGBToAttribute(0, 205, 0)},
+		{"bright named color", termbox.ColorLightRed, termbox.RGBToAttribute(255, 0, 0)},
+		{"named color with attributes", termbox.ColorYellow | termbox.AttrBold | termbox.AttrReverse, termbox.RGBToAttribute(205, 205, 0) | termbox.AttrBold | termbox.AttrReverse},
+		{"palette color", termbox.Attribute(197), termbox.RGBToAttribute(255, 0, 0)},
+		{"gray palette color", termbox.Attribute(233), termbox.RGBToAttribute(8, 8, 8)},
+		{"24-bit color", termbox.RGBToAttribute(12, 34, 56), termbox.RGBToAttribute(12, 34, 56)},
+		{"black 24-bit color", termbox.RGBToAttribute(0, 0, 0) | termbox.AttrBold, termbox.RGBToAttribute(0, 0, 0) | termbox.AttrBold},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := trueColorAttribute(tt.attr); got != tt.want {
+				t.Errorf("trueColorAttribute(%#x) = %#x, want %#x", tt.attr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorModeColor(t *testing.T) {
+	for _, tt := range []struct {
+		mode colorMode
+		attr termbox.Attribute
+		want termbox.Attribute
+	}{
+		{normalColors, termbox.Attribute(197), termbox.ColorDefault},
+		{palette256Colors, termbox.Attribute(197), termbox.Attribute(197)},
+		{trueColors, termbox.Attribute(197), termbox.RGBToAttribute(255, 0, 0)},
+		{trueColors, termbox.ColorRed, termbox.RGBToAttribute(205, 0, 0)},
+	} {
+		if got := tt.mode.color(tt.attr); got != tt.want {
+			t.Errorf("colorMode(%d).color(%#x) = %#x, want %#x", tt.mode, tt.attr, got, tt.want)
+		}
+	}
+}
+
+func TestTerminalHasTrueColor(t *testing.T) {
+	for _, tt := range []struct {
+		desc      string
+		colorTerm string
+		wtSession string
+		want      bool
+	}{
+		{desc: "truecolor", colorTerm: "truecolor", want: true},
+		{desc: "24bit", colorTerm: "24bit", want: true},
+		{desc: "windows terminal", wtSession: "6b1e0a4c-0d9e-4f8e-9d2c-4a1f0e8b7c3d", want: true},
+		{desc: "256 colors", colorTerm: "256color"},
+		{desc: "nothing set"},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Setenv("COLORTERM", tt.colorTerm)
+			t.Setenv("WT_SESSION", tt.wtSession)
+			if got := terminalHasTrueColor(); got != tt.want {
+				t.Errorf("terminalHasTrueColor() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
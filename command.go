@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// commandHelp lists the commands that can be typed after ':'.
+var commandHelp = "add SYMBOLS, delete, pin, open, refresh, filter [TEXT], sort symbol|change|percent|volume|sector, sector|industry [NAME], source google|yahoo|random, screen gainers|losers|active [pe<N] [cap>N], view daily|weekly|monthly, theme default|deuteranopia, quit"
+
+// parseCommand splits a command line like "add AAPL MSFT" into the command's name and arguments.
+func parseCommand(line string) (name string, args []string) {
+	fs := strings.Fields(line)
+	if len(fs) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fs[0]), fs[1:]
+}
+
+// sortStocks sorts the stocks by the key while keeping the pinned stocks first.
+// Stocks are sorted by symbol in ascending order, grouped by sector and industry with
+// unclassified stocks last, or by their latest session's change, percent change, or
+// volume in descending order with missing data last.
+func sortStocks(stocks []stock, key string) error {
+	var value func(ts stockTradingSession) float64
+	switch key {
+	case "sector":
+		sort.SliceStable(stocks, func(i, j int) bool {
+			si, sj := stocks[i], stocks[j]
+			if si.pinned != sj.pinned {
+				return si.pinned
+			}
+			if (si.sector == "") != (sj.sector == "") {
+				return sj.sector == ""
+			}
+			if si.sector != sj.sector {
+				return si.sector < sj.sector
+			}
+			if si.industry != sj.industry {
+				return si.industry < sj.industry
+			}
+			return si.symbol < sj.symbol
+		})
+		return nil
+	case "symbol":
+	case "change":
+		value = func(ts stockTradingSession) float64 { return ts.change }
+	case "percent":
+		value = func(ts stockTradingSession) float64 { return ts.percentChange }
+	case "volume":
+		value = func(ts stockTradingSession) float64 { return float64(ts.volume) }
+	default:
+		return fmt.Errorf("unknown sort key: %q", key)
+	}
+
+	sort.SliceStable(stocks, func(i, j int) bool {
+		si, sj := stocks[i], stocks[j]
+		if si.pinned != sj.pinned {
+			return si.pinned
+		}
+		if value == nil {
+			return si.symbol < sj.symbol
+		}
+
+		ti, iok := latestTradingSession(si.tradingSessionMap)
+		tj, jok := latestTradingSession(sj.tradingSessionMap)
+		if iok != jok {
+			return iok
+		}
+		return value(ti) > value(tj)
+	})
+	return nil
+}
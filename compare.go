@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// maxCompareSymbols is the most symbols that can be compared at once.
+const maxCompareSymbols = 5
+
+// compareStyles are the colors and markers of the compared symbols' lines.
+// The markers tell the lines apart without colors.
+var compareStyles = []struct {
+	color  termbox.Attribute
+	marker rune
+}{
+	{termbox.ColorCyan, '*'},
+	{termbox.ColorYellow, 'o'},
+	{termbox.ColorMagenta, '+'},
+	{termbox.ColorGreen, 'x'},
+	{termbox.ColorRed, '#'},
+}
+
+// normalizePerformance returns the closes of the chronological sessions scaled so the first is 100.
+func normalizePerformance(tss []stockTradingSession) []float64 {
+	if len(tss) == 0 || tss[0].close == 0 {
+		return nil
+	}
+	vs := make([]float64, len(tss))
+	for i, ts := range tss {
+		vs[i] = ts.close / tss[0].close * 100
+	}
+	return vs
+}
+
+// printCompare plots the relative performance of the symbols from start to end as overlaid lines.
+func printCompare(sd *stockData, symbols []string, rangeLabel string, start, end time.Time, w, h int) {
+	print := func(x, y int, fg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, termbox.ColorDefault)
+			x++
+		}
+		return x
+	}
+
+	print(0, 0, termbox.ColorDefault, " Relative performance over %s (start = 100)  Tab: Range", rangeLabel)
+
+	if len(symbols) < 2 {
+		print(1, 2, termbox.ColorDefault, "Mark 2 to %d stocks with '+' in the grid to compare them.", maxCompareSymbols)
+		return
+	}
+
+	type series struct {
+		tss    []stockTradingSession
+		values []float64
+	}
+	var ss []series
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, symbol := range symbols {
+		var s series
+		for _, st := range sd.stocks {
+			if st.symbol == symbol {
+				s.tss = chartTradingSessions(st.tradingSessionMap, start, end)
+				s.values = normalizePerformance(s.tss)
+			}
+		}
+		for _, v := range s.values {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		ss = append(ss, s)
+	}
+
+	// Print the legend with each symbol's latest relative value.
+	x := 1
+	for i, symbol := range symbols {
+		st := compareStyles[i]
+		x = print(x, 1, st.color, "%c %s", st.marker, symbol)
+		if vs := ss[i].values; len(vs) > 0 {
+			x = print(x, 1, st.color, " %.1f", vs[len(vs)-1])
+		}
+		x += 3
+	}
+
+	// labelWidth is the width of the value labels on the left side.
+	const labelWidth = 7
+
+	// Leave room for the title, legend, and the date labels at the bottom.
+	cx, cy := labelWidth+padding, 3
+	cw, ch := w-cx-padding, h-cy-1
+	if cw <= 1 || ch <= 1 || math.IsInf(min, 0) {
+		return
+	}
+
+	getRow := func(v float64) int {
+		if max == min {
+			return cy + ch/2
+		}
+		return cy + int(math.Round((max-v)/(max-min)*float64(ch-1)))
+	}
+	getColumn := func(date time.Time) float64 {
+		return float64(date.Sub(start)) / float64(end.Sub(start)) * float64(cw-1)
+	}
+
+	print(0, cy, termbox.ColorDefault, "%[1]*.1f", labelWidth, max)
+	print(0, cy+ch-1, termbox.ColorDefault, "%[1]*.1f", labelWidth, min)
+	if min < 100 && max > 100 {
+		print(0, getRow(100), termbox.ColorDefault, "%[1]*.1f", labelWidth, 100.0)
+		for i := 0; i < cw; i++ {
+			term.setCell(cx+i, getRow(100), '·', termbox.ColorDefault, termbox.ColorDefault)
+		}
+	}
+
+	// Draw each line by interpolating between the sessions for every column in between.
+	for i, s := range ss {
+		st := compareStyles[i]
+		for j := range s.values {
+			c1, v1 := getColumn(s.tss[j].date), s.values[j]
+			c2, v2 := c1, v1
+			if j+1 < len(s.values) {
+				c2, v2 = getColumn(s.tss[j+1].date), s.values[j+1]
+			}
+			for c := int(math.Round(c1)); c <= int(math.Round(c2)); c++ {
+				v := v1
+				if c2 > c1 {
+					v = v1 + (v2-v1)*(float64(c)-c1)/(c2-c1)
+				}
+				if c >= 0 && c < cw {
+					term.setCell(cx+c, getRow(v), st.marker, st.color, termbox.ColorDefault)
+				}
+			}
+		}
+	}
+
+	print(cx, h-1, termbox.ColorDefault, "%s", start.Format("1/2/06"))
+	endLabel := end.Format("1/2/06")
+	print(cx+cw-len(endLabel), h-1, termbox.ColorDefault, "%s", endLabel)
+}
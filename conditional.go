@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// maxConditionalResponses is the most responses kept to serve when the server says they are unchanged.
+const maxConditionalResponses = 500
+
+// conditionalResponse is a response body with the validators to send when requesting it again.
+type conditionalResponse struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// conditionalResponses is a map from URL to the latest response that had validators.
+var conditionalResponses = struct {
+	// Embedded mutex that guards the map.
+	sync.Mutex
+	m map[string]conditionalResponse
+}{
+	m: map[string]conditionalResponse{},
+}
+
+// addConditionalHeaders adds the validators of the URL's cached response to the GET request,
+// so that the server can respond with 304 Not Modified if the response has not changed.
+func addConditionalHeaders(req *http.Request) {
+	if req.Method != "GET" {
+		return
+	}
+
+	conditionalResponses.Lock()
+	cr, ok := conditionalResponses.m[req.URL.String()]
+	conditionalResponses.Unlock()
+	if !ok {
+		return
+	}
+
+	if cr.etag != "" {
+		req.Header.Set("If-None-Match", cr.etag)
+	}
+	if cr.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cr.lastModified)
+	}
+}
+
+// handleConditionalResponse replaces a 304 response with the cached response and caches
+// successful responses with validators. Callers only see 200 responses for cached URLs.
+func handleConditionalResponse(resp *http.Response) (*http.Response, error) {
+	req := resp.Request
+	if req == nil || req.Method != "GET" {
+		return resp, nil
+	}
+	key := req.URL.String()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		conditionalResponses.Lock()
+		cr, ok := conditionalResponses.m[key]
+		conditionalResponses.Unlock()
+		if !ok {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		countNotModified()
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        cr.header,
+			Body:          ioutil.NopCloser(bytes.NewReader(cr.body)),
+			ContentLength: int64(len(cr.body)),
+			Request:       req,
+		}, nil
+
+	case http.StatusOK:
+		etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if etag == "" && lastModified == "" {
+			return resp, nil
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		conditionalResponses.Lock()
+		if _, ok := conditionalResponses.m[key]; !ok && len(conditionalResponses.m) >= maxConditionalResponses {
+			// Make room by dropping any one of the responses.
+			for k := range conditionalResponses.m {
+				delete(conditionalResponses.m, k)
+				break
+			}
+		}
+		conditionalResponses.m[key] = conditionalResponse{
+			etag:         etag,
+			lastModified: lastModified,
+			header:       resp.Header,
+			body:         body,
+		}
+		conditionalResponses.Unlock()
+	}
+
+	return resp, nil
+}
@@ -2,29 +2,202 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/user"
 	"path"
+	"strings"
 	"sync"
+	"time"
 )
 
+// profile is a flag to use a separate set of config, cache, and state files.
+var profile = flag.String("profile", "", "Name of a profile like work or paper with its own watchlist, settings, cache, and state.")
+
 // config has the user's saved stocks.
 type config struct {
 	// Stocks are the config's stocks. Capitalized for JSON decoding.
 	Stocks []configStock
+
+	// ChartLayouts are the user's saved chart layouts. Capitalized for JSON decoding.
+	ChartLayouts []configChartLayout
+
+	// TimeZone is the name of the time zone like "America/Los_Angeles" to show times in.
+	// Times are shown in the local time zone if empty. Capitalized for JSON decoding.
+	TimeZone string
+
+	// Periods are the labels of the performance periods like "YTD", "1M", "3M", or "1Y" to show
+	// for each stock. Only YTD is shown if empty. Capitalized for JSON decoding.
+	Periods []string
+
+	// SectorETFs are the symbols shown in the sector strip toggled with Ctrl+E.
+	// The SPDR sector ETFs are shown if empty. Capitalized for JSON decoding.
+	SectorETFs []string
+
+	// QuoteURL is the URL template of the page to open for the selected symbol like
+	// "https://www.tradingview.com/symbols/{symbol}". Yahoo Finance is used if empty.
+	// Capitalized for JSON decoding.
+	QuoteURL string
+
+	// Hooks are the commands to run when events happen. Capitalized for JSON decoding.
+	Hooks []configHook
+
+	// AlpacaKeyID and AlpacaSecretKey are the Alpaca API credentials to use instead of the ones
+	// in the environment, so that profiles can use different accounts. Capitalized for JSON decoding.
+	AlpacaKeyID, AlpacaSecretKey string
+
+	// Events are economic events like CPI releases to mark in the date header with -econ_events.
+	// Capitalized for JSON decoding.
+	Events []configEconEvent
+
+	// SymbolMappings is a map from source like "yahoo" to a map from the symbol shown like "BRK.B"
+	// to the one the source uses like "BRK-B". Capitalized for JSON decoding.
+	SymbolMappings map[string]map[string]string
+
+	// AlpacaURL is the Alpaca trading API endpoint to use instead of -alpaca_url, like the live
+	// endpoint for a profile with live credentials. Capitalized for JSON decoding.
+	AlpacaURL string
+
+	// HistoryRetentionDays is how many days of sessions to keep in the history database.
+	// Sessions are kept forever if zero. Capitalized for JSON decoding.
+	HistoryRetentionDays int
+
+	// Precisions is a map from asset class like "currency" or "stock" to the number of decimal places
+	// to show its prices with. Currencies use 4 and the rest use 2 if missing. Capitalized for JSON decoding.
+	Precisions map[string]int
+
+	// Locale is the locale like "de-DE" whose thousands and decimal separators prices are shown with.
+	// Prices are shown like 34,123.45 if empty. Capitalized for JSON decoding.
+	Locale string
+
+	// Sales are the realized sales of all the stocks including the ones no longer in the watchlist.
+	// Capitalized for JSON decoding.
+	Sales []configSale
+}
+
+// configHook represents a command to run when an event happens.
+type configHook struct {
+	// Event is the event like "post-refresh" or "fetch-failed". Capitalized for JSON decoding.
+	Event string
+
+	// Command is the shell command to run. It gets PONZI_EVENT and, for fetch-failed, PONZI_SYMBOL
+	// and PONZI_ERROR in its environment and the latest quotes as JSON on stdin.
+	// Capitalized for JSON decoding.
+	Command string
+}
+
+// configEconEvent represents an economic event to mark in the date header.
+type configEconEvent struct {
+	// Date is the date of the event. Capitalized for JSON decoding.
+	Date time.Time
+
+	// Name is a short name like "CPI". Capitalized for JSON decoding.
+	Name string
+
+	// Description says what the event is. Capitalized for JSON decoding.
+	Description string
+}
+
+// configChartLayout represents a user's saved chart layout.
+type configChartLayout struct {
+	// Name is the layout's name. Capitalized for JSON decoding.
+	Name string
+
+	// Range is the label of a preset range like "1Y" or "Custom". Capitalized for JSON decoding.
+	Range string
+
+	// Start and End are the dates of a custom range. Capitalized for JSON decoding.
+	Start, End time.Time
+
+	// MovingAverages are the periods of the moving averages. Capitalized for JSON decoding.
+	MovingAverages []int
+
+	// LogScale is whether to use a logarithmic price scale. Capitalized for JSON decoding.
+	LogScale bool
 }
 
 // configStock represents a single user's stock.
 type configStock struct {
 	// Symbol is the stock's symbol. Capitalized for JSON decoding.
 	Symbol string
+
+	// Lots are the user's lots of the stock. Capitalized for JSON decoding.
+	Lots []configLot
+
+	// Dividends are the stock's manually entered dividends. Capitalized for JSON decoding.
+	Dividends []configDividend
+
+	// Sales are the stock's realized sales saved by older versions. They are moved to the config's
+	// Sales when loaded. Capitalized for JSON decoding.
+	Sales []configSale
+
+	// Pinned is whether the stock stays at the top while scrolling. Capitalized for JSON decoding.
+	Pinned bool
+
+	// Source is the data source like "yahoo" to get the stock's trading sessions from instead of
+	// -data_source. Capitalized for JSON decoding.
+	Source string
+
+	// Sector and Industry are the stock's classification like "Technology" and "Semiconductors".
+	// Capitalized for JSON decoding.
+	Sector, Industry string
+
+	// Precision is the number of decimal places to show the stock's prices with like 3 for some ETFs.
+	// The precision of its asset class is used if zero. Capitalized for JSON decoding.
+	Precision int
+}
+
+// configSale represents a sale of shares from a single lot.
+type configSale struct {
+	// Symbol is the symbol of the stock sold. Capitalized for JSON decoding.
+	Symbol string
+
+	// Date is when the shares were sold. Capitalized for JSON decoding.
+	Date time.Time
+
+	// Acquired is when the lot was bought. Capitalized for JSON decoding.
+	Acquired time.Time
+
+	// Quantity is the number of shares sold. Capitalized for JSON decoding.
+	Quantity float64
+
+	// Proceeds is the total amount received. Capitalized for JSON decoding.
+	Proceeds float64
+
+	// Cost is the cost basis of the shares sold. Capitalized for JSON decoding.
+	Cost float64
+}
+
+// configDividend represents a manually entered dividend per share.
+type configDividend struct {
+	// Date is the ex-dividend date. Capitalized for JSON decoding.
+	Date time.Time
+
+	// Amount is the amount paid per share. Capitalized for JSON decoding.
+	Amount float64
+}
+
+// configLot represents a lot of a user's stock.
+type configLot struct {
+	// Date is when the lot was bought. Capitalized for JSON decoding.
+	Date time.Time
+
+	// Quantity is the number of shares. Capitalized for JSON decoding.
+	Quantity float64
+
+	// Cost is the total cost basis. Capitalized for JSON decoding.
+	Cost float64
 }
 
 // configMutex prevents config file reads and writes from conflicting.
 var configMutex sync.RWMutex
 
+// configSaves tracks the config saves in the background to wait for before exiting.
+var configSaves sync.WaitGroup
+
 // loadConfig loads the user's config from disk.
 func loadConfig() (config, error) {
 	configMutex.RLock()
@@ -50,9 +223,47 @@ func loadConfig() (config, error) {
 	if err := d.Decode(&cfg); err != nil && err != io.EOF {
 		return config{}, err
 	}
+	cfg.Stocks = mergeDuplicateStocks(cfg.Stocks)
 	return cfg, nil
 }
 
+// mergeDuplicateStocks merges stocks with the same symbol into the first one, since older
+// versions let the same symbol be added more than once. The duplicates' lots, dividends,
+// and sales are appended to the first stock's, and their source, sector, industry, and
+// precision fill in the ones the first stock doesn't have.
+func mergeDuplicateStocks(css []configStock) []configStock {
+	var merged []configStock
+	im := map[string]int{}
+	for _, cs := range css {
+		i, ok := im[cs.Symbol]
+		if !ok {
+			im[cs.Symbol] = len(merged)
+			merged = append(merged, cs)
+			continue
+		}
+
+		log.Printf("merging duplicate symbol: %s", cs.Symbol)
+		m := &merged[i]
+		m.Lots = append(m.Lots, cs.Lots...)
+		m.Dividends = append(m.Dividends, cs.Dividends...)
+		m.Sales = append(m.Sales, cs.Sales...)
+		m.Pinned = m.Pinned || cs.Pinned
+		if m.Source == "" {
+			m.Source = cs.Source
+		}
+		if m.Sector == "" {
+			m.Sector = cs.Sector
+		}
+		if m.Industry == "" {
+			m.Industry = cs.Industry
+		}
+		if m.Precision == 0 {
+			m.Precision = cs.Precision
+		}
+	}
+	return merged
+}
+
 // saveConfig saves the user's config to disk.
 func saveConfig(cfg config) error {
 	configMutex.Lock()
@@ -62,13 +273,30 @@ func saveConfig(cfg config) error {
 	if err != nil {
 		return err
 	}
+	return writeConfig(cfgPath, cfg)
+}
+
+// writeConfig writes the config to the file. Only the user can read the file if it has the Alpaca
+// credentials, since anyone who can read them can trade with the account.
+func writeConfig(cfgPath string, cfg config) error {
+	var perm os.FileMode = 0660
+	if cfg.AlpacaKeyID != "" || cfg.AlpacaSecretKey != "" {
+		perm = 0600
+	}
 
-	file, err := os.OpenFile(cfgPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	file, err := os.OpenFile(cfgPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
+	// OpenFile only uses the mode when it creates the file, so restrict a file saved before the credentials were added.
+	if perm == 0600 {
+		if err := file.Chmod(perm); err != nil {
+			return err
+		}
+	}
+
 	return json.NewEncoder(file).Encode(&cfg)
 }
 
@@ -86,17 +314,33 @@ func getUserConfigDir() (string, error) {
 		return "", err
 	}
 	p := path.Join(u.HomeDir, ".config", "ponzi")
+
+	// Keep each profile's config, cache, state, and logs in its own directory.
+	if *profile != "" {
+		if strings.ContainsAny(*profile, `/\`) || *profile == "." || *profile == ".." {
+			return "", fmt.Errorf("bad profile name: %q", *profile)
+		}
+		p = path.Join(p, "profiles", *profile)
+	}
 	if err := os.MkdirAll(p, 0755); err != nil {
 		return "", err
 	}
 	return p, nil
 }
 
-func initLogger() (*os.File, error) {
+// logFileName is the name of the log of the UI and the other modes.
+const logFileName = "log.txt"
+
+// statusLineLogFileName is the name of the log of the status line mode. It has its own log,
+// since status bars run it alongside the UI and truncating the UI's log would lose its messages.
+const statusLineLogFileName = "statusline-log.txt"
+
+// initLogger truncates the log file with the name in the config directory and redirects the logger to it.
+func initLogger(fileName string) (*os.File, error) {
 	configMutex.Lock()
 	defer configMutex.Unlock()
 
-	logPath, err := getUserLogPath()
+	logPath, err := getUserLogPath(fileName)
 	if err != nil {
 		return nil, err
 	}
@@ -110,10 +354,11 @@ func initLogger() (*os.File, error) {
 	return file, nil
 }
 
-func getUserLogPath() (string, error) {
+// getUserLogPath returns the path of the log file with the name in the config directory.
+func getUserLogPath(fileName string) (string, error) {
 	dirPath, err := getUserConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return path.Join(dirPath, "log.txt"), nil
+	return path.Join(dirPath, fileName), nil
 }
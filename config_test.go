@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteConfigPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't have Unix file permissions")
+	}
+
+	for _, tt := range []struct {
+		desc     string
+		existing bool
+		cfg      config
+		want     os.FileMode
+	}{
+		{
+			desc: "no credentials",
+			cfg:  config{Stocks: []configStock{{Symbol: "SPY"}}},
+			want: 0660,
+		},
+		{
+			desc: "new file with credentials",
+			cfg:  config{AlpacaKeyID: "key", AlpacaSecretKey: "secret"},
+			want: 0600,
+		},
+		{
+			desc:     "credentials added to an existing file",
+			existing: true,
+			cfg:      config{AlpacaKeyID: "key", AlpacaSecretKey: "secret"},
+			want:     0600,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			cfgPath := filepath.Join(t.TempDir(), "config.json")
+			if tt.existing {
+				if err := writeConfig(cfgPath, config{}); err != nil {
+					t.Fatalf("writeConfig() error = %v", err)
+				}
+				if err := os.Chmod(cfgPath, 0664); err != nil {
+					t.Fatalf("Chmod: %v", err)
+				}
+			}
+
+			if err := writeConfig(cfgPath, tt.cfg); err != nil {
+				t.Fatalf("writeConfig() error = %v", err)
+			}
+
+			fi, err := os.Stat(cfgPath)
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			// The umask may remove the group's permissions but never adds any.
+			if got := fi.Mode().Perm(); got&^tt.want != 0 {
+				t.Errorf("config mode = %v, want at most %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicateStocks(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 8, d, 0, 0, 0, 0, time.UTC) }
+
+	got := mergeDuplicateStocks([]configStock{
+		{Symbol: "AAPL", Lots: []configLot{{Date: day(24), Quantity: 1}}, Sector: "Technology"},
+		{Symbol: "MSFT"},
+		{Symbol: "AAPL", Lots: []configLot{{Date: day(25), Quantity: 2}}, Pinned: true, Source: "yahoo", Sector: "Consumer", Industry: "Consumer Electronics"},
+		{Symbol: "AAPL", Source: "google", Precision: 3, Dividends: []configDividend{{Date: day(26), Amount: 0.82}}},
+	})
+
+	want := []configStock{
+		{
+			Symbol:    "AAPL",
+			Lots:      []configLot{{Date: day(24), Quantity: 1}, {Date: day(25), Quantity: 2}},
+			Dividends: []configDividend{{Date: day(26), Amount: 0.82}},
+			Pinned:    true,
+			Source:    "yahoo",
+			Sector:    "Technology",
+			Industry:  "Consumer Electronics",
+			Precision: 3,
+		},
+		{Symbol: "MSFT"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeDuplicateStocks() = %+v, want %+v", got, want)
+	}
+}
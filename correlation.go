@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// correlation returns the Pearson correlation of the daily returns on the dates that both have returns.
+// It returns NaN if there are fewer than two common dates or either has no variance.
+func correlation(a, b map[time.Time]float64) float64 {
+	var xs, ys []float64
+	for date, x := range a {
+		if y, ok := b[date]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < 2 {
+		return math.NaN()
+	}
+
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= float64(len(xs))
+	my /= float64(len(ys))
+
+	var cov, vx, vy float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+		vx += (xs[i] - mx) * (xs[i] - mx)
+		vy += (ys[i] - my) * (ys[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+// printCorrelations prints a color coded matrix of the correlations between the stocks' daily returns.
+// The caller must hold the stockData read lock.
+func printCorrelations(sd *stockData, w, h int, colors colorMode) {
+	print := func(x, y int, fg, bg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, bg)
+			x++
+		}
+		return x
+	}
+
+	// cellWidth is the width of each matrix cell.
+	const cellWidth = 6
+
+	// getBg returns the background color of the correlation using the price change colors.
+	getBg := func(c float64) termbox.Attribute {
+		if math.IsNaN(c) {
+			return termbox.ColorDefault
+		}
+		i := int(math.Abs(c) * float64(colorCount))
+		if i >= colorCount {
+			i = colorCount - 1
+		}
+		if c < 0 {
+			return colors.color(negativeColors[i])
+		}
+		return colors.color(positiveColors[i])
+	}
+
+	print(0, 0, termbox.ColorDefault, termbox.ColorDefault, " Correlation of daily returns")
+
+	var returns []map[time.Time]float64
+	for _, s := range sd.stocks {
+		returns = append(returns, dailyReturns(s.tradingSessionMap))
+	}
+
+	// Print the symbols across the top.
+	x := symbolColumnWidth + padding*2
+	for _, s := range sd.stocks {
+		if x+cellWidth > w {
+			break
+		}
+		print(x, 2, termbox.ColorDefault, termbox.ColorDefault, "%[1]*s", cellWidth, s.symbol)
+		x += cellWidth + padding
+	}
+
+	for i, s := range sd.stocks {
+		y := 3 + i
+		if y >= h-1 {
+			break
+		}
+
+		print(padding, y, termbox.ColorDefault, termbox.ColorDefault, "%[1]*s", symbolColumnWidth, s.symbol)
+
+		x := symbolColumnWidth + padding*2
+		for j := range sd.stocks {
+			if x+cellWidth > w {
+				break
+			}
+
+			c := 1.0
+			if i != j {
+				c = correlation(returns[i], returns[j])
+			}
+
+			if math.IsNaN(c) {
+				print(x, y, termbox.ColorDefault, termbox.ColorDefault, "%[1]*s", cellWidth, "-")
+			} else {
+				print(x, y, termbox.ColorDefault, getBg(c), "%+[1]*.2f", cellWidth, c)
+			}
+			x += cellWidth + padding
+		}
+	}
+
+	print(0, h-1, termbox.ColorDefault, termbox.ColorDefault, " Esc:Back")
+}
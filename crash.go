@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+)
+
+// crashExitCode is the exit code after a panic, which matches the one the Go runtime uses.
+const crashExitCode = 2
+
+// recoverCrash recovers from a panic, restores the terminal, writes the stack trace to the log,
+// and exits after printing a short summary to stderr. It must be deferred in each go routine
+// that should not leave the terminal in raw mode when it panics.
+func recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	// Restore the terminal first, so the summary is printed to a usable shell.
+	term.close()
+
+	log.Printf("panic: %v\n%s", r, debug.Stack())
+
+	fmt.Fprintf(os.Stderr, "ponzi crashed: %v\n", r)
+	if logPath, err := getUserLogPath(logFileName); err == nil {
+		fmt.Fprintf(os.Stderr, "See %s for the stack trace.\n", logPath)
+	}
+	os.Exit(crashExitCode)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns is a map from column name in the header row to a function that parses
+// the column's value of the current record into a field.
+type csvColumns map[string]func(value string) error
+
+// readCSV reads CSV with a header row. For each record, it calls the column functions
+// and then the record function to collect the parsed fields. Columns that are not in
+// the map are ignored, but every column in the map must be in the header row.
+func readCSV(r io.Reader, columns csvColumns, record func()) error {
+	cr := csv.NewReader(r)
+
+	// indices is a map from column name to its index in the records.
+	var indices map[string]int
+
+	for {
+		values, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				// Treat an empty response as an error rather than no records.
+				if indices == nil {
+					return errors.New("missing header row")
+				}
+				return nil
+			}
+			return err
+		}
+
+		if indices == nil {
+			indices = map[string]int{}
+			for i, v := range values {
+				// Trim any byte order mark at the start of the header row.
+				indices[strings.TrimSpace(strings.TrimPrefix(v, "\ufeff"))] = i
+			}
+			for name := range columns {
+				if _, ok := indices[name]; !ok {
+					return fmt.Errorf("missing column %q in header %q", name, values)
+				}
+			}
+			continue
+		}
+
+		for name, parse := range columns {
+			if err := parse(values[indices[name]]); err != nil {
+				return fmt.Errorf("column %q: %v", name, err)
+			}
+		}
+		record()
+	}
+}
+
+// csvDate returns a column function that parses dates with the layout into the field.
+func csvDate(field *time.Time, layout string) func(string) error {
+	return func(value string) (err error) {
+		*field, err = time.Parse(layout, value)
+		return err
+	}
+}
+
+// csvFloat returns a column function that parses floats with commas into the field.
+func csvFloat(field *float64) func(string) error {
+	return func(value string) (err error) {
+		*field, err = parseFloat(value)
+		return err
+	}
+}
+
+// csvInt returns a column function that parses integers into the field.
+func csvInt(field *int64) func(string) error {
+	return func(value string) (err error) {
+		*field, err = strconv.ParseInt(value, 10, 64)
+		return err
+	}
+}
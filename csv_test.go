@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSV(t *testing.T) {
+	type row struct {
+		symbol string
+		price  float64
+	}
+
+	for _, tt := range []struct {
+		desc    string
+		data    string
+		want    []row
+		wantErr bool
+	}{
+		{
+			desc: "records in order",
+			data: "Symbol,Price\nAAPL,499.23\nMSFT,228.91\n",
+			want: []row{{"AAPL", 499.23}, {"MSFT", 228.91}},
+		},
+		{
+			desc: "columns in another order with extra columns",
+			data: "Price,Exchange,Symbol\n499.23,NASDAQ,AAPL\n",
+			want: []row{{"AAPL", 499.23}},
+		},
+		{
+			desc: "byte order mark and spaces in the header",
+			data: "\ufeff Symbol , Price\nAAPL,499.23\n",
+			want: []row{{"AAPL", 499.23}},
+		},
+		{
+			desc: "quoted value with commas",
+			data: "Symbol,Price\nTSLA,\"2,213.40\"\n",
+			want: []row{{"TSLA", 2213.40}},
+		},
+		{
+			desc: "header without records",
+			data: "Symbol,Price\n",
+		},
+		{
+			desc:    "empty",
+			data:    "",
+			wantErr: true,
+		},
+		{
+			desc:    "missing column",
+			data:    "Symbol,Close\nAAPL,499.23\n",
+			wantErr: true,
+		},
+		{
+			desc:    "bad value",
+			data:    "Symbol,Price\nAAPL,n/a\n",
+			wantErr: true,
+		},
+		{
+			desc:    "short record",
+			data:    "Symbol,Price\nAAPL\n",
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			var (
+				got []row
+				r   row
+			)
+			columns := csvColumns{
+				"Symbol": func(v string) error { r.symbol = v; return nil },
+				"Price":  csvFloat(&r.price),
+			}
+			err := readCSV(strings.NewReader(tt.data), columns, func() { got = append(got, r) })
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("readCSV() error = %v, want error %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readCSV() records = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTradingSessionsCSVFixtures(t *testing.T) {
+	for _, tt := range []struct {
+		file       string
+		dateLayout string
+	}{
+		{"google_history.csv", "2-Jan-06"},
+		{"yahoo_history.csv", "2006-01-02"},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer f.Close()
+
+			tss, err := readTradingSessionsCSV(f, tt.dateLayout)
+			if err != nil {
+				t.Fatalf("readTradingSessionsCSV() error = %v", err)
+			}
+			if len(tss) != 5 {
+				t.Fatalf("readTradingSessionsCSV() read %d sessions, want 5", len(tss))
+			}
+
+			// The most recent session is at the front regardless of the file's order.
+			latest := tss[0]
+			if want := time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC); !latest.date.Equal(want) {
+				t.Errorf("latest session date = %v, want %v", latest.date, want)
+			}
+			if latest.close < 499.22 || latest.close > 499.24 {
+				t.Errorf("latest session close = %v, want 499.23", latest.close)
+			}
+			if latest.volume < 46907000 {
+				t.Errorf("latest session volume = %d, want about 46907479", latest.volume)
+			}
+			checkSessions(t, tss)
+		})
+	}
+}
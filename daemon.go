@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// daemon is a flag to run a headless daemon that fetches the data for attached clients.
+	daemon = flag.Bool("daemon", false, "Run a headless daemon that fetches the watchlist's data for clients started with -attach.")
+
+	// attach is a flag to get the data from the daemon instead of the data providers.
+	attach = flag.Bool("attach", false, "Get the watchlist's data from a daemon started with -daemon instead of fetching it.")
+)
+
+// minDaemonRefreshInterval is how long the daemon reuses its data when clients ask for a refresh,
+// so that many clients refreshing at once only fetch the data once.
+const minDaemonRefreshInterval = time.Minute
+
+// getDaemonSocketPath returns the path of the unix socket that the daemon listens on.
+func getDaemonSocketPath() (string, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dirPath, "ponzid.sock"), nil
+}
+
+// runDaemon refreshes the watchlist every hour and serves it to clients until the context is done.
+// Clients send "get" or "refresh" on a line and receive the data in the cache's JSON format.
+func runDaemon(ctx context.Context) error {
+	p, err := getDaemonSocketPath()
+	if err != nil {
+		return err
+	}
+
+	// Remove the socket left behind by a daemon that didn't shut down cleanly.
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	l, err := net.Listen("unix", p)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	// Only let the user connect, since the data has their watchlist and clients can make it refresh.
+	if err := os.Chmod(p, 0600); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	setAlpacaCredentials(cfg.AlpacaKeyID, cfg.AlpacaSecretKey, cfg.AlpacaURL)
+	setSymbolMappings(cfg.SymbolMappings)
+	sd := newStockData(cfg)
+	if err := loadCache(sd); err != nil {
+		log.Printf("loadCache: %v", err)
+	}
+	if err := loadHistory(sd); err != nil {
+		log.Printf("loadHistory: %v", err)
+	}
+
+	// refreshMutex makes concurrent refresh requests wait for the one in progress.
+	var refreshMutex sync.Mutex
+
+	// refresh fetches the data if the watchlist changed, it is forced, or the data is old.
+	refresh := func(force bool) {
+		refreshMutex.Lock()
+		defer refreshMutex.Unlock()
+
+		changed, err := syncStocks(sd)
+		if err != nil {
+			log.Printf("syncStocks: %v", err)
+		}
+
+		sd.RLock()
+		fresh := clk.now().Sub(sd.refreshTime) < minDaemonRefreshInterval
+		sd.RUnlock()
+		if fresh && !changed && !force {
+			return
+		}
+
+		refreshStockData(ctx, sd, "")
+
+		// Fetch the history for the performance periods here, since the clients don't.
+		backfillPeriods(ctx, sd)
+
+		if err := saveCache(sd); err != nil {
+			log.Printf("saveCache: %v", err)
+		}
+		if err := saveHistory(sd); err != nil {
+			log.Printf("saveHistory: %v", err)
+		}
+	}
+
+	// Refresh right away when asked from outside like with kill -USR1 from a cron job.
+	refreshSignals := make(chan os.Signal, 1)
+	notifyRefresh(refreshSignals)
+
+	go func() {
+		for {
+			refresh(true)
+			select {
+			case <-ctx.Done():
+				return
+			case <-clk.after(time.Hour):
+			case <-refreshSignals:
+			}
+		}
+	}()
+
+	// Stop accepting clients when the context is done.
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	log.Printf("daemon listening on %s", p)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go serveDaemonClient(conn, sd, refresh)
+	}
+}
+
+// serveDaemonClient handles a client's request and writes the data back.
+func serveDaemonClient(conn net.Conn, sd *stockData, refresh func(force bool)) {
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		log.Printf("ReadString: %v", err)
+		return
+	}
+
+	switch cmd := strings.TrimSpace(line); cmd {
+	case "get":
+	case "refresh":
+		refresh(false)
+	default:
+		log.Printf("unknown daemon command: %q", cmd)
+		return
+	}
+
+	// Send all the sessions, so the clients can show the longer charts without fetching them.
+	if err := json.NewEncoder(conn).Encode(newCache(sd, true)); err != nil {
+		log.Printf("Encode: %v", err)
+	}
+}
+
+// syncStocks updates the daemon's stocks to match the config, since clients add and remove
+// stocks by saving the config. It returns true if any stocks were added.
+func syncStocks(sd *stockData) (bool, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return false, err
+	}
+	stocks := newStockData(cfg).stocks
+
+	sd.Lock()
+	defer sd.Unlock()
+
+	old := map[string]stock{}
+	for _, s := range sd.stocks {
+		old[s.symbol] = s
+	}
+
+	var added bool
+	for i, s := range stocks {
+		o, ok := old[s.symbol]
+		if !ok {
+			added = true
+			continue
+		}
+		// Keep all the fetched data, so it isn't fetched or computed again. The rest is from the config.
+		stocks[i].tradingSessionMap = o.tradingSessionMap
+		stocks[i].fetchedDividends = o.fetchedDividends
+		stocks[i].historyStart = o.historyStart
+		stocks[i].historyEnd = o.historyEnd
+		stocks[i].updateTime = o.updateTime
+		stocks[i].cached = o.cached
+		stocks[i].pluginValues = o.pluginValues
+		stocks[i].filings = o.filings
+		stocks[i].analyst = o.analyst
+		stocks[i].fetchError = o.fetchError
+		stocks[i].volatility = o.volatility
+		stocks[i].splits = o.splits
+		stocks[i].checkedSplits = o.checkedSplits
+	}
+	sd.stocks = stocks
+	return added, nil
+}
+
+// refreshFromDaemon asks the daemon to refresh and updates the stocks with its data.
+func refreshFromDaemon(ctx context.Context, sd *stockData) error {
+	p, err := getDaemonSocketPath()
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", p)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Close the connection to stop waiting if the context is done.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if _, err := fmt.Fprintln(conn, "refresh"); err != nil {
+		return err
+	}
+
+	var c cache
+	if err := json.NewDecoder(conn).Decode(&c); err != nil {
+		return err
+	}
+
+	sd.Lock()
+	applyCache(sd, c, false)
+	sd.Unlock()
+	return nil
+}
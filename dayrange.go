@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"strings"
+)
+
+// dayRange is a flag to show a line under each cell with where the close is in the day's range.
+var dayRange = flag.Bool("day_range", false, "Show a bar under each cell marking where the close is between the day's low and high.")
+
+// withDayRange returns the live session with the open, high, and low of the fetched session
+// of the same day, widening the range if the live price is outside of it.
+func withDayRange(live, fetched stockTradingSession) stockTradingSession {
+	if fetched.high <= 0 || fetched.low <= 0 {
+		return live
+	}
+	live.open = fetched.open
+	live.high = math.Max(fetched.high, live.close)
+	live.low = math.Min(fetched.low, live.close)
+	return live
+}
+
+// dayRangeBar returns a bar like "├──●───┤" with the close marked between the day's low on the left
+// and high on the right. It returns an empty string if the session has no range.
+func dayRangeBar(ts stockTradingSession, width int) string {
+	if width < 3 || ts.high <= 0 || ts.low <= 0 || ts.high <= ts.low {
+		return ""
+	}
+
+	bar := []rune("├" + strings.Repeat("─", width-2) + "┤")
+	f := (ts.close - ts.low) / (ts.high - ts.low)
+	f = math.Max(0, math.Min(1, f))
+	bar[int(math.Round(f*float64(width-1)))] = '●'
+	return string(bar)
+}
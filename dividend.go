@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// dividend is a dividend paid per share of a stock.
+type dividend struct {
+	// date is the ex-dividend date.
+	date time.Time
+
+	// amount is the amount paid per share.
+	amount float64
+}
+
+func getDividendsFromYahoo(ctx context.Context, symbol string, startDate, endDate time.Time) ([]dividend, error) {
+	v := url.Values{}
+	v.Set("s", providerSymbol(yahoo, symbol))
+	v.Set("a", strconv.Itoa(int(startDate.Month())-1))
+	v.Set("b", strconv.Itoa(startDate.Day()))
+	v.Set("c", strconv.Itoa(startDate.Year()))
+	v.Set("d", strconv.Itoa(int(endDate.Month())-1))
+	v.Set("e", strconv.Itoa(endDate.Day()))
+	v.Set("f", strconv.Itoa(endDate.Year()))
+	v.Set("g", "v")
+	v.Set("ignore", ".csv")
+
+	u, err := url.Parse("http://ichart.yahoo.com/table.csv")
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = v.Encode()
+	resp, err := httpGet(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		ds []dividend
+		d  dividend
+	)
+	columns := csvColumns{
+		"Date":      csvDate(&d.date, "2006-01-02"),
+		"Dividends": csvFloat(&d.amount),
+	}
+	if err := readCSV(resp.Body, columns, func() { ds = append(ds, d) }); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// mergeDividends merges the fetched and manually entered dividends in chronological order.
+// Manually entered dividends replace fetched dividends with the same date.
+func mergeDividends(fetched, manual []dividend) []dividend {
+	dm := map[time.Time]dividend{}
+	for _, d := range fetched {
+		dm[d.date] = d
+	}
+	for _, d := range manual {
+		dm[d.date] = d
+	}
+
+	var ds []dividend
+	for _, d := range dm {
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(i, j int) bool {
+		return ds[i].date.Before(ds[j].date)
+	})
+	return ds
+}
+
+// trailingDividends returns the dividends per share with ex-dividend dates in the year before now.
+func trailingDividends(ds []dividend, now time.Time) float64 {
+	start := now.AddDate(-1, 0, 0)
+	var total float64
+	for _, d := range ds {
+		if d.date.After(start) && !d.date.After(now) {
+			total += d.amount
+		}
+	}
+	return total
+}
+
+// nextExDividendDate returns the next ex-dividend date after now from the chronological dividends.
+// If no future dividend is known, it is estimated from the interval between the last two dividends.
+func nextExDividendDate(ds []dividend, now time.Time) (date time.Time, estimated bool, ok bool) {
+	for _, d := range ds {
+		if d.date.After(now) {
+			return d.date, false, true
+		}
+	}
+
+	if len(ds) < 2 {
+		return time.Time{}, false, false
+	}
+
+	last, prev := ds[len(ds)-1].date, ds[len(ds)-2].date
+	interval := last.Sub(prev)
+	next := last.Add(interval)
+	for !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next, true, true
+}
+
+// refreshDividends fetches the past two years of dividends for the stocks with lots.
+func refreshDividends(ctx context.Context, sd *stockData) {
+	end := chartToday()
+	start := end.AddDate(-2, 0, 0)
+
+	sd.RLock()
+	var symbols []string
+	for _, s := range sd.stocks {
+		if len(s.lots) > 0 {
+			symbols = append(symbols, s.symbol)
+		}
+	}
+	sd.RUnlock()
+
+	dm := map[string][]dividend{}
+	for _, symbol := range symbols {
+		ds, err := getDividendsFromYahoo(ctx, symbol, start, end)
+		if err != nil {
+			log.Printf("getDividendsFromYahoo(%s): %v", symbol, err)
+			continue
+		}
+		dm[symbol] = ds
+	}
+
+	sd.Lock()
+	for i, s := range sd.stocks {
+		if ds, ok := dm[s.symbol]; ok {
+			sd.stocks[i].fetchedDividends = ds
+		}
+	}
+	sd.Unlock()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"sync"
+	"time"
+)
+
+// showEconEvents is a flag to mark the dates of the major scheduled economic events in the date header.
+var showEconEvents = flag.Bool("econ_events", false, "Mark the dates of FOMC decisions, jobs reports, and the config's Events in the date header.")
+
+// econEvent is a scheduled economic release or meeting that can move the whole market.
+type econEvent struct {
+	// name is a short name like "FOMC" shown in the footer.
+	name string
+
+	// description says what the event is.
+	description string
+}
+
+// fomcDecisionDates are the dates of the FOMC rate decisions announced at the end of each scheduled meeting.
+var fomcDecisionDates = []string{
+	"2024-01-31", "2024-03-20", "2024-05-01", "2024-06-12", "2024-07-31", "2024-09-18", "2024-11-07", "2024-12-18",
+	"2025-01-29", "2025-03-19", "2025-05-07", "2025-06-18", "2025-07-30", "2025-09-17", "2025-10-29", "2025-12-10",
+	"2026-01-28", "2026-03-18", "2026-04-29", "2026-06-17", "2026-07-29", "2026-09-16", "2026-10-28", "2026-12-09",
+}
+
+// configEconEvents are the events from the config like CPI releases, which have no fixed schedule.
+var configEconEvents = struct {
+	// Embedded mutex that guards the map.
+	sync.RWMutex
+
+	// m is a map from UTC midnight date to the events on that date.
+	m map[time.Time][]econEvent
+}{}
+
+// setEconEvents sets the events from the config.
+func setEconEvents(ces []configEconEvent) {
+	m := map[time.Time][]econEvent{}
+	for _, ce := range ces {
+		y, mo, d := ce.Date.Date()
+		date := time.Date(y, mo, d, 0, 0, 0, 0, time.UTC)
+		m[date] = append(m[date], econEvent{name: ce.Name, description: ce.Description})
+	}
+	configEconEvents.Lock()
+	configEconEvents.m = m
+	configEconEvents.Unlock()
+}
+
+// getEconEvents returns the economic events on the date if the econEvents flag is set.
+func getEconEvents(date time.Time) []econEvent {
+	if !*showEconEvents {
+		return nil
+	}
+
+	y, m, d := date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	var es []econEvent
+	for _, s := range fomcDecisionDates {
+		if fd, err := time.Parse("2006-01-02", s); err == nil && fd.Equal(day) {
+			es = append(es, econEvent{name: "FOMC", description: "Fed interest rate decision"})
+		}
+	}
+	if jobsReportDate(y, m).Equal(day) {
+		es = append(es, econEvent{name: "NFP", description: "Employment Situation report"})
+	}
+
+	configEconEvents.RLock()
+	es = append(es, configEconEvents.m[day]...)
+	configEconEvents.RUnlock()
+
+	return es
+}
+
+// jobsReportDate returns the usual date of the month's Employment Situation report as a UTC midnight date.
+// It is usually released on the first Friday or the trading day before it if that is a holiday.
+func jobsReportDate(year int, month time.Month) time.Time {
+	t := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	for t.Weekday() != time.Friday {
+		t = t.AddDate(0, 0, 1)
+	}
+	for !isTradingDay(t) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
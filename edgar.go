@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// checkFilings is a flag to check SEC EDGAR for the stocks' recent filings.
+	checkFilings = flag.Bool("edgar", false, "Check SEC EDGAR for the stocks' recent 8-K, 10-Q, 10-K, and Form 4 filings at each refresh. Requires -edgar_email.")
+
+	// edgarEmail is a flag with the user's contact email to send to the SEC with each request.
+	edgarEmail = flag.String("edgar_email", "", "Contact email sent to SEC EDGAR in the User-Agent, which its fair access policy requires.")
+)
+
+// edgarUserAgent returns the user agent identifying the app and the user to the SEC,
+// which rejects requests without a contact email.
+func edgarUserAgent(email string) (string, error) {
+	if email == "" {
+		return "", errors.New("-edgar needs a contact email in -edgar_email for the SEC's fair access policy")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" {
+		return "", fmt.Errorf("bad -edgar_email %q: want an address like name@example.com", email)
+	}
+	return "ponzi github.com/btmura/ponzi " + addr.Address, nil
+}
+
+// filingForms are the forms that are worth flagging.
+var filingForms = map[string]bool{
+	"8-K":  true,
+	"10-Q": true,
+	"10-K": true,
+	"4":    true,
+}
+
+// recentFilingAge is how old a filing can be to be flagged with a badge next to the symbol.
+const recentFilingAge = 7 * 24 * time.Hour
+
+// maxFilings is the number of the latest filings to keep for each stock.
+const maxFilings = 10
+
+// secFiling is a form filed with the SEC.
+type secFiling struct {
+	form string
+	date time.Time
+	url  string
+}
+
+// secCIKs is a map from ticker to the SEC's Central Index Key fetched once per session.
+var secCIKs = struct {
+	// Embedded mutex that guards the map.
+	sync.Mutex
+	m map[string]int
+}{}
+
+// getSECCIK returns the SEC's Central Index Key of the symbol.
+func getSECCIK(ctx context.Context, symbol string) (int, error) {
+	secCIKs.Lock()
+	defer secCIKs.Unlock()
+
+	if secCIKs.m == nil {
+		var parsed map[string]struct {
+			CIK    int `json:"cik_str"`
+			Ticker string
+		}
+		if err := getEDGARJSON(ctx, "https://www.sec.gov/files/company_tickers.json", &parsed); err != nil {
+			return 0, err
+		}
+		m := map[string]int{}
+		for _, p := range parsed {
+			m[p.Ticker] = p.CIK
+		}
+		secCIKs.m = m
+	}
+
+	// The SEC uses dashes for share classes like BRK-B.
+	cik, ok := secCIKs.m[strings.Replace(symbol, ".", "-", -1)]
+	if !ok {
+		return 0, fmt.Errorf("no CIK for %s", symbol)
+	}
+	return cik, nil
+}
+
+// getSECFilings returns the symbol's latest filings of the flagged forms from the most recent.
+func getSECFilings(ctx context.Context, symbol string) ([]secFiling, error) {
+	cik, err := getSECCIK(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Filings struct {
+			Recent struct {
+				AccessionNumber []string
+				FilingDate      []string
+				Form            []string
+				PrimaryDocument []string
+			}
+		}
+	}
+	if err := getEDGARJSON(ctx, fmt.Sprintf("https://data.sec.gov/submissions/CIK%010d.json", cik), &parsed); err != nil {
+		return nil, err
+	}
+
+	r := parsed.Filings.Recent
+	var fs []secFiling
+	for i, form := range r.Form {
+		if len(fs) >= maxFilings {
+			break
+		}
+		if !filingForms[form] || i >= len(r.FilingDate) || i >= len(r.AccessionNumber) || i >= len(r.PrimaryDocument) {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", r.FilingDate[i])
+		if err != nil {
+			return nil, fmt.Errorf("filing date: %v", err)
+		}
+		fs = append(fs, secFiling{
+			form: form,
+			date: date,
+			url: fmt.Sprintf("https://www.sec.gov/Archives/edgar/data/%d/%s/%s",
+				cik, strings.Replace(r.AccessionNumber[i], "-", "", -1), r.PrimaryDocument[i]),
+		})
+	}
+	return fs, nil
+}
+
+// getEDGARJSON gets the EDGAR URL and decodes its JSON into v.
+func getEDGARJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	ua, err := edgarUserAgent(*edgarEmail)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", ua)
+
+	resp, err := doHTTPRequest(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("%s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// refreshFilings gets the stocks' latest filings if the edgar flag is set.
+func refreshFilings(ctx context.Context, sd *stockData) {
+	if !*checkFilings || *offline {
+		return
+	}
+
+	sd.RLock()
+	var symbols []string
+	for _, s := range sd.stocks {
+		// Skip the indices, currencies, and futures, which have no filings.
+		if classifySymbol(s.symbol) == equityAsset {
+			symbols = append(symbols, s.symbol)
+		}
+	}
+	sd.RUnlock()
+
+	for _, symbol := range symbols {
+		fs, err := getSECFilings(ctx, symbol)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("getSECFilings(%s): %v", symbol, err)
+			continue
+		}
+
+		sd.Lock()
+		for i, s := range sd.stocks {
+			if s.symbol == symbol {
+				sd.stocks[i].filings = fs
+			}
+		}
+		sd.Unlock()
+	}
+}
+
+// hasRecentFiling returns whether any of the filings were filed recently.
+func hasRecentFiling(fs []secFiling, now time.Time) bool {
+	for _, f := range fs {
+		if now.Sub(f.date) < recentFilingAge {
+			return true
+		}
+	}
+	return false
+}
+
+// filingLines returns the lines of the filings popup for the stock.
+func filingLines(s stock) []string {
+	lines := []string{fmt.Sprintf("%s SEC Filings", s.symbol), ""}
+	for _, f := range s.filings {
+		lines = append(lines, fmt.Sprintf("%-5s %s  %s", f.form, f.date.Format("1/2/06"), f.url))
+	}
+	if len(s.filings) == 0 {
+		lines = append(lines, "None")
+	}
+	return append(lines, "", "Ctrl+F: Close")
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestEDGARUserAgent(t *testing.T) {
+	for _, tt := range []struct {
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{email: "jane@example.com", want: "ponzi github.com/btmura/ponzi jane@example.com"},
+		{email: " jane@example.com ", want: "ponzi github.com/btmura/ponzi jane@example.com"},
+		{email: "", wantErr: true},
+		{email: "jane", wantErr: true},
+		{email: "Jane <jane@example.com>", wantErr: true},
+	} {
+		got, err := edgarUserAgent(tt.email)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("edgarUserAgent(%q) error = %v, want error %t", tt.email, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("edgarUserAgent(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// exchangeDelays is a map from exchange to how long live quotes are delayed.
+// Exchanges not in the map have an unknown delay.
+var exchangeDelays = map[string]time.Duration{
+	// US exchanges and indices are real-time.
+	"NASDAQ":       0,
+	"NYSE":         0,
+	"NYSEARCA":     0,
+	"NYSEAMERICAN": 0,
+	"NYSEMKT":      0,
+	"BATS":         0,
+	"INDEXDJX":     0,
+	"INDEXSP":      0,
+	"INDEXNASDAQ":  0,
+
+	// OTC and international exchanges are delayed.
+	"OTCMKTS": 15 * time.Minute,
+	"TSE":     15 * time.Minute,
+	"CVE":     15 * time.Minute,
+	"LON":     20 * time.Minute,
+	"EPA":     15 * time.Minute,
+	"AMS":     15 * time.Minute,
+	"FRA":     15 * time.Minute,
+	"ETR":     15 * time.Minute,
+	"SWX":     15 * time.Minute,
+	"TYO":     20 * time.Minute,
+	"HKG":     15 * time.Minute,
+	"ASX":     20 * time.Minute,
+}
+
+// exchangeDelay returns how long quotes from the exchange are delayed and whether the delay is known.
+func exchangeDelay(exchange string) (time.Duration, bool) {
+	d, ok := exchangeDelays[exchange]
+	return d, ok
+}
+
+// exchangeDelayLabel returns a short label like "RT" or "15m" describing the exchange's delay.
+func exchangeDelayLabel(exchange string) string {
+	d, ok := exchangeDelay(exchange)
+	switch {
+	case !ok:
+		return "?"
+	case d == 0:
+		return "RT"
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
+
+// sortedExchanges returns the unique exchanges in the symbol to exchange map in sorted order.
+func sortedExchanges(exchanges map[string]string) []string {
+	m := map[string]bool{}
+	var es []string
+	for _, e := range exchanges {
+		if e != "" && !m[e] {
+			m[e] = true
+			es = append(es, e)
+		}
+	}
+	sort.Strings(es)
+	return es
+}
+
+// exchangeHours are the time zone and regular session hours of an exchange.
+type exchangeHours struct {
+	// loc is the exchange's time zone.
+	loc *time.Location
+
+	// open and close are the times since midnight that the regular session opens and closes.
+	open, close time.Duration
+}
+
+// internationalExchangeHours is a map from non-US exchange to its hours.
+// Exchanges not in the map are assumed to be US exchanges.
+var internationalExchangeHours = map[string]exchangeHours{
+	"TSE": {mustLoadLocation("America/Toronto"), 9*time.Hour + 30*time.Minute, 16 * time.Hour},
+	"CVE": {mustLoadLocation("America/Toronto"), 9*time.Hour + 30*time.Minute, 16 * time.Hour},
+	"LON": {mustLoadLocation("Europe/London"), 8 * time.Hour, 16*time.Hour + 30*time.Minute},
+	"EPA": {mustLoadLocation("Europe/Paris"), 9 * time.Hour, 17*time.Hour + 30*time.Minute},
+	"AMS": {mustLoadLocation("Europe/Amsterdam"), 9 * time.Hour, 17*time.Hour + 30*time.Minute},
+	"FRA": {mustLoadLocation("Europe/Berlin"), 8 * time.Hour, 20 * time.Hour},
+	"ETR": {mustLoadLocation("Europe/Berlin"), 9 * time.Hour, 17*time.Hour + 30*time.Minute},
+	"SWX": {mustLoadLocation("Europe/Zurich"), 9 * time.Hour, 17*time.Hour + 30*time.Minute},
+	"TYO": {mustLoadLocation("Asia/Tokyo"), 9 * time.Hour, 15 * time.Hour},
+	"HKG": {mustLoadLocation("Asia/Hong_Kong"), 9*time.Hour + 30*time.Minute, 16 * time.Hour},
+	"ASX": {mustLoadLocation("Australia/Sydney"), 10 * time.Hour, 16 * time.Hour},
+}
+
+// isUSExchange returns whether the exchange follows New York hours and the NYSE holidays.
+func isUSExchange(exchange string) bool {
+	_, ok := internationalExchangeHours[exchange]
+	return !ok
+}
+
+// getExchangeHours returns the hours of the exchange or the New York hours for US exchanges.
+func getExchangeHours(exchange string) exchangeHours {
+	if eh, ok := internationalExchangeHours[exchange]; ok {
+		return eh
+	}
+	return exchangeHours{newYorkLoc, 9*time.Hour + 30*time.Minute, 16 * time.Hour}
+}
+
+// isExchangeOpen returns whether the exchange's regular session is open at the time.
+// Holidays are only known for US exchanges.
+func isExchangeOpen(exchange string, t time.Time) bool {
+	if isUSExchange(exchange) {
+		return isMarketHours(t)
+	}
+
+	eh := getExchangeHours(exchange)
+	lt := t.In(eh.loc)
+	switch lt.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	sinceMidnight := lt.Sub(midnight(lt))
+	return sinceMidnight >= eh.open && sinceMidnight < eh.close
+}
+
+// exchangeDate returns the date at the exchange of the time as UTC midnight like trading session dates.
+func exchangeDate(exchange string, t time.Time) time.Time {
+	lt := t.In(getExchangeHours(exchange).loc)
+	return time.Date(lt.Year(), lt.Month(), lt.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExchangeDate(t *testing.T) {
+	for _, tt := range []struct {
+		desc     string
+		exchange string
+		t        time.Time
+		want     time.Time
+	}{
+		{
+			desc:     "close before the spring forward",
+			exchange: "NASDAQ",
+			t:        time.Date(2020, 3, 6, 21, 0, 0, 0, time.UTC),
+			want:     time.Date(2020, 3, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "after-hours quote after the spring forward",
+			exchange: "NASDAQ",
+			t:        time.Date(2020, 3, 9, 23, 59, 0, 0, time.UTC),
+			want:     time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "after-hours quote before the fall back crosses UTC midnight",
+			exchange: "NYSE",
+			t:        time.Date(2020, 10, 31, 0, 30, 0, 0, time.UTC),
+			want:     time.Date(2020, 10, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "after-hours quote after the fall back crosses UTC midnight",
+			exchange: "NYSE",
+			t:        time.Date(2020, 11, 3, 0, 30, 0, 0, time.UTC),
+			want:     time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "tokyo afternoon quote",
+			exchange: "TYO",
+			t:        time.Date(2020, 8, 28, 5, 59, 0, 0, time.UTC),
+			want:     time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "tokyo morning quote is the previous day in UTC",
+			exchange: "TYO",
+			t:        time.Date(2020, 8, 27, 23, 30, 0, 0, time.UTC),
+			want:     time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "unknown exchange uses new york",
+			exchange: "",
+			t:        time.Date(2020, 11, 3, 1, 0, 0, 0, time.UTC),
+			want:     time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := exchangeDate(tt.exchange, tt.t); !got.Equal(tt.want) {
+				t.Errorf("exchangeDate(%q, %v) = %v, want %v", tt.exchange, tt.t, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+import "strings"
+
+// matchesFilter returns whether the stock is shown by the watchlist filter.
+// Stocks only have tickers, so the filter matches any part of the symbol.
+// An empty filter matches every stock.
+func matchesFilter(s stock, filter string) bool {
+	return strings.Contains(s.symbol, strings.ToUpper(filter))
+}
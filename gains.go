@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// sale is a sale of shares from a single lot.
+type sale struct {
+	// symbol is the symbol of the stock sold.
+	symbol string
+
+	// date is when the shares were sold.
+	date time.Time
+
+	// acquired is when the lot was bought or zero if unknown.
+	acquired time.Time
+
+	// quantity is the number of shares sold.
+	quantity float64
+
+	// proceeds is the total amount received for the shares.
+	proceeds float64
+
+	// cost is the cost basis of the shares.
+	cost float64
+}
+
+// newSale returns the sale saved in the config.
+func newSale(cs configSale) sale {
+	return sale{
+		symbol:   cs.Symbol,
+		date:     cs.Date,
+		acquired: cs.Acquired,
+		quantity: cs.Quantity,
+		proceeds: cs.Proceeds,
+		cost:     cs.Cost,
+	}
+}
+
+// gain returns the realized gain or loss of the sale.
+func (s sale) gain() float64 {
+	return s.proceeds - s.cost
+}
+
+// longTerm returns true if the shares were held for more than a year.
+func (s sale) longTerm() bool {
+	return !s.acquired.IsZero() && s.date.After(s.acquired.AddDate(1, 0, 0))
+}
+
+// transaction is a buy or sell of a stock entered by the user.
+type transaction struct {
+	side     tradeSide
+	quantity float64
+	price    float64
+	date     time.Time
+
+	// lotIndex is the index of the specific lot to sell from or -1 to sell first in first out.
+	lotIndex int
+}
+
+// parseTransaction parses input like "buy 10 182.50 2016-10-03" or "sell 5 190 2016-11-01 2".
+// The date defaults to today, and the optional 1-based lot number selects a specific lot to sell.
+func parseTransaction(input string) (transaction, error) {
+	fields := strings.Fields(strings.ToLower(input))
+	if len(fields) < 3 || len(fields) > 5 {
+		return transaction{}, errors.New("expected: buy|sell QTY PRICE [YYYY-MM-DD] [LOT]")
+	}
+
+	t := transaction{lotIndex: -1, date: chartToday()}
+
+	switch tradeSide(fields[0]) {
+	case buy, sell:
+		t.side = tradeSide(fields[0])
+	default:
+		return transaction{}, fmt.Errorf("bad side: %q", fields[0])
+	}
+
+	var err error
+	t.quantity, err = parseFloat(fields[1])
+	if err != nil || t.quantity <= 0 {
+		return transaction{}, fmt.Errorf("bad quantity: %q", fields[1])
+	}
+
+	t.price, err = parseFloat(fields[2])
+	if err != nil || t.price < 0 {
+		return transaction{}, fmt.Errorf("bad price: %q", fields[2])
+	}
+
+	if len(fields) > 3 {
+		t.date, err = time.Parse("2006-01-02", fields[3])
+		if err != nil {
+			return transaction{}, fmt.Errorf("bad date: %q", fields[3])
+		}
+	}
+
+	if len(fields) > 4 {
+		if t.side != sell {
+			return transaction{}, errors.New("lot number is only for sells")
+		}
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n <= 0 {
+			return transaction{}, fmt.Errorf("bad lot number: %q", fields[4])
+		}
+		t.lotIndex = n - 1
+	}
+
+	return t, nil
+}
+
+// applyTransaction returns the lots after the transaction and any sales it realized.
+// Buys add a new lot in date order. Sells take shares from a specific lot or from the oldest lots first.
+func applyTransaction(lots []lot, t transaction) ([]lot, []sale, error) {
+	// Copy the lots to leave the originals untouched on error.
+	lots = append([]lot(nil), lots...)
+
+	if t.side == buy {
+		lots = append(lots, lot{
+			date:     t.date,
+			quantity: t.quantity,
+			cost:     t.quantity * t.price,
+		})
+		sort.SliceStable(lots, func(i, j int) bool {
+			return lots[i].date.Before(lots[j].date)
+		})
+		return lots, nil, nil
+	}
+
+	order := make([]int, len(lots))
+	for i := range lots {
+		order[i] = i
+	}
+	if t.lotIndex >= 0 {
+		if t.lotIndex >= len(lots) {
+			return nil, nil, fmt.Errorf("no lot %d", t.lotIndex+1)
+		}
+		order = []int{t.lotIndex}
+	}
+
+	var available float64
+	for _, i := range order {
+		available += lots[i].quantity
+	}
+	if t.quantity > available {
+		return nil, nil, fmt.Errorf("selling %g shares but only %g available", t.quantity, available)
+	}
+
+	var sales []sale
+	remaining := t.quantity
+	for _, i := range order {
+		if remaining <= 0 {
+			break
+		}
+
+		q := lots[i].quantity
+		if q > remaining {
+			q = remaining
+		}
+
+		cost := lots[i].cost * q / lots[i].quantity
+		sales = append(sales, sale{
+			date:     t.date,
+			acquired: lots[i].date,
+			quantity: q,
+			proceeds: q * t.price,
+			cost:     cost,
+		})
+
+		lots[i].quantity -= q
+		lots[i].cost -= cost
+		remaining -= q
+	}
+
+	// Remove the lots that were sold entirely.
+	var open []lot
+	for _, l := range lots {
+		if l.quantity > 0 {
+			open = append(open, l)
+		}
+	}
+	return open, sales, nil
+}
+
+// yearGains are the realized gains of a tax year.
+type yearGains struct {
+	year      int
+	shortTerm float64
+	longTerm  float64
+}
+
+// realizedGainsByYear returns the realized gains of the sales for each year with the most recent first.
+func realizedGainsByYear(sales []sale) []yearGains {
+	ym := map[int]*yearGains{}
+	for _, sl := range sales {
+		y := sl.date.Year()
+		if ym[y] == nil {
+			ym[y] = &yearGains{year: y}
+		}
+		if sl.longTerm() {
+			ym[y].longTerm += sl.gain()
+		} else {
+			ym[y].shortTerm += sl.gain()
+		}
+	}
+
+	var ygs []yearGains
+	for _, yg := range ym {
+		ygs = append(ygs, *yg)
+	}
+	sort.Slice(ygs, func(i, j int) bool {
+		return ygs[i].year > ygs[j].year
+	})
+	return ygs
+}
+
+// writeRealizedGainsCSV writes the sales of the year or all years if the year is zero as CSV.
+func writeRealizedGainsCSV(w io.Writer, sales []sale, year int) error {
+	formatDate := func(t time.Time) string {
+		if t.IsZero() {
+			return "VARIOUS"
+		}
+		return t.Format("01/02/2006")
+	}
+
+	formatMoney := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Symbol", "Quantity", "Date Acquired", "Date Sold", "Proceeds", "Cost Basis", "Gain", "Term"}); err != nil {
+		return err
+	}
+
+	for _, sl := range sales {
+		if year != 0 && sl.date.Year() != year {
+			continue
+		}
+
+		term := "Short"
+		if sl.longTerm() {
+			term = "Long"
+		}
+
+		if err := cw.Write([]string{
+			sl.symbol,
+			strconv.FormatFloat(sl.quantity, 'f', -1, 64),
+			formatDate(sl.acquired),
+			formatDate(sl.date),
+			formatMoney(sl.proceeds),
+			formatMoney(sl.cost),
+			formatMoney(sl.gain()),
+			term,
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// printGains prints the realized gains per year and the open lots of the selected stock.
+// The caller must hold the stockData read lock.
+func printGains(sd *stockData, selected stock, w, h int) {
+	print := func(x, y int, fg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, termbox.ColorDefault)
+			x++
+		}
+		return x
+	}
+
+	print(0, 0, termbox.ColorDefault, " Realized Gains")
+	print(0, 2, termbox.ColorDefault, " %-6s %12s %12s %12s", "Year", "Short Term", "Long Term", "Total")
+
+	y := 3
+	for _, yg := range realizedGainsByYear(sd.sales) {
+		if y >= h/2 {
+			break
+		}
+		x := print(0, y, termbox.ColorDefault, " %-6d", yg.year)
+		x = print(x, y, changeFgColor(yg.shortTerm), " %12.2f", yg.shortTerm)
+		x = print(x, y, changeFgColor(yg.longTerm), " %12.2f", yg.longTerm)
+		print(x, y, changeFgColor(yg.shortTerm+yg.longTerm), " %12.2f", yg.shortTerm+yg.longTerm)
+		y++
+	}
+
+	y++
+	print(0, y, termbox.ColorDefault, " %s Open Lots", selected.symbol)
+	y++
+	print(0, y, termbox.ColorDefault, " %-4s %-10s %12s %12s", "Lot", "Date", "Qty", "Cost")
+	y++
+	for i, l := range selected.lots {
+		if y >= h-2 {
+			break
+		}
+		date := "-"
+		if !l.date.IsZero() {
+			date = l.date.Format("2006-01-02")
+		}
+		print(0, y, termbox.ColorDefault, " %-4d %-10s %12g %12.2f", i+1, date, l.quantity, l.cost)
+		y++
+	}
+
+	print(0, h-1, termbox.ColorDefault, " Ctrl+T:Transaction  Esc:Back")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewStockDataKeepsSalesApart(t *testing.T) {
+	day := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC) }
+
+	// An older config with sales saved on a stock and a newer one with the sales apart.
+	cfg := config{
+		Stocks: []configStock{
+			{
+				Symbol: "AAPL",
+				Sales:  []configSale{{Date: day(2020, 8, 28), Acquired: day(2019, 1, 2), Quantity: 10, Proceeds: 5000, Cost: 1500}},
+			},
+		},
+		Sales: []configSale{
+			{Symbol: "TSLA", Date: day(2020, 6, 1), Acquired: day(2020, 1, 2), Quantity: 1, Proceeds: 900, Cost: 400},
+		},
+	}
+
+	sd := newStockData(cfg)
+	want := []sale{
+		{symbol: "TSLA", date: day(2020, 6, 1), acquired: day(2020, 1, 2), quantity: 1, proceeds: 900, cost: 400},
+		{symbol: "AAPL", date: day(2020, 8, 28), acquired: day(2019, 1, 2), quantity: 10, proceeds: 5000, cost: 1500},
+	}
+	if !reflect.DeepEqual(sd.sales, want) {
+		t.Fatalf("sales = %+v, want %+v", sd.sales, want)
+	}
+
+	// Deleting the stocks from the watchlist keeps the tax history.
+	sd.stocks = nil
+	got := realizedGainsByYear(sd.sales)
+	if want := []yearGains{{year: 2020, shortTerm: 500, longTerm: 3500}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("realizedGainsByYear() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteRealizedGainsCSV(t *testing.T) {
+	day := func(y, m, d int) time.Time { return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC) }
+	sales := []sale{
+		{symbol: "AAPL", date: day(2020, 8, 28), acquired: day(2019, 1, 2), quantity: 10, proceeds: 5000, cost: 1500},
+		{symbol: "TSLA", date: day(2019, 6, 1), quantity: 1.5, proceeds: 900, cost: 1000},
+	}
+
+	for _, tt := range []struct {
+		desc string
+		year int
+		want string
+	}{
+		{
+			desc: "all years",
+			want: "Symbol,Quantity,Date Acquired,Date Sold,Proceeds,Cost Basis,Gain,Term\n" +
+				"AAPL,10,01/02/2019,08/28/2020,5000.00,1500.00,3500.00,Long\n" +
+				"TSLA,1.5,VARIOUS,06/01/2019,900.00,1000.00,-100.00,Short\n",
+		},
+		{
+			desc: "one year",
+			year: 2019,
+			want: "Symbol,Quantity,Date Acquired,Date Sold,Proceeds,Cost Basis,Gain,Term\n" +
+				"TSLA,1.5,VARIOUS,06/01/2019,900.00,1000.00,-100.00,Short\n",
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeRealizedGainsCSV(&buf, sales, tt.year); err != nil {
+				t.Fatalf("writeRealizedGainsCSV() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeRealizedGainsCSV() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
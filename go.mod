@@ -0,0 +1,20 @@
+module github.com/btmura/ponzi
+
+go 1.21
+
+require (
+	github.com/atotto/clipboard v0.1.4
+	github.com/gdamore/tcell/v2 v2.7.4
+	github.com/nsf/termbox-go v1.1.1
+	go.starlark.net v0.0.0-20240925182052-1207426daebd
+)
+
+require (
+	github.com/gdamore/encoding v1.0.0 // indirect
+	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
+	github.com/mattn/go-runewidth v0.0.15 // indirect
+	github.com/rivo/uniseg v0.4.3 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	golang.org/x/term v0.17.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// importHistoryPath is a flag to import downloaded CSV files into the history database.
+var importHistoryPath = flag.String("import_history", "", "Path of a Yahoo, Google, or stooq CSV file or a directory of them to import into the history database before exiting.")
+
+// importHistory imports the CSV file or the CSV files under the directory into the history
+// database. The symbol is taken from the file name like AAPL.csv or aapl.us.txt or from stooq's
+// ticker column. It returns the number of files imported.
+func importHistory(p string) (int, error) {
+	var files []string
+	if err := filepath.Walk(p, func(fp string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			files = append(files, fp)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, fp := range files {
+		m, err := readHistoryCSV(fp)
+		if err != nil {
+			// Skip files like READMEs that the bulk downloads come with.
+			log.Printf("readHistoryCSV(%s): %v", fp, err)
+			continue
+		}
+		for symbol, tss := range m {
+			if err := mergeHistory(symbol, tss); err != nil {
+				return n, err
+			}
+			log.Printf("imported %d sessions of %s from %s", len(tss), symbol, fp)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// readHistoryCSV reads the trading sessions of a downloaded CSV file and returns a map from
+// symbol to its sessions with the most recent at the front.
+func readHistoryCSV(fp string) (map[string][]tradingSession, error) {
+	data, err := ioutil.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stooq's bulk files have a ticker column and bracketed column names.
+	if bytes.HasPrefix(data, []byte("<TICKER>")) {
+		return readStooqCSV(bytes.NewReader(data))
+	}
+
+	tss, err := readTradingSessionsCSV(bytes.NewReader(data), historyCSVDateLayout(data))
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]tradingSession{historyCSVSymbol(fp): tss}, nil
+}
+
+// historyCSVDateLayout returns the layout of the dates in the first column of the second line.
+// Google's dates are like 2-Jan-06 while Yahoo's and stooq's daily downloads are like 2006-01-02.
+func historyCSVDateLayout(data []byte) string {
+	const yahooLayout, googleLayout = "2006-01-02", "2-Jan-06"
+	lines := strings.SplitN(string(data), "\n", 3)
+	if len(lines) < 2 {
+		return yahooLayout
+	}
+	date := strings.TrimSpace(strings.SplitN(lines[1], ",", 2)[0])
+	if _, err := time.Parse(googleLayout, date); err == nil {
+		return googleLayout
+	}
+	return yahooLayout
+}
+
+// readStooqCSV reads stooq's bulk format that has the sessions of one or more tickers like AAPL.US.
+func readStooqCSV(r io.Reader) (map[string][]tradingSession, error) {
+	var (
+		m      = map[string][]tradingSession{}
+		ticker string
+		ts     tradingSession
+	)
+	columns := csvColumns{
+		"<TICKER>": func(value string) error {
+			ticker = stooqSymbol(value)
+			return nil
+		},
+		"<DATE>":  csvDate(&ts.date, "20060102"),
+		"<OPEN>":  csvFloat(&ts.open),
+		"<HIGH>":  csvFloat(&ts.high),
+		"<LOW>":   csvFloat(&ts.low),
+		"<CLOSE>": csvFloat(&ts.close),
+		"<VOL>":   csvFloatInt(&ts.volume),
+	}
+	if err := readCSV(r, columns, func() { m[ticker] = append(m[ticker], ts) }); err != nil {
+		return nil, err
+	}
+
+	// Most recent trading sessions at the front.
+	for ticker, tss := range m {
+		sort.Sort(sort.Reverse(sortableTradingSessions(tss)))
+		m[ticker] = dropAnomalousSessions(tss)
+	}
+	return m, nil
+}
+
+// csvFloatInt returns a column function that parses integers written as floats like 1.5e+07 into the field.
+func csvFloatInt(field *int64) func(string) error {
+	return func(value string) error {
+		f, err := parseFloat(value)
+		*field = int64(f)
+		return err
+	}
+}
+
+// historyCSVSymbol returns the symbol of a file named like AAPL.csv or aapl.us.txt.
+func historyCSVSymbol(fp string) string {
+	name := path.Base(filepath.ToSlash(fp))
+	return stooqSymbol(strings.TrimSuffix(name, path.Ext(name)))
+}
+
+// stooqSymbol returns the symbol of a stooq ticker like AAPL.US by removing its US market suffix.
+func stooqSymbol(ticker string) string {
+	return strings.TrimSuffix(strings.ToUpper(ticker), ".US")
+}
+
+// mergeHistory adds the sessions to the symbol's history file without replacing any it has.
+func mergeHistory(symbol string, tss []tradingSession) error {
+	if len(tss) == 0 {
+		return nil
+	}
+
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	dirPath, err := getHistoryDir()
+	if err != nil {
+		return err
+	}
+	p := path.Join(dirPath, historyFileName(symbol))
+
+	s := stock{
+		symbol:            symbol,
+		tradingSessionMap: map[time.Time]stockTradingSession{},
+	}
+	hf, err := readHistoryFile(p)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		for _, ts := range hf.sessions() {
+			s.tradingSessionMap[ts.date] = ts
+		}
+		s.historyStart, s.historyEnd = hf.HistoryStart, hf.HistoryEnd
+		s.splits = hf.stockSplits()
+	}
+
+	for _, ts := range convertTradingSessions(adjustForSplits(tss, s.splits)) {
+		if _, ok := s.tradingSessionMap[ts.date]; !ok {
+			s.tradingSessionMap[ts.date] = ts
+		}
+	}
+
+	// Extend the range considered fetched only if the import leaves no gap before it.
+	first, last := tss[len(tss)-1].date, tss[0].date
+	if s.historyStart.IsZero() {
+		s.historyStart, s.historyEnd = first, last
+	} else if first.Before(s.historyStart) && !last.Before(s.historyStart) {
+		s.historyStart = first
+	}
+
+	// Splits in the imported range are confirmed against the provider's split events on the next refresh.
+	return writeHistoryFile(p, newHistoryFile(s, time.Time{}))
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pruneHistory is a flag to apply the retention policy to the history database and exit.
+var pruneHistory = flag.Bool("prune_history", false, "Remove the saved sessions older than the config's HistoryRetentionDays and of the symbols no longer in the watchlist before exiting.")
+
+// historyFile is a stock's trading sessions saved in the history database. The sessions are
+// stored by column from oldest to newest, which gob encodes more compactly than a slice of structs.
+// Capitalized for gob encoding.
+type historyFile struct {
+	Symbol        string
+	HistoryStart  time.Time
+	HistoryEnd    time.Time
+	Dates         []time.Time
+	Opens         []float64
+	Highs         []float64
+	Lows          []float64
+	Closes        []float64
+	Volumes       []int64
+	Changes       []float64
+	PercentChange []float64
+	SplitDates    []time.Time
+	SplitRatios   []float64
+}
+
+// sessions returns the file's sessions from oldest to newest.
+func (hf historyFile) sessions() []stockTradingSession {
+	var sts []stockTradingSession
+	for i, date := range hf.Dates {
+		sts = append(sts, stockTradingSession{
+			date:          date,
+			open:          hf.Opens[i],
+			high:          hf.Highs[i],
+			low:           hf.Lows[i],
+			close:         hf.Closes[i],
+			volume:        hf.Volumes[i],
+			change:        hf.Changes[i],
+			percentChange: hf.PercentChange[i],
+		})
+	}
+	return sts
+}
+
+// stockSplits returns the file's splits.
+func (hf historyFile) stockSplits() []stockSplit {
+	var splits []stockSplit
+	for i, date := range hf.SplitDates {
+		splits = append(splits, stockSplit{date: date, ratio: hf.SplitRatios[i]})
+	}
+	return splits
+}
+
+// historyMutex prevents history file reads and writes from conflicting.
+var historyMutex sync.Mutex
+
+// loadHistory loads the saved trading sessions into the stocks, so charts of the ranges fetched
+// before are drawn without downloading them again. Sessions the stocks already have are kept.
+func loadHistory(sd *stockData) error {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	dirPath, err := getHistoryDir()
+	if err != nil {
+		return err
+	}
+
+	sd.RLock()
+	var symbols []string
+	for _, s := range sd.stocks {
+		symbols = append(symbols, s.symbol)
+	}
+	sd.RUnlock()
+
+	hfs := map[string]historyFile{}
+	for _, symbol := range symbols {
+		hf, err := readHistoryFile(path.Join(dirPath, historyFileName(symbol)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Printf("readHistoryFile(%s): %v", symbol, err)
+			continue
+		}
+		hfs[symbol] = hf
+	}
+
+	sd.Lock()
+	defer sd.Unlock()
+	for i, s := range sd.stocks {
+		hf, ok := hfs[s.symbol]
+		if !ok {
+			continue
+		}
+		if sd.stocks[i].tradingSessionMap == nil {
+			sd.stocks[i].tradingSessionMap = map[time.Time]stockTradingSession{}
+		}
+		for _, ts := range hf.sessions() {
+			if _, ok := sd.stocks[i].tradingSessionMap[ts.date]; !ok {
+				sd.stocks[i].tradingSessionMap[ts.date] = ts
+			}
+		}
+		sd.stocks[i].volatility = volatility(sd.stocks[i].tradingSessionMap)
+		if s.historyStart.IsZero() || hf.HistoryStart.Before(s.historyStart) {
+			sd.stocks[i].historyStart = hf.HistoryStart
+		}
+		if hf.HistoryEnd.After(s.historyEnd) {
+			sd.stocks[i].historyEnd = hf.HistoryEnd
+		}
+		if len(s.splits) == 0 {
+			sd.stocks[i].splits = hf.stockSplits()
+		}
+	}
+	return nil
+}
+
+// saveHistory saves the stocks' trading sessions to the history database, dropping the ones
+// older than the retention policy.
+func saveHistory(sd *stockData) error {
+	sd.RLock()
+	cutoff := historyCutoff(sd.historyRetentionDays, clk.now())
+	var hfs []historyFile
+	for _, s := range sd.stocks {
+		if len(s.tradingSessionMap) == 0 {
+			continue
+		}
+		hfs = append(hfs, newHistoryFile(s, cutoff))
+	}
+	sd.RUnlock()
+
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	dirPath, err := getHistoryDir()
+	if err != nil {
+		return err
+	}
+	for _, hf := range hfs {
+		if err := writeHistoryFile(path.Join(dirPath, historyFileName(hf.Symbol)), hf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneHistoryFiles applies the retention policy to the saved sessions and removes the
+// files of the symbols that are no longer in the watchlist.
+func pruneHistoryFiles(sd *stockData) error {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	dirPath, err := getHistoryDir()
+	if err != nil {
+		return err
+	}
+
+	fis, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return err
+	}
+
+	sd.RLock()
+	cutoff := historyCutoff(sd.historyRetentionDays, clk.now())
+	watched := map[string]bool{}
+	for _, s := range sd.stocks {
+		watched[historyFileName(s.symbol)] = true
+	}
+	sd.RUnlock()
+
+	for _, fi := range fis {
+		p := path.Join(dirPath, fi.Name())
+		if !watched[fi.Name()] {
+			log.Printf("prune: removing %s", fi.Name())
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if cutoff.IsZero() {
+			continue
+		}
+
+		hf, err := readHistoryFile(p)
+		if err != nil {
+			return err
+		}
+		n := len(hf.Dates)
+		hf = pruneHistoryFile(hf, cutoff)
+		if len(hf.Dates) == n {
+			continue
+		}
+		log.Printf("prune: removing %d sessions from %s", n-len(hf.Dates), fi.Name())
+		if err := writeHistoryFile(p, hf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newHistoryFile returns the stock's sessions on or after the cutoff as a historyFile.
+func newHistoryFile(s stock, cutoff time.Time) historyFile {
+	var dates []time.Time
+	for date := range s.tradingSessionMap {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	hf := historyFile{
+		Symbol:       s.symbol,
+		HistoryStart: s.historyStart,
+		HistoryEnd:   s.historyEnd,
+	}
+	for _, sp := range s.splits {
+		hf.SplitDates = append(hf.SplitDates, sp.date)
+		hf.SplitRatios = append(hf.SplitRatios, sp.ratio)
+	}
+	for _, date := range dates {
+		ts := s.tradingSessionMap[date]
+		hf.Dates = append(hf.Dates, date)
+		hf.Opens = append(hf.Opens, ts.open)
+		hf.Highs = append(hf.Highs, ts.high)
+		hf.Lows = append(hf.Lows, ts.low)
+		hf.Closes = append(hf.Closes, ts.close)
+		hf.Volumes = append(hf.Volumes, ts.volume)
+		hf.Changes = append(hf.Changes, ts.change)
+		hf.PercentChange = append(hf.PercentChange, ts.percentChange)
+	}
+	return pruneHistoryFile(hf, cutoff)
+}
+
+// pruneHistoryFile drops the sessions before the cutoff, which is zero to keep everything.
+func pruneHistoryFile(hf historyFile, cutoff time.Time) historyFile {
+	if cutoff.IsZero() {
+		return hf
+	}
+
+	i := sort.Search(len(hf.Dates), func(i int) bool { return !hf.Dates[i].Before(cutoff) })
+	hf.Dates = hf.Dates[i:]
+	hf.Opens = hf.Opens[i:]
+	hf.Highs = hf.Highs[i:]
+	hf.Lows = hf.Lows[i:]
+	hf.Closes = hf.Closes[i:]
+	hf.Volumes = hf.Volumes[i:]
+	hf.Changes = hf.Changes[i:]
+	hf.PercentChange = hf.PercentChange[i:]
+
+	// Fetch the dropped range again if a longer chart is shown.
+	if hf.HistoryStart.Before(cutoff) {
+		hf.HistoryStart = cutoff
+	}
+	return hf
+}
+
+// historyCutoff returns the date before which sessions are dropped or zero if they are kept forever.
+func historyCutoff(retentionDays int, now time.Time) time.Time {
+	if retentionDays <= 0 {
+		return time.Time{}
+	}
+	return midnight(now.In(newYorkLoc)).AddDate(0, 0, -retentionDays)
+}
+
+func readHistoryFile(p string) (historyFile, error) {
+	var hf historyFile
+
+	file, err := os.Open(p)
+	if err != nil {
+		return hf, err
+	}
+	defer file.Close()
+
+	if err := gob.NewDecoder(file).Decode(&hf); err != nil {
+		return hf, err
+	}
+
+	// Files saved before the day's range was kept have no opens, highs, and lows.
+	n := len(hf.Dates)
+	if len(hf.Opens) == 0 && len(hf.Highs) == 0 && len(hf.Lows) == 0 {
+		hf.Opens, hf.Highs, hf.Lows = make([]float64, n), make([]float64, n), make([]float64, n)
+	}
+
+	// Reject files with missing values, since the sessions are read by index from each column.
+	for _, c := range []struct {
+		name string
+		len  int
+	}{
+		{"Opens", len(hf.Opens)},
+		{"Highs", len(hf.Highs)},
+		{"Lows", len(hf.Lows)},
+		{"Closes", len(hf.Closes)},
+		{"Volumes", len(hf.Volumes)},
+		{"Changes", len(hf.Changes)},
+		{"PercentChange", len(hf.PercentChange)},
+	} {
+		if c.len != n {
+			return historyFile{}, fmt.Errorf("%s has %d values, want %d like Dates", c.name, c.len, n)
+		}
+	}
+	if len(hf.SplitRatios) != len(hf.SplitDates) {
+		return historyFile{}, fmt.Errorf("SplitRatios has %d values, want %d like SplitDates", len(hf.SplitRatios), len(hf.SplitDates))
+	}
+	return hf, nil
+}
+
+// writeHistoryFile writes to a temporary file and renames it, so a crash doesn't leave a partial file.
+func writeHistoryFile(p string, hf historyFile) error {
+	tmp := p + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(hf); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// historyFileName returns the name of the symbol's file, escaping characters like "/" in the symbol.
+func historyFileName(symbol string) string {
+	return url.PathEscape(strings.ToUpper(symbol)) + ".gob"
+}
+
+func getHistoryDir() (string, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	p := path.Join(dirPath, "history")
+	if err := os.MkdirAll(p, 0755); err != nil {
+		return "", err
+	}
+	return p, nil
+}
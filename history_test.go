@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadHistoryFile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 8, d, 0, 0, 0, 0, time.UTC) }
+	valid := func() historyFile {
+		return historyFile{
+			Symbol:        "AAPL",
+			Dates:         []time.Time{day(27), day(28)},
+			Opens:         []float64{505, 500},
+			Highs:         []float64{507, 503},
+			Lows:          []float64{499, 498},
+			Closes:        []float64{500.04, 499.23},
+			Volumes:       []int64{38888096, 46907479},
+			Changes:       []float64{-6.05, -0.81},
+			PercentChange: []float64{-0.012, -0.0016},
+		}
+	}
+
+	for _, tt := range []struct {
+		desc    string
+		change  func(hf *historyFile)
+		wantErr string
+	}{
+		{
+			desc: "valid",
+		},
+		{
+			desc: "saved before opens, highs, and lows",
+			change: func(hf *historyFile) {
+				hf.Opens, hf.Highs, hf.Lows = nil, nil, nil
+			},
+		},
+		{
+			desc: "missing close",
+			change: func(hf *historyFile) {
+				hf.Closes = hf.Closes[:1]
+			},
+			wantErr: "Closes has 1 values",
+		},
+		{
+			desc: "extra volume",
+			change: func(hf *historyFile) {
+				hf.Volumes = append(hf.Volumes, 1)
+			},
+			wantErr: "Volumes has 3 values",
+		},
+		{
+			desc: "missing percent change",
+			change: func(hf *historyFile) {
+				hf.PercentChange = nil
+			},
+			wantErr: "PercentChange has 0 values",
+		},
+		{
+			desc: "missing high",
+			change: func(hf *historyFile) {
+				hf.Highs = hf.Highs[:1]
+			},
+			wantErr: "Highs has 1 values",
+		},
+		{
+			desc: "split without a ratio",
+			change: func(hf *historyFile) {
+				hf.SplitDates = []time.Time{day(31)}
+			},
+			wantErr: "SplitRatios has 0 values",
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			hf := valid()
+			if tt.change != nil {
+				tt.change(&hf)
+			}
+
+			p := filepath.Join(t.TempDir(), historyFileName(hf.Symbol))
+			if err := writeHistoryFile(p, hf); err != nil {
+				t.Fatalf("writeHistoryFile() error = %v", err)
+			}
+
+			got, err := readHistoryFile(p)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readHistoryFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readHistoryFile() error = %v", err)
+			}
+			if n := len(got.sessions()); n != len(hf.Dates) {
+				t.Errorf("sessions() returned %d sessions, want %d", n, len(hf.Dates))
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import "time"
+
+// marketHoliday is a day the NYSE is closed or closes early.
+type marketHoliday struct {
+	name string
+
+	// earlyClose is whether the market closes at 1:00 PM rather than being closed all day.
+	earlyClose bool
+}
+
+// getMarketHoliday returns the NYSE holiday on the date or false if it is a regular day.
+// Dates follow the NYSE rules, including moving holidays on weekends to the nearest weekday,
+// but not one-off closures like for national days of mourning.
+func getMarketHoliday(date time.Time) (marketHoliday, bool) {
+	y, m, d := date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	for _, h := range marketHolidays(y) {
+		if h.date.Equal(day) {
+			return h.marketHoliday, true
+		}
+	}
+	return marketHoliday{}, false
+}
+
+// datedMarketHoliday is a marketHoliday on a specific date.
+type datedMarketHoliday struct {
+	marketHoliday
+	date time.Time
+}
+
+// marketHolidays returns the NYSE holidays and early closes of the year as UTC midnight dates.
+func marketHolidays(year int) []datedMarketHoliday {
+	date := func(m time.Month, d int) time.Time {
+		return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// nthWeekday returns the nth weekday of the month or the last one if n is -1.
+	nthWeekday := func(m time.Month, wd time.Weekday, n int) time.Time {
+		if n < 0 {
+			t := date(m+1, 1).AddDate(0, 0, -1)
+			for t.Weekday() != wd {
+				t = t.AddDate(0, 0, -1)
+			}
+			return t
+		}
+		t := date(m, 1)
+		for t.Weekday() != wd {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t.AddDate(0, 0, 7*(n-1))
+	}
+
+	// observed moves a holiday on Saturday to Friday and on Sunday to Monday.
+	observed := func(t time.Time) time.Time {
+		switch t.Weekday() {
+		case time.Saturday:
+			return t.AddDate(0, 0, -1)
+		case time.Sunday:
+			return t.AddDate(0, 0, 1)
+		}
+		return t
+	}
+
+	closed := func(name string, t time.Time) datedMarketHoliday {
+		return datedMarketHoliday{marketHoliday{name: name}, t}
+	}
+
+	early := func(name string, t time.Time) datedMarketHoliday {
+		return datedMarketHoliday{marketHoliday{name: name, earlyClose: true}, t}
+	}
+
+	var hs []datedMarketHoliday
+
+	// New Year's Day on a Saturday is not moved to the Friday before, since that would be in the prior year.
+	if nyd := date(time.January, 1); nyd.Weekday() != time.Saturday {
+		hs = append(hs, closed("New Year's Day", observed(nyd)))
+	}
+
+	hs = append(hs,
+		closed("Martin Luther King Jr. Day", nthWeekday(time.January, time.Monday, 3)),
+		closed("Washington's Birthday", nthWeekday(time.February, time.Monday, 3)),
+		closed("Good Friday", easter(year).AddDate(0, 0, -2)),
+		closed("Memorial Day", nthWeekday(time.May, time.Monday, -1)),
+	)
+
+	if year >= 2022 {
+		hs = append(hs, closed("Juneteenth", observed(date(time.June, 19))))
+	}
+
+	july4 := observed(date(time.July, 4))
+	hs = append(hs, closed("Independence Day", july4))
+	if july3 := date(time.July, 3); july4.Day() == 4 && july3.Weekday() != time.Sunday && july3.Weekday() != time.Saturday {
+		hs = append(hs, early("Independence Day Eve", july3))
+	}
+
+	thanksgiving := nthWeekday(time.November, time.Thursday, 4)
+	hs = append(hs,
+		closed("Labor Day", nthWeekday(time.September, time.Monday, 1)),
+		closed("Thanksgiving Day", thanksgiving),
+		early("Day After Thanksgiving", thanksgiving.AddDate(0, 0, 1)),
+	)
+
+	christmas := observed(date(time.December, 25))
+	hs = append(hs, closed("Christmas Day", christmas))
+	if eve := date(time.December, 24); christmas.Day() == 25 && eve.Weekday() != time.Sunday && eve.Weekday() != time.Saturday {
+		hs = append(hs, early("Christmas Eve", eve))
+	}
+
+	return hs
+}
+
+// easter returns the date of Easter Sunday in the year using the anonymous Gregorian algorithm.
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// isTradingDay returns whether the market is open at all on the date.
+func isTradingDay(date time.Time) bool {
+	switch date.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	h, ok := getMarketHoliday(date)
+	return !ok || h.earlyClose
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// hookEvent is an event that runs the commands of the user's hooks.
+type hookEvent string
+
+// List of possible hookEvent values.
+const (
+	// postRefreshEvent happens after the stocks are refreshed.
+	postRefreshEvent hookEvent = "post-refresh"
+
+	// fetchFailedEvent happens when a stock's trading sessions could not be fetched.
+	fetchFailedEvent hookEvent = "fetch-failed"
+)
+
+// hook is a command to run when an event happens.
+type hook struct {
+	// event is the event that runs the command.
+	event hookEvent
+
+	// command is the shell command to run.
+	command string
+}
+
+// hookTimeout is how long a hook's command can run before it is killed, so a hung command
+// like a notifier waiting on the network doesn't pile up with every refresh.
+var hookTimeout = time.Minute
+
+// hookQuote is a stock's latest quote in the JSON written to a hook command's stdin.
+// Capitalized for JSON encoding.
+type hookQuote struct {
+	Symbol        string
+	Date          time.Time
+	Close         float64
+	Change        float64
+	PercentChange float64
+	Volume        int64
+}
+
+// newHooks returns the hooks from the config, skipping the ones with unknown events.
+func newHooks(chs []configHook) []hook {
+	var hs []hook
+	for _, ch := range chs {
+		switch e := hookEvent(ch.Event); e {
+		case postRefreshEvent, fetchFailedEvent:
+			hs = append(hs, hook{e, ch.Command})
+		default:
+			log.Printf("unsupported hook event: %s", ch.Event)
+		}
+	}
+	return hs
+}
+
+// runHooks runs the commands of the event's hooks in the background. The event and the extra
+// variables like "PONZI_SYMBOL=AAPL" are passed in the environment and the latest quotes as JSON on stdin.
+func runHooks(sd *stockData, event hookEvent, env ...string) {
+	sd.RLock()
+	var commands []string
+	for _, h := range sd.hooks {
+		if h.event == event {
+			commands = append(commands, h.command)
+		}
+	}
+	var quotes []hookQuote
+	if len(commands) > 0 {
+		for _, s := range sd.stocks {
+			if ts, ok := latestTradingSession(s.tradingSessionMap); ok {
+				quotes = append(quotes, hookQuote{s.symbol, ts.date, ts.close, ts.change, ts.percentChange, ts.volume})
+			}
+		}
+	}
+	sd.RUnlock()
+
+	if len(commands) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(quotes)
+	if err != nil {
+		log.Printf("json.Marshal: %v", err)
+		return
+	}
+
+	env = append(os.Environ(), append(env, "PONZI_EVENT="+string(event))...)
+	for _, c := range commands {
+		go runHook(c, env, data)
+	}
+}
+
+// runHook runs the shell command with the environment and data on stdin and logs its output.
+// The command is killed if it runs longer than hookTimeout.
+func runHook(command string, env []string, data []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = env
+	cmd.Stdin = bytes.NewReader(data)
+
+	// Stop waiting for the output of the command's children that outlive it after it is killed.
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Printf("hook %q: %v: %s", command, err, out)
+		return
+	}
+	log.Printf("hook %q: %s", command, out)
+}
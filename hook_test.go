@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunHookTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook uses sleep from the Unix shell")
+	}
+
+	oldTimeout := hookTimeout
+	hookTimeout = 100 * time.Millisecond
+	defer func() { hookTimeout = oldTimeout }()
+
+	done := make(chan struct{})
+	go func() {
+		runHook("sleep 30", os.Environ(), nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("runHook() didn't return after the hook timed out")
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// debugHTTP is a flag to save response bodies to disk for debugging.
+	debugHTTP = flag.Bool("debug_http", false, "Save HTTP response bodies to the http directory next to the log. Account data from Alpaca is never saved.")
+
+	// recordDir is a flag to record the successful responses to a directory for replaying later.
+	recordDir = flag.String("record", "", "Directory to record successful HTTP responses to for use with -replay.")
+
+	// offline is a flag to show the cached data without making any network requests.
+	offline = flag.Bool("offline", false, "Show the cached data from the last run and never make network requests.")
+
+	// replayDir is a flag to serve responses from a directory of recorded responses instead of the network.
+	replayDir = flag.String("replay", "", "Directory of responses recorded with -record to serve instead of making HTTP requests.")
+)
+
+// httpClient is shared by all requests, so connections to the sources are kept alive and reused
+// across the many requests of each refresh. Responses are requested gzipped and decoded transparently.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// maxDrainLength is the most bytes of an unread response body that are discarded when it is closed,
+// so the connection can be reused rather than closed.
+const maxDrainLength = 64 << 10
+
+// httpDebugCount numbers the saved response bodies to keep their file names unique.
+var httpDebugCount int64
+
+// httpGet gets the URL and logs the request. The request is canceled if the context is done.
+func httpGet(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doHTTPRequest(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// maxResponseLength is the most bytes read from a response, which is much more than
+// the largest responses like EDGAR's company tickers.
+const maxResponseLength = 64 << 20
+
+// errResponseTooLarge is returned when reading more than maxResponseLength bytes of a response.
+var errResponseTooLarge = fmt.Errorf("response larger than %d bytes", maxResponseLength)
+
+// limitedReadCloser returns errResponseTooLarge once more than n bytes are read.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+
+	// n is the most bytes that can be read.
+	n int64
+
+	// count is the number of bytes read so far.
+	count int64
+}
+
+// Close implements io.Closer. It reads the rest of a short body first to reuse the connection.
+func (l *limitedReadCloser) Close() error {
+	io.CopyN(ioutil.Discard, l.Reader, maxDrainLength)
+	return l.Closer.Close()
+}
+
+// Read implements io.Reader.
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := l.Reader.Read(p)
+	l.count += int64(n)
+	if l.count > l.n {
+		return 0, errResponseTooLarge
+	}
+	return n, err
+}
+
+// maxErrorBodyLength is how much of an error response's body is kept in its statusError.
+const maxErrorBodyLength = 200
+
+// statusError is the error when a response has a status other than 200 OK.
+type statusError struct {
+	// status is the response's status like "404 Not Found".
+	status string
+
+	// body is the start of the response's body, which may explain the error.
+	body string
+
+	// retryAfter is how long the provider asked to wait before retrying or zero if it didn't.
+	retryAfter time.Duration
+}
+
+// Error implements error.
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return e.status
+	}
+	return fmt.Sprintf("%s: %s", e.status, e.body)
+}
+
+// checkStatus returns a statusError and closes the body if the response's status is not 200 OK.
+// Only the start of the body is read, since error pages may be large.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+	if err != nil {
+		log.Printf("ReadAll: %v", err)
+	}
+	return &statusError{
+		status:     resp.Status,
+		body:       strings.Join(strings.Fields(string(data)), " "),
+		retryAfter: parseRetryAfter(resp, time.Now()),
+	}
+}
+
+// parseRetryAfter returns the duration of the response's Retry-After header, which is either
+// seconds or an HTTP date. It returns zero if the header is missing or invalid.
+func parseRetryAfter(resp *http.Response, now time.Time) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// doHTTPRequest does the request and logs it. If debugging or recording, the response body is
+// streamed to a file as it is read rather than being buffered or written to the log.
+// If replaying, the response is served from the recorded responses instead.
+func doHTTPRequest(req *http.Request) (*http.Response, error) {
+	log.Printf("%s %s", req.Method, req.URL)
+
+	if *replayDir != "" {
+		return replayResponse(req)
+	}
+
+	if *offline {
+		return nil, errors.New("offline")
+	}
+
+	if err := waitRateLimit(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	countRequest(req.URL.Host)
+
+	addConditionalHeaders(req)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep a misbehaving endpoint from using up memory with an endless response,
+	// including the ones cached for conditional requests.
+	resp.Body = &limitedReadCloser{
+		Reader: io.LimitReader(resp.Body, maxResponseLength+1),
+		Closer: resp.Body,
+		n:      maxResponseLength,
+	}
+
+	if resp, err = handleConditionalResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Stop sending requests to the host for a while if it says there are too many.
+	if resp.StatusCode == http.StatusTooManyRequests {
+		d := parseRetryAfter(resp, time.Now())
+		if d == 0 {
+			d = defaultRetryAfter
+		}
+		log.Printf("%s: %s, backing off for %v", req.URL.Host, resp.Status, d)
+		backOffRateLimit(req.URL.Host, time.Now().Add(d))
+	}
+
+	// Fail with the page's title rather than letting the parsers choke on the HTML.
+	// Other statuses are left for checkStatus, so callers still see the status and when to retry.
+	if resp.StatusCode == http.StatusOK {
+		if err := checkErrorPage(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	if *recordDir != "" && resp.StatusCode == http.StatusOK {
+		file, err := createFile(*recordDir, recordingName(req))
+		if err != nil {
+			log.Printf("createFile: %v", err)
+		} else {
+			teeBody(resp, file)
+		}
+	}
+
+	if *debugHTTP {
+		file, err := createHTTPDebugFile(req)
+		if err != nil {
+			log.Printf("createHTTPDebugFile: %v", err)
+			return resp, nil
+		}
+		log.Printf("%s %s: %s, saving body to %s", req.Method, req.URL, resp.Status, file.Name())
+		teeBody(resp, file)
+	}
+
+	return resp, nil
+}
+
+// doPrivateHTTPRequest does a request for account data like Alpaca's balances and orders. Unlike
+// doHTTPRequest, the response is never recorded, saved for debugging, or cached for conditional requests.
+func doPrivateHTTPRequest(req *http.Request) (*http.Response, error) {
+	log.Printf("%s %s", req.Method, req.URL)
+
+	if *replayDir != "" {
+		return nil, errors.New("private responses are not recorded")
+	}
+
+	if *offline {
+		return nil, errors.New("offline")
+	}
+
+	if err := waitRateLimit(req.Context(), req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	countRequest(req.URL.Host)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &limitedReadCloser{
+		Reader: io.LimitReader(resp.Body, maxResponseLength+1),
+		Closer: resp.Body,
+		n:      maxResponseLength,
+	}
+	return resp, nil
+}
+
+// replayResponse returns the recorded response of the request.
+func replayResponse(req *http.Request) (*http.Response, error) {
+	file, err := os.Open(path.Join(*replayDir, recordingName(req)))
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response: %v", err)
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		Header:     http.Header{},
+		Body:       file,
+		Request:    req,
+	}, nil
+}
+
+// recordingName returns the file name of the request's recorded response.
+func recordingName(req *http.Request) string {
+	return fmt.Sprintf("%s-%x.txt", req.URL.Host, sha1.Sum([]byte(req.Method+" "+req.URL.String())))
+}
+
+// teeBody copies what is read from the response body to the file.
+func teeBody(resp *http.Response, file *os.File) {
+	resp.Body = &teeReadCloser{
+		Reader: io.TeeReader(resp.Body, file),
+		body:   resp.Body,
+		file:   file,
+	}
+}
+
+// createHTTPDebugFile creates a file to save the response body of the request.
+func createHTTPDebugFile(req *http.Request) (*os.File, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	n := atomic.AddInt64(&httpDebugCount, 1)
+	name := fmt.Sprintf("%s-%03d-%s.txt", time.Now().Format("20060102-150405"), n, req.URL.Host)
+	return createFile(path.Join(dirPath, "http"), name)
+}
+
+// createFile creates or truncates the file in the directory, creating the directory if needed.
+func createFile(dirPath, name string) (*os.File, error) {
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path.Join(dirPath, name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+}
+
+// teeReadCloser copies what is read from the response body to a file and closes both.
+type teeReadCloser struct {
+	io.Reader
+	body io.Closer
+	file io.Closer
+}
+
+// Close implements io.Closer.
+func (t *teeReadCloser) Close() error {
+	ferr := t.file.Close()
+	if err := t.body.Close(); err != nil {
+		return err
+	}
+	return ferr
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPGetErrorPages(t *testing.T) {
+	for _, tt := range []struct {
+		desc           string
+		status         int
+		retryAfter     string
+		wantStatus     string
+		wantRetryAfter time.Duration
+		wantErrorPage  bool
+	}{
+		{
+			desc:       "html not found",
+			status:     http.StatusNotFound,
+			wantStatus: "404 Not Found",
+		},
+		{
+			desc:           "html too many requests",
+			status:         http.StatusTooManyRequests,
+			retryAfter:     "30",
+			wantStatus:     "429 Too Many Requests",
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			desc:          "html with ok status",
+			status:        http.StatusOK,
+			wantErrorPage: true,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte("<html><head><title>Oops</title></head></html>"))
+			}))
+			defer ts.Close()
+
+			_, err := httpGet(context.Background(), ts.URL)
+
+			var pe *errorPageError
+			if got := errors.As(err, &pe); got != tt.wantErrorPage {
+				t.Fatalf("httpGet() error = %v, want error page %t", err, tt.wantErrorPage)
+			}
+			if tt.wantErrorPage {
+				return
+			}
+
+			var se *statusError
+			if !errors.As(err, &se) {
+				t.Fatalf("httpGet() error = %v, want a statusError", err)
+			}
+			if se.status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", se.status, tt.wantStatus)
+			}
+			if se.retryAfter != tt.wantRetryAfter {
+				t.Errorf("retryAfter = %v, want %v", se.retryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
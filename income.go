@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nsf/termbox-go"
+)
+
+// printIncome prints the dividend income of the stocks with lots.
+// The caller must hold the stockData read lock.
+func printIncome(sd *stockData, w, h int) {
+	print := func(x, y int, fg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, termbox.ColorDefault)
+			x++
+		}
+		return x
+	}
+
+	const format = " %-6s %10s %12s %10s %12s %8s %-12s"
+	now := chartToday()
+
+	print(0, 0, termbox.ColorDefault, " Dividend Income")
+	print(0, 2, termbox.ColorDefault, format, "Symbol", "Qty", "Cost", "TTM/Share", "TTM Income", "YOC", "Next Ex-Date")
+
+	var totalIncome, totalCostBasis float64
+	y := 3
+	for _, s := range sd.stocks {
+		if len(s.lots) == 0 {
+			continue
+		}
+		if y >= h-2 {
+			break
+		}
+
+		ds := mergeDividends(s.fetchedDividends, s.dividends)
+		quantity, cost := totalQuantity(s.lots), totalCost(s.lots)
+		perShare := trailingDividends(ds, now)
+		income := perShare * quantity
+
+		yoc := "-"
+		if cost > 0 {
+			yoc = fmt.Sprintf("%.2f%%", income/cost*100)
+		}
+
+		next := "-"
+		if date, estimated, ok := nextExDividendDate(ds, now); ok {
+			next = date.Format("1/2/06")
+			if estimated {
+				next += " est"
+			}
+		}
+
+		print(0, y, termbox.ColorDefault, format,
+			s.symbol,
+			fmt.Sprintf("%.4g", quantity),
+			fmt.Sprintf("%.2f", cost),
+			fmt.Sprintf("%.4f", perShare),
+			fmt.Sprintf("%.2f", income),
+			yoc,
+			next)
+
+		totalIncome += income
+		totalCostBasis += cost
+		y++
+	}
+
+	yoc := "-"
+	if totalCostBasis > 0 {
+		yoc = fmt.Sprintf("%.2f%%", totalIncome/totalCostBasis*100)
+	}
+	print(0, y+1, termbox.ColorDefault|termbox.AttrBold, format, "Total", "", fmt.Sprintf("%.2f", totalCostBasis), "", fmt.Sprintf("%.2f", totalIncome), yoc, "")
+
+	print(0, h-1, termbox.ColorDefault, " Esc:Back")
+}
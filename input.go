@@ -0,0 +1,618 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+)
+
+// handleEvent handles the event from the screen and returns true if the user asked to quit.
+// Keys go to the open view or popup before the grid's own keys.
+func (u *ui) handleEvent(ev termbox.Event) (quit bool) {
+	if ev.Type != termbox.EventKey {
+		return false
+	}
+
+	switch {
+	case u.openView != nil:
+		return u.handleViewKey(ev)
+	case u.detailOpen && u.layoutNameOpen:
+		u.handleLayoutNameKey(ev)
+	case u.detailOpen && u.detailInputOpen:
+		u.handleDetailInputKey(ev)
+	case u.detailOpen:
+		return u.handleDetailKey(ev)
+	case u.txOpen:
+		u.handleTransactionKey(ev)
+	case u.backtestOpen:
+		u.handleBacktestKey(ev)
+	case u.commandOpen:
+		return u.handleCommandKey(ev)
+	case u.filterOpen:
+		u.handleFilterKey(ev)
+	case u.orderOpen:
+		u.handleOrderKey(ev)
+	default:
+		return u.handleGridKey(ev)
+	}
+	return false
+}
+
+// handleViewKey handles keys for the open full screen view.
+func (u *ui) handleViewKey(ev termbox.Event) (quit bool) {
+	switch {
+	case u.openView.handleKey != nil && u.openView.handleKey(ev):
+	case ev.Key == termbox.KeyCtrlC || ev.Key == termbox.KeyCtrlD:
+		return true
+	case ev.Key == termbox.KeyEsc || u.views[ev.Key] == u.openView:
+		u.openView = nil
+	}
+	return false
+}
+
+// handleLayoutNameKey handles keys typed into the layout name popup.
+func (u *ui) handleLayoutNameKey(ev termbox.Event) {
+	switch ev.Key {
+	case termbox.KeyEsc:
+		u.layoutNameOpen = false
+
+	case termbox.KeyEnter:
+		if u.layoutName == "" {
+			break
+		}
+		u.layoutNameOpen = false
+
+		cl := chartLayout{
+			name:       u.layoutName,
+			rangeLabel: u.detailLabel,
+			options:    u.detailOptions,
+		}
+		if _, ok := findChartRange(u.detailLabel); !ok {
+			cl.start, cl.end = u.detailStart, u.detailEnd
+		}
+
+		// Replace any layout with the same name or add a new one.
+		u.sd.Lock()
+		replaced := false
+		for i := range u.sd.layouts {
+			if u.sd.layouts[i].name == cl.name {
+				u.sd.layouts[i], replaced = cl, true
+			}
+		}
+		if !replaced {
+			u.sd.layouts = append(u.sd.layouts, cl)
+		}
+		saveStockData(u.sd)
+		u.sd.Unlock()
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.layoutName) > 0 {
+			u.layoutName = u.layoutName[:len(u.layoutName)-1]
+		}
+
+	case termbox.KeySpace:
+		u.layoutName += " "
+
+	default:
+		if ev.Ch != 0 {
+			u.layoutName += string(ev.Ch)
+		}
+	}
+}
+
+// handleDetailInputKey handles keys typed into the custom chart range popup.
+func (u *ui) handleDetailInputKey(ev termbox.Event) {
+	switch ev.Key {
+	case termbox.KeyEsc:
+		u.detailInputOpen = false
+
+	case termbox.KeyEnter:
+		start, end, err := parseChartRangeInput(u.detailInput)
+		if err != nil {
+			u.detailStatus = err.Error()
+			break
+		}
+		u.detailInputOpen = false
+		u.setDetailRange("Custom", start, end)
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.detailInput) > 0 {
+			u.detailInput = u.detailInput[:len(u.detailInput)-1]
+		}
+		u.detailStatus = ""
+
+	case termbox.KeySpace:
+		u.detailInput += " "
+		u.detailStatus = ""
+
+	default:
+		if unicode.IsDigit(ev.Ch) || ev.Ch == '-' {
+			u.detailInput += string(ev.Ch)
+			u.detailStatus = ""
+		}
+	}
+}
+
+// handleDetailKey handles keys for the detail view.
+func (u *ui) handleDetailKey(ev termbox.Event) (quit bool) {
+	switch {
+	case ev.Key == termbox.KeyCtrlC || ev.Key == termbox.KeyCtrlD:
+		return true
+
+	case ev.Key == termbox.KeyEsc:
+		u.detailOpen = false
+
+	case ev.Ch == 'c' || ev.Ch == 'C':
+		u.detailInputOpen, u.detailInput, u.detailStatus = true, "", ""
+
+	case ev.Ch == 'i' || ev.Ch == 'I':
+		u.detailOptions.movingAverages = nextMovingAverages(u.detailOptions.movingAverages)
+		u.setDetailRange(u.detailLabel, u.detailStart, u.detailEnd)
+
+	case ev.Ch == 'l' || ev.Ch == 'L':
+		u.detailOptions.logScale = !u.detailOptions.logScale
+
+	case ev.Ch == 's' || ev.Ch == 'S':
+		u.layoutNameOpen, u.layoutName = true, ""
+
+	case ev.Ch >= '1' && ev.Ch <= '9':
+		u.sd.RLock()
+		i := int(ev.Ch - '1')
+		ok := i < len(u.sd.layouts)
+		var cl chartLayout
+		if ok {
+			cl = u.sd.layouts[i]
+		}
+		u.sd.RUnlock()
+		if ok {
+			u.applyLayout(cl)
+		}
+
+	default:
+		for _, cr := range chartRanges {
+			if unicode.ToLower(ev.Ch) == cr.key {
+				end := chartToday()
+				u.setDetailRange(cr.label, cr.start(end), end)
+			}
+		}
+	}
+	return false
+}
+
+// handleTransactionKey handles keys typed into the transaction popup.
+func (u *ui) handleTransactionKey(ev termbox.Event) {
+	switch ev.Key {
+	case termbox.KeyEsc:
+		u.txOpen = false
+
+	case termbox.KeyEnter:
+		t, err := parseTransaction(u.txInput)
+		if err != nil {
+			u.txStatus = err.Error()
+			break
+		}
+
+		u.sd.Lock()
+		s := &u.sd.stocks[u.selectedIndex]
+		lots, sales, err := applyTransaction(s.lots, t)
+		if err != nil {
+			u.txStatus = err.Error()
+		} else {
+			s.lots = lots
+			for _, sl := range sales {
+				sl.symbol = s.symbol
+				u.sd.sales = append(u.sd.sales, sl)
+			}
+			saveStockData(u.sd)
+			u.txOpen = false
+		}
+		u.sd.Unlock()
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.txInput) > 0 {
+			u.txInput = u.txInput[:len(u.txInput)-1]
+		}
+		u.txStatus = ""
+
+	case termbox.KeySpace:
+		u.txInput += " "
+		u.txStatus = ""
+
+	default:
+		if ev.Ch != 0 {
+			u.txInput += string(ev.Ch)
+			u.txStatus = ""
+		}
+	}
+}
+
+// handleBacktestKey handles keys typed into the backtest popup.
+func (u *ui) handleBacktestKey(ev termbox.Event) {
+	switch ev.Key {
+	case termbox.KeyEsc:
+		u.backtestOpen = false
+
+	case termbox.KeyEnter:
+		weights, err := parseAllocation(u.backtestInput)
+		if err != nil {
+			u.backtestLines = []string{err.Error()}
+			break
+		}
+
+		u.sd.RLock()
+		tsms := map[string]map[time.Time]stockTradingSession{}
+		for _, s := range u.sd.stocks {
+			tsms[s.symbol] = s.tradingSessionMap
+		}
+		res, err := backtest(tsms, weights)
+		u.sd.RUnlock()
+
+		if err != nil {
+			u.backtestLines = []string{err.Error()}
+			break
+		}
+		u.backtestLines = res.lines()
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.backtestInput) > 0 {
+			u.backtestInput = u.backtestInput[:len(u.backtestInput)-1]
+		}
+
+	case termbox.KeySpace:
+		u.backtestInput += " "
+
+	default:
+		if ev.Ch != 0 {
+			u.backtestInput += strings.ToUpper(string(ev.Ch))
+		}
+	}
+}
+
+// handleCommandKey handles keys typed into the command input and returns true for the quit command.
+func (u *ui) handleCommandKey(ev termbox.Event) (quit bool) {
+	switch ev.Key {
+	case termbox.KeyEsc:
+		u.commandOpen = false
+
+	case termbox.KeyEnter:
+		if name, _ := parseCommand(u.command); name == "q" || name == "quit" {
+			return true
+		}
+		status, err := u.runCommand(u.command)
+		if err != nil {
+			log.Printf("runCommand: %v", err)
+			status = err.Error()
+		}
+		u.commandOpen, u.commandStatus = status != "", status
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.command) > 0 {
+			u.command = u.command[:len(u.command)-1]
+		}
+		u.commandStatus = ""
+
+	case termbox.KeySpace:
+		u.command += " "
+		u.commandStatus = ""
+
+	default:
+		if ev.Ch != 0 {
+			u.command += string(ev.Ch)
+			u.commandStatus = ""
+		}
+	}
+	return false
+}
+
+// handleFilterKey handles keys typed into the filter input.
+func (u *ui) handleFilterKey(ev termbox.Event) {
+	switch ev.Key {
+	case termbox.KeyEsc:
+		u.filterOpen, u.filter = false, ""
+
+	case termbox.KeyEnter:
+		u.filterOpen = false
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.filter) > 0 {
+			u.filter = u.filter[:len(u.filter)-1]
+		}
+
+	default:
+		if unicode.IsLetter(ev.Ch) || unicode.IsDigit(ev.Ch) || ev.Ch == '.' || ev.Ch == '^' {
+			u.filter += strings.ToUpper(string(ev.Ch))
+		}
+	}
+	u.selectMatch()
+}
+
+// handleOrderKey handles keys typed into the order popup.
+func (u *ui) handleOrderKey(ev termbox.Event) {
+	u.sd.RLock()
+	symbol := u.sd.stocks[u.selectedIndex].symbol
+	u.sd.RUnlock()
+
+	switch ev.Key {
+	case termbox.KeyCtrlC, termbox.KeyCtrlD, termbox.KeyEsc:
+		u.orderOpen = false
+
+	case termbox.KeyTab:
+		if u.orderSide == buy {
+			u.orderSide = sell
+		} else {
+			u.orderSide = buy
+		}
+		u.orderConfirm, u.orderStatus = nil, ""
+
+	case termbox.KeyEnter:
+		if u.orderConfirm == nil {
+			o, err := parseAlpacaOrderInput(symbol, u.orderSide, u.orderInput)
+			if err != nil {
+				u.orderStatus = err.Error()
+				break
+			}
+			u.orderConfirm, u.orderStatus = &o, ""
+			break
+		}
+
+		if err := submitAlpacaOrder(u.ctx, *u.orderConfirm); err != nil {
+			log.Printf("submitAlpacaOrder: %v", err)
+			u.orderConfirm, u.orderStatus = nil, err.Error()
+			break
+		}
+		u.orderOpen = false
+
+		// Get the updated account and positions.
+		refreshStockData(u.ctx, u.sd, symbol)
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.orderInput) > 0 {
+			u.orderInput = u.orderInput[:len(u.orderInput)-1]
+		}
+		u.orderConfirm, u.orderStatus = nil, ""
+
+	default:
+		if unicode.IsDigit(ev.Ch) || ev.Ch == '.' || ev.Ch == '@' {
+			u.orderInput += string(ev.Ch)
+			u.orderConfirm, u.orderStatus = nil, ""
+		}
+	}
+}
+
+// handleGridKey handles keys for the grid when no popup is open.
+func (u *ui) handleGridKey(ev termbox.Event) (quit bool) {
+	// Open the full screen view of the function key.
+	if v, ok := u.views[ev.Key]; ok {
+		if v.open == nil || v.open() {
+			u.openView = v
+		}
+		return false
+	}
+
+	switch ev.Key {
+	case termbox.KeyCtrlC, termbox.KeyCtrlD:
+		return true
+
+	case termbox.KeyCtrlR, termbox.KeyF5:
+		refreshStockData(u.ctx, u.sd, "")
+
+	case termbox.KeyArrowLeft, termbox.KeyArrowRight:
+		if len(u.visibleDates) == 0 {
+			break
+		}
+
+		// Start at the most recent date and move within the visible dates.
+		i := len(u.visibleDates) - 1
+		for j, td := range u.visibleDates {
+			if td.Equal(u.selectedDate) {
+				i = j
+				if ev.Key == termbox.KeyArrowLeft && i > 0 {
+					i--
+				}
+				if ev.Key == termbox.KeyArrowRight && i+1 < len(u.visibleDates) {
+					i++
+				}
+			}
+		}
+		u.selectedDate = u.visibleDates[i]
+
+	case termbox.KeyEsc:
+		if u.inputSymbol != "" {
+			u.inputSymbol, u.inputStatus = "", ""
+			break
+		}
+		u.selectedDate = time.Time{}
+
+	case termbox.KeyF7:
+		u.backtestOpen, u.backtestLines = true, nil
+
+	case termbox.KeyF9:
+		u.statsOpen = !u.statsOpen
+
+	case termbox.KeyCtrlY, termbox.KeyCtrlU:
+		// Copy the selected symbol or its latest quote.
+		u.sd.RLock()
+		var text string
+		if len(u.sd.stocks) > 0 {
+			text = u.sd.stocks[u.selectedIndex].symbol
+			if ev.Key == termbox.KeyCtrlU {
+				text = quoteLine(u.sd.stocks[u.selectedIndex])
+			}
+		}
+		u.sd.RUnlock()
+		if text != "" {
+			if err := copyToClipboard(text); err != nil {
+				log.Printf("copyToClipboard: %v", err)
+			}
+		}
+
+	case termbox.KeyCtrlB:
+		// Open the selected symbol's page in the browser.
+		u.sd.RLock()
+		var link string
+		if len(u.sd.stocks) > 0 {
+			link = quoteURL(u.sd.quoteURL, u.sd.stocks[u.selectedIndex].symbol)
+		}
+		u.sd.RUnlock()
+		if link != "" {
+			if err := openBrowser(link); err != nil {
+				log.Printf("openBrowser: %v", err)
+			}
+		}
+
+	case termbox.KeyCtrlV:
+		// Paste a list of symbols into the input to add with Enter.
+		text, err := pasteFromClipboard()
+		if err != nil {
+			log.Printf("pasteFromClipboard: %v", err)
+			break
+		}
+		if symbols := parseSymbolList(text); len(symbols) > 0 {
+			if u.inputSymbol != "" {
+				u.inputSymbol += ","
+			}
+			u.inputSymbol, u.inputStatus = u.inputSymbol+strings.Join(symbols, ","), ""
+		}
+
+	case termbox.KeyCtrlE:
+		u.sectorStrip = !u.sectorStrip
+
+	case termbox.KeyCtrlF:
+		u.filingsOpen = !u.filingsOpen
+
+	case termbox.KeyCtrlL:
+		u.timelineOpen = !u.timelineOpen
+
+	case termbox.KeyCtrlW:
+		u.setAggregation(u.gridAggregation.next())
+
+	case termbox.KeyCtrlT:
+		if *readOnly {
+			break
+		}
+		u.sd.RLock()
+		u.txOpen, u.txInput, u.txStatus = len(u.sd.stocks) > 0, "", ""
+		u.sd.RUnlock()
+
+	case termbox.KeyCtrlO:
+		if *readOnly {
+			break
+		}
+		u.sd.RLock()
+		hasStocks := len(u.sd.stocks) > 0
+		u.sd.RUnlock()
+		if hasStocks && hasAlpacaCredentials() {
+			u.orderOpen, u.orderSide, u.orderInput, u.orderConfirm, u.orderStatus = true, buy, "", nil, ""
+		}
+
+	case termbox.KeyArrowUp:
+		// Alt+Up moves the stock in terminals that support it. See '[' for others.
+		u.moveSelection(-1, ev.Mod == termbox.ModAlt)
+
+	case termbox.KeyArrowDown:
+		u.moveSelection(1, ev.Mod == termbox.ModAlt)
+
+	case termbox.KeyEnter:
+		// Open the detail view of the selected stock if the user is not typing a symbol.
+		if u.inputSymbol == "" {
+			u.sd.RLock()
+			hasStocks := len(u.sd.stocks) > 0
+			u.sd.RUnlock()
+			if !hasStocks {
+				break
+			}
+
+			u.detailOpen = true
+			u.detailMarkDate = time.Time{}
+
+			// Get the analysts' price target in the background and repaint when it arrives.
+			u.sd.RLock()
+			symbol := u.sd.stocks[u.selectedIndex].symbol
+			u.sd.RUnlock()
+			go func() {
+				if refreshAnalystSummary(u.ctx, u.sd, symbol) {
+					term.interrupt()
+				}
+			}()
+
+			// Center the chart on the selected date if a date column is selected.
+			if !u.selectedDate.IsZero() {
+				end := u.selectedDate.AddDate(0, 1, 0)
+				if today := chartToday(); end.After(today) {
+					end = today
+				}
+				u.detailMarkDate = u.selectedDate
+				u.setDetailRange(u.selectedDate.Format("1/2/06")+" ±1M", u.selectedDate.AddDate(0, -1, 0), end)
+				break
+			}
+
+			end := chartToday()
+			u.setDetailRange(chartRanges[0].label, chartRanges[0].start(end), end)
+			break
+		}
+
+		u.inputSymbol, u.inputStatus = u.addSymbols(u.inputSymbol)
+
+	case termbox.KeyDelete:
+		u.deleteSelected()
+
+	case termbox.KeySpace:
+		if u.inputSymbol != "" {
+			u.inputSymbol, u.inputStatus = u.inputSymbol+" ", ""
+		}
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(u.inputSymbol) > 0 {
+			u.inputSymbol, u.inputStatus = u.inputSymbol[:len(u.inputSymbol)-1], ""
+		}
+
+	default:
+		switch {
+		// Separate several symbols to add with commas or spaces.
+		// Allow the punctuation and digits of symbols like ES=F and BRK.B after the first letter.
+		case unicode.IsLetter(ev.Ch) || u.inputSymbol != "" && (unicode.IsDigit(ev.Ch) || strings.ContainsRune(",=.", ev.Ch)):
+			u.inputSymbol, u.inputStatus = u.inputSymbol+strings.ToUpper(string(ev.Ch)), ""
+
+		// Move the stock with '[' and ']' too, since Windows consoles don't report Alt+Arrow.
+		case ev.Ch == '[':
+			u.moveSelection(-1, true)
+
+		case ev.Ch == ']':
+			u.moveSelection(1, true)
+
+		case ev.Ch == '*':
+			u.togglePinned()
+
+		case ev.Ch == '/':
+			u.filterOpen = true
+
+		case ev.Ch == '+':
+			// Mark or unmark the selected stock to compare in the compare view.
+			u.sd.RLock()
+			if len(u.sd.stocks) > 0 {
+				symbol := u.sd.stocks[u.selectedIndex].symbol
+				if u.compareMarks[symbol] {
+					delete(u.compareMarks, symbol)
+				} else if len(u.compareMarks) < maxCompareSymbols {
+					u.compareMarks[symbol] = true
+				}
+			}
+			u.sd.RUnlock()
+
+		case ev.Ch == ':':
+			u.commandOpen, u.command, u.commandStatus = true, "", ""
+
+		case ev.Ch == '|':
+			u.highlightColumn = !u.highlightColumn
+
+			// Select the most recent date to have something to highlight.
+			if u.highlightColumn && u.selectedDate.IsZero() && len(u.visibleDates) > 0 {
+				u.selectedDate = u.visibleDates[len(u.visibleDates)-1]
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// maxTimelineLines is the number of the latest observations listed in the timeline popup.
+const maxTimelineLines = 12
+
+// journalEntry is a live quote observed during a refresh. Capitalized for JSON encoding.
+type journalEntry struct {
+	Time          time.Time
+	Symbol        string
+	Price         float64
+	Change        float64
+	PercentChange float64
+}
+
+// journal is the day's observed live quotes, appended to a file per day to survive restarts.
+var journal = struct {
+	// Embedded mutex that guards the fields and the file.
+	sync.Mutex
+
+	// date is the New York date of the entries like "2006-01-02" or empty if nothing is loaded.
+	date string
+
+	// entries is a map from symbol to its observations in the order they were made.
+	entries map[string][]journalEntry
+}{}
+
+// recordJournal appends the live quotes that changed since their last observation to the day's journal.
+func recordJournal(lts []liveTradingSession) {
+	if len(lts) == 0 {
+		return
+	}
+
+	journal.Lock()
+	defer journal.Unlock()
+
+	if err := loadJournalLocked(clk.now()); err != nil {
+		log.Printf("loadJournal: %v", err)
+		return
+	}
+
+	var added []journalEntry
+	for _, lt := range lts {
+		es := journal.entries[lt.symbol]
+		if n := len(es); n > 0 && es[n-1].Price == lt.price && es[n-1].Change == lt.change {
+			continue
+		}
+		e := journalEntry{
+			Time:          clk.now(),
+			Symbol:        lt.symbol,
+			Price:         lt.price,
+			Change:        lt.change,
+			PercentChange: lt.percentChange,
+		}
+		journal.entries[lt.symbol] = append(es, e)
+		added = append(added, e)
+	}
+	// Keep the observations in memory without writing them in read-only mode.
+	if len(added) == 0 || *readOnly {
+		return
+	}
+
+	p, err := getJournalPath(journal.date)
+	if err != nil {
+		log.Printf("getJournalPath: %v", err)
+		return
+	}
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0660)
+	if err != nil {
+		log.Printf("os.OpenFile: %v", err)
+		return
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	for _, e := range added {
+		if err := enc.Encode(e); err != nil {
+			log.Printf("journal: %v", err)
+			return
+		}
+	}
+}
+
+// journalTimeline returns the day's observations of the symbol.
+func journalTimeline(symbol string) []journalEntry {
+	journal.Lock()
+	defer journal.Unlock()
+
+	if err := loadJournalLocked(clk.now()); err != nil {
+		log.Printf("loadJournal: %v", err)
+		return nil
+	}
+	return append([]journalEntry(nil), journal.entries[symbol]...)
+}
+
+// loadJournalLocked loads the journal of the time's New York date unless it is already loaded.
+// The caller must hold the journal lock.
+func loadJournalLocked(t time.Time) error {
+	date := t.In(newYorkLoc).Format("2006-01-02")
+	if journal.date == date {
+		return nil
+	}
+	journal.date, journal.entries = date, map[string][]journalEntry{}
+
+	p, err := getJournalPath(date)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return err
+		}
+		journal.entries[e.Symbol] = append(journal.entries[e.Symbol], e)
+	}
+	return s.Err()
+}
+
+// getJournalPath returns the path of the date's journal, creating its directory if needed.
+func getJournalPath(date string) (string, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dirPath = path.Join(dirPath, "journal")
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return "", err
+	}
+	return path.Join(dirPath, date+".jsonl"), nil
+}
+
+// timelineLines returns the lines of the timeline popup of the symbol's observations.
+func timelineLines(symbol string, es []journalEntry) []string {
+	lines := []string{fmt.Sprintf("%s Today Since Start", symbol), ""}
+	if len(es) == 0 {
+		return append(lines, "No quotes observed yet", "", "Ctrl+L: Close")
+	}
+
+	var prices []float64
+	for _, e := range es {
+		prices = append(prices, e.Price)
+	}
+	lines = append(lines, sparkline(prices), "")
+
+	if len(es) > maxTimelineLines {
+		es = es[len(es)-maxTimelineLines:]
+	}
+	for _, e := range es {
+		lines = append(lines, fmt.Sprintf("%s  %10.2f  %+8.2f  %+6.2f%%",
+			formatDisplayTime(e.Time, "3:04 PM"), e.Price, e.Change, e.PercentChange*100))
+	}
+	return append(lines, "", "Ctrl+L: Close")
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// liveTradingSessionFunc is a function that returns liveTradingSessions.
+type liveTradingSessionFunc func(ctx context.Context, symbols []string) ([]liveTradingSession, error)
+
+// liveSource is a source of live trading sessions and how healthy it has been.
+type liveSource struct {
+	name string
+	get  liveTradingSessionFunc
+
+	// maxSymbols is the most symbols the source accepts in one request.
+	maxSymbols int
+
+	// failures is the number of consecutive failed requests.
+	failures int
+
+	// retryTime is when to try the source again after it failed.
+	retryTime time.Time
+}
+
+// liveSources are the live trading session sources in the order to try them.
+var liveSources = struct {
+	// Embedded mutex that guards the sources' health.
+	sync.Mutex
+	sources []*liveSource
+}{
+	sources: []*liveSource{
+		{name: "google", get: getLiveTradingSessionsFromGoogle, maxSymbols: 100},
+		{name: "yahoo", get: getLiveTradingSessionsFromYahoo, maxSymbols: 200},
+	},
+}
+
+// maxLiveSourceBackoff is the longest time to wait before retrying a failing source.
+const maxLiveSourceBackoff = time.Hour
+
+// getLiveTradingSessions gets the live trading sessions from the first healthy source that succeeds.
+// Failing sources are skipped with an increasing backoff until they recover.
+// If every source is backing off, they are all tried anyway rather than returning nothing.
+func getLiveTradingSessions(ctx context.Context, symbols []string) ([]liveTradingSession, error) {
+	now := clk.now()
+
+	liveSources.Lock()
+	var healthy, unhealthy []*liveSource
+	for _, s := range liveSources.sources {
+		if now.Before(s.retryTime) {
+			unhealthy = append(unhealthy, s)
+		} else {
+			healthy = append(healthy, s)
+		}
+	}
+	liveSources.Unlock()
+
+	if len(healthy) == 0 {
+		healthy = unhealthy
+	}
+
+	var errs []string
+	for _, s := range healthy {
+		lts, err := getLiveTradingSessionsInChunks(ctx, s, symbols)
+
+		liveSources.Lock()
+		if err != nil {
+			s.failures++
+			backoff := time.Minute << uint(s.failures-1)
+			if backoff > maxLiveSourceBackoff || backoff <= 0 {
+				backoff = maxLiveSourceBackoff
+			}
+			s.retryTime = now.Add(backoff)
+		} else {
+			s.failures, s.retryTime = 0, time.Time{}
+		}
+		liveSources.Unlock()
+
+		if err == nil {
+			return lts, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		log.Printf("live source %s: %v", s.name, err)
+		errs = append(errs, fmt.Sprintf("%s: %v", s.name, err))
+	}
+	return nil, fmt.Errorf("all live sources failed: %s", strings.Join(errs, "; "))
+}
+
+// getLiveTradingSessionsInChunks gets the live trading sessions from the source with as few requests
+// as its limit on the symbols per request allows. It fails if any of the requests fail.
+func getLiveTradingSessionsInChunks(ctx context.Context, s *liveSource, symbols []string) ([]liveTradingSession, error) {
+	if s.maxSymbols <= 0 || len(symbols) <= s.maxSymbols {
+		return s.get(ctx, symbols)
+	}
+
+	var lts []liveTradingSession
+	for len(symbols) > 0 {
+		n := s.maxSymbols
+		if n > len(symbols) {
+			n = len(symbols)
+		}
+		chunk, err := s.get(ctx, symbols[:n])
+		if err != nil {
+			return nil, err
+		}
+		lts = append(lts, chunk...)
+		symbols = symbols[n:]
+	}
+	return lts, nil
+}
+
+// liveSourceLines returns lines describing the health of the live sources for the stats overlay.
+func liveSourceLines() []string {
+	liveSources.Lock()
+	defer liveSources.Unlock()
+
+	var lines []string
+	for _, s := range liveSources.sources {
+		status := "OK"
+		if s.failures > 0 {
+			status = fmt.Sprintf("%d failures, retry %s", s.failures, formatDisplayTime(s.retryTime, "3:04 PM"))
+		}
+		lines = append(lines, fmt.Sprintf("%-18s %s", s.name, status))
+	}
+	return lines
+}
+
+// yahooSymbols is a map from the index symbols used by Google to the ones used by Yahoo.
+var yahooSymbols = map[string]string{
+	dowSymbol:    "^DJI",
+	sapSymbol:    "^GSPC",
+	nasdaqSymbol: "^IXIC",
+}
+
+// yahooExchanges is a map from Yahoo exchange codes to the Google ones used by exchangeDelays.
+var yahooExchanges = map[string]string{
+	"NMS": "NASDAQ",
+	"NGM": "NASDAQ",
+	"NCM": "NASDAQ",
+	"NYQ": "NYSE",
+	"PCX": "NYSEARCA",
+	"ASE": "NYSEAMERICAN",
+	"BTS": "BATS",
+	"DJI": "INDEXDJX",
+	"SNP": "INDEXSP",
+	"NIM": "INDEXNASDAQ",
+	"TOR": "TSE",
+	"VAN": "CVE",
+	"LSE": "LON",
+	"PAR": "EPA",
+	"AMS": "AMS",
+	"FRA": "FRA",
+	"GER": "ETR",
+	"EBS": "SWX",
+	"JPX": "TYO",
+	"HKG": "HKG",
+	"ASX": "ASX",
+}
+
+func getLiveTradingSessionsFromYahoo(ctx context.Context, symbols []string) ([]liveTradingSession, error) {
+	// Map the Yahoo symbols in the response back to the requested ones.
+	ys, sm := providerSymbols(yahoo, symbols)
+
+	v := url.Values{}
+	v.Set("s", strings.Join(ys, ","))
+	v.Set("f", "sl1c1p2d1t1x") // symbol, price, change, percent change, date, time, exchange
+
+	u, err := url.Parse("http://download.finance.yahoo.com/d/quotes.csv")
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = v.Encode()
+	resp, err := httpGet(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var lts []liveTradingSession
+	r := csv.NewReader(resp.Body)
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		if len(record) != 7 {
+			return nil, fmt.Errorf("record length should be 7, got %d", len(record))
+		}
+
+		// Yahoo returns N/A for the fields of unknown symbols.
+		if record[1] == "N/A" {
+			continue
+		}
+
+		exchange, ok := yahooExchanges[record[6]]
+		if !ok {
+			exchange = record[6]
+		}
+
+		// Yahoo's times are in the exchange's time zone.
+		timestamp, err := time.ParseInLocation("1/2/2006 3:04pm", record[4]+" "+record[5], getExchangeHours(exchange).loc)
+		if err != nil {
+			return nil, fmt.Errorf("record: %q timestamp: %v", record, err)
+		}
+
+		price, err := parseFloat(record[1])
+		if err != nil {
+			return nil, fmt.Errorf("record: %q price: %v", record, err)
+		}
+
+		change, err := parseFloat(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("record: %q change: %v", record, err)
+		}
+
+		percentChange, err := parseFloat(strings.TrimSuffix(record[3], "%"))
+		if err != nil {
+			return nil, fmt.Errorf("record: %q percentChange: %v", record, err)
+		}
+
+		symbol, ok := sm[record[0]]
+		if !ok {
+			symbol = record[0]
+		}
+
+		lts = append(lts, liveTradingSession{
+			symbol:        symbol,
+			exchange:      exchange,
+			timestamp:     timestamp.UTC(),
+			price:         price,
+			change:        change,
+			percentChange: percentChange / 100.0,
+		})
+	}
+
+	if len(lts) == 0 {
+		return nil, errors.New("expected at least one entry")
+	}
+
+	return lts, nil
+}
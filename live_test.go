@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc is an http.RoundTripper that serves responses without the network.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestGetLiveTradingSessionsFromYahoo(t *testing.T) {
+	oldClient := httpClient
+	httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `"AAPL",499.23,-0.81,"-0.16%","8/28/2020","4:00pm","NMS"` + "\n" +
+			`"7203.T",6937.00,12.00,"+0.17%","8/28/2020","11:45pm","JPX"` + "\n"
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/csv"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})}
+	defer func() { httpClient = oldClient }()
+
+	lts, err := getLiveTradingSessionsFromYahoo(context.Background(), []string{"AAPL", "7203.T"})
+	if err != nil {
+		t.Fatalf("getLiveTradingSessionsFromYahoo() error = %v", err)
+	}
+	if len(lts) != 2 {
+		t.Fatalf("getLiveTradingSessionsFromYahoo() returned %d sessions, want 2", len(lts))
+	}
+
+	// The times are in each exchange's time zone, so a late Tokyo quote stays on its day there.
+	for i, want := range []time.Time{
+		time.Date(2020, 8, 28, 20, 0, 0, 0, time.UTC),
+		time.Date(2020, 8, 28, 14, 45, 0, 0, time.UTC),
+	} {
+		if got := lts[i].timestamp; !got.Equal(want) {
+			t.Errorf("%s timestamp = %v, want %v", lts[i].symbol, got, want)
+		}
+	}
+}
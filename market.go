@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// showIndexFutures is a flag to show index futures in the header outside market hours.
+var showIndexFutures = flag.Bool("index_futures", false, "Show index futures instead of the closing index values outside market hours.")
+
+// indexFutureSymbols is a map from index symbol to the symbol of its futures.
+var indexFutureSymbols = map[string]string{
+	dowSymbol:    "YM=F",
+	sapSymbol:    "ES=F",
+	nasdaqSymbol: "NQ=F",
+}
+
+// marketStatus is the phase of the US stock market's trading day.
+type marketStatus int
+
+// List of possible marketStatus values.
+const (
+	marketClosed marketStatus = iota
+	marketPre
+	marketOpen
+	marketAfter
+)
+
+// String returns the label shown in the header's market status badge.
+func (ms marketStatus) String() string {
+	switch ms {
+	case marketPre:
+		return "PRE-MARKET"
+	case marketOpen:
+		return "MARKET OPEN"
+	case marketAfter:
+		return "AFTER-HOURS"
+	default:
+		return "MARKET CLOSED"
+	}
+}
+
+// marketSession returns the times of the pre-market open, regular open, regular close, and after-hours close
+// on the day of the time in New York. It returns false if it is a weekend or holiday.
+func marketSession(t time.Time) (preOpen, open, close, afterClose time.Time, ok bool) {
+	ny := t.In(newYorkLoc)
+	switch ny.Weekday() {
+	case time.Saturday, time.Sunday:
+		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, false
+	}
+
+	at := func(hour, min int) time.Time {
+		return time.Date(ny.Year(), ny.Month(), ny.Day(), hour, min, 0, 0, newYorkLoc)
+	}
+
+	h, ok := getMarketHoliday(ny)
+	switch {
+	case ok && h.earlyClose:
+		return at(4, 0), at(9, 30), at(13, 0), at(17, 0), true
+	case ok:
+		return time.Time{}, time.Time{}, time.Time{}, time.Time{}, false
+	}
+	return at(4, 0), at(9, 30), at(16, 0), at(20, 0), true
+}
+
+// getMarketStatus returns the market status at the time and when the regular session next opens or closes.
+func getMarketStatus(t time.Time) (status marketStatus, next time.Time) {
+	preOpen, open, close, afterClose, ok := marketSession(t)
+	switch {
+	case ok && !t.Before(open) && t.Before(close):
+		return marketOpen, close
+	case ok && !t.Before(preOpen) && t.Before(open):
+		return marketPre, open
+	case ok && t.Before(open):
+		return marketClosed, open
+	case ok && !t.Before(close) && t.Before(afterClose):
+		status = marketAfter
+	}
+
+	// Find the next day that the market opens. Give up after a couple weeks to avoid looping forever.
+	ny := t.In(newYorkLoc)
+	for i := 1; i <= 14; i++ {
+		day := time.Date(ny.Year(), ny.Month(), ny.Day()+i, 12, 0, 0, 0, newYorkLoc)
+		if _, open, _, _, ok := marketSession(day); ok {
+			return status, open
+		}
+	}
+	return status, time.Time{}
+}
+
+// isMarketHours returns whether the US stock market's regular session is open at the time.
+func isMarketHours(t time.Time) bool {
+	status, _ := getMarketStatus(t)
+	return status == marketOpen
+}
+
+// marketCountdown returns text like "closes in 2h13m" or "opens in 15h0m" for the header.
+func marketCountdown(status marketStatus, next, now time.Time) string {
+	if next.IsZero() {
+		return ""
+	}
+
+	d := next.Sub(now).Truncate(time.Minute)
+	var s string
+	if d >= 24*time.Hour {
+		s = fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	} else {
+		s = fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	if status == marketOpen {
+		return "closes in " + s
+	}
+	return "opens in " + s
+}
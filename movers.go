@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nsf/termbox-go"
+)
+
+// mover is a stock and its latest trading session in the top movers view.
+type mover struct {
+	symbol string
+	ts     stockTradingSession
+}
+
+// topMovers returns up to n of the stocks that gained and lost the most by their latest
+// session's percent change, from the biggest move to the smallest.
+func topMovers(stocks []stock, n int) (gainers, losers []mover) {
+	for _, s := range stocks {
+		ts, ok := latestTradingSession(s.tradingSessionMap)
+		if !ok {
+			continue
+		}
+		switch {
+		case ts.percentChange > 0:
+			gainers = append(gainers, mover{s.symbol, ts})
+		case ts.percentChange < 0:
+			losers = append(losers, mover{s.symbol, ts})
+		}
+	}
+
+	sort.SliceStable(gainers, func(i, j int) bool {
+		return gainers[i].ts.percentChange > gainers[j].ts.percentChange
+	})
+	sort.SliceStable(losers, func(i, j int) bool {
+		return losers[i].ts.percentChange < losers[j].ts.percentChange
+	})
+
+	if len(gainers) > n {
+		gainers = gainers[:n]
+	}
+	if len(losers) > n {
+		losers = losers[:n]
+	}
+	return gainers, losers
+}
+
+// printMovers prints the gainers and then the losers and highlights the selected one,
+// which is an index into the gainers followed by the losers.
+func printMovers(gainers, losers []mover, selected, w, h int) {
+	print := func(x, y int, fg, bg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, bg)
+			x++
+		}
+		return x
+	}
+
+	const format = " %-8s %10s %10s %9s "
+
+	print(0, 0, termbox.ColorDefault, termbox.ColorDefault, " Top movers by latest percent change")
+
+	y, i := 2, 0
+	printGroup := func(title string, ms []mover, fg termbox.Attribute) {
+		print(0, y, termbox.ColorDefault|termbox.AttrBold, termbox.ColorDefault, " %s", title)
+		y++
+		if len(ms) == 0 {
+			print(0, y, termbox.ColorDefault, termbox.ColorDefault, " None")
+			y++
+		}
+		for _, m := range ms {
+			bg := termbox.ColorDefault
+			if i == selected {
+				bg = termbox.ColorBlue
+			}
+			x := print(0, y, termbox.ColorDefault, bg, " %-8s %10.2f ", m.symbol, m.ts.close)
+			print(x, y, fg, bg, "%+10.2f %+8.2f%% ", m.ts.change, m.ts.percentChange*100)
+			y++
+			i++
+		}
+		y++
+	}
+	printGroup("Gainers", gainers, upColor)
+	printGroup("Losers", losers, downColor)
+
+	print(0, h-1, termbox.ColorDefault, termbox.ColorDefault, " Up/Down: Select  Enter: Jump to row  F12: Close")
+}
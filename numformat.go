@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// numberFormat is how a locale separates the thousands and the decimals of numbers.
+type numberFormat struct {
+	// group separates each group of three digits like the comma in 34,123.45.
+	group string
+
+	// decimal separates the decimals like the period in 34,123.45.
+	decimal string
+}
+
+// localeNumberFormats is a map from locale like "de-CH" or language like "de" to its number format.
+// Spaces are used instead of the narrow no-break spaces of some locales to keep the columns aligned.
+var localeNumberFormats = map[string]numberFormat{
+	"en":    {",", "."},
+	"ja":    {",", "."},
+	"ko":    {",", "."},
+	"zh":    {",", "."},
+	"de":    {".", ","},
+	"es":    {".", ","},
+	"it":    {".", ","},
+	"nl":    {".", ","},
+	"pt":    {".", ","},
+	"da":    {".", ","},
+	"tr":    {".", ","},
+	"fr":    {" ", ","},
+	"sv":    {" ", ","},
+	"fi":    {" ", ","},
+	"nb":    {" ", ","},
+	"pl":    {" ", ","},
+	"cs":    {" ", ","},
+	"ru":    {" ", ","},
+	"de-CH": {"'", "."},
+	"fr-CH": {"'", "."},
+	"it-CH": {"'", "."},
+}
+
+// displayNumberFormat is the number format that prices are shown in.
+var displayNumberFormat = localeNumberFormats["en"]
+
+// setNumberLocale sets the number format to the locale's like "de-DE" or "de_DE.UTF-8".
+// It keeps the English format if the locale is empty or unknown.
+func setNumberLocale(locale string) {
+	if locale == "" {
+		return
+	}
+
+	tag := strings.Replace(strings.SplitN(locale, ".", 2)[0], "_", "-", -1)
+	if nf, ok := localeNumberFormats[tag]; ok {
+		displayNumberFormat = nf
+		return
+	}
+	if nf, ok := localeNumberFormats[strings.ToLower(strings.SplitN(tag, "-", 2)[0])]; ok {
+		displayNumberFormat = nf
+	}
+}
+
+// formatNumber formats the number with the decimal places and the display number format like 34,123.45.
+func formatNumber(v float64, precision int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', precision, 64)
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteString("-")
+	}
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(displayNumberFormat.group)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(displayNumberFormat.decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// fitNumber drops the thousands separators of the formatted number if it is wider than the column.
+func fitNumber(s string, width int) string {
+	if len([]rune(s)) > width {
+		return strings.Replace(s, displayNumberFormat.group, "", -1)
+	}
+	return s
+}
+
+// formatChange formats the number like formatNumber but with a plus sign if it isn't negative like +1,234.50.
+func formatChange(v float64, precision int) string {
+	s := formatNumber(v, precision)
+	if !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+	return s
+}
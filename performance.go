@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// defaultPeriods are the performance periods shown when the config has none.
+var defaultPeriods = []string{"YTD"}
+
+// maxPeriods is the number of performance periods that fit in a stock's row.
+const maxPeriods = 4
+
+// displayPeriods returns the configured performance periods that fit in a stock's row.
+func displayPeriods(periods []string) []string {
+	if len(periods) == 0 {
+		return defaultPeriods
+	}
+	if len(periods) > maxPeriods {
+		return periods[:maxPeriods]
+	}
+	return periods
+}
+
+// periodStart returns the date whose close the period's change is measured from.
+// The label is "YTD" or the label of a preset chart range like "3M".
+func periodStart(label string, end time.Time) (time.Time, bool) {
+	if label == "YTD" {
+		// Measure from the last close of the previous year.
+		return time.Date(end.Year(), time.January, 1, 0, 0, 0, 0, end.Location()).AddDate(0, 0, -1), true
+	}
+	if cr, ok := findChartRange(label); ok && cr.months != 0 {
+		return cr.start(end), true
+	}
+	return time.Time{}, false
+}
+
+// periodChange returns the percent change from the close on or before the period's start to the latest close.
+// It returns false if the history does not go back far enough.
+func periodChange(tsm map[time.Time]stockTradingSession, label string, end time.Time) (float64, bool) {
+	start, ok := periodStart(label, end)
+	if !ok {
+		return 0, false
+	}
+
+	latest, ok := latestTradingSession(tsm)
+	if !ok {
+		return 0, false
+	}
+
+	var base stockTradingSession
+	for date, ts := range tsm {
+		if !date.After(start) && date.After(base.date) {
+			base = ts
+		}
+	}
+	if base.date.IsZero() || base.close == 0 {
+		return 0, false
+	}
+	return (latest.close - base.close) / base.close, true
+}
+
+// backfillPeriods fetches enough history for the stocks to show the changes over the performance periods.
+func backfillPeriods(ctx context.Context, sd *stockData) {
+	end := chartToday()
+
+	sd.RLock()
+	start := end
+	for _, label := range displayPeriods(sd.periods) {
+		if ps, ok := periodStart(label, end); ok && ps.Before(start) {
+			start = ps
+		}
+	}
+	var symbols []string
+	for _, s := range sd.stocks {
+		symbols = append(symbols, s.symbol)
+	}
+	sd.RUnlock()
+
+	// Go back an extra week to find a close on or before the start when it is a holiday or weekend.
+	start = start.AddDate(0, 0, -7)
+
+	for _, symbol := range symbols {
+		backfillStockData(ctx, sd, symbol, start, end)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// maxPluginSteps limits how long a plugin's top-level code or a call to one of its functions can run.
+const maxPluginSteps = 1000000
+
+// pluginContextKey is the thread local key of the context of a sessions function's request.
+const pluginContextKey = "ctx"
+
+// plugin is a user's Starlark script that computes a value shown in the performance column for each stock
+// or gets the trading sessions of the stocks that use it as their data source.
+type plugin struct {
+	// name is the label shown next to the value and the name of the data source.
+	// Only the first three letters fit next to the value.
+	name string
+
+	// column is the script's column function or nil if it has none.
+	column starlark.Value
+
+	// sessions is the script's sessions function or nil if it is not a data source.
+	sessions starlark.Value
+}
+
+// pluginSources is a map from data source name to the plugin with a sessions function.
+// It is set when the plugins are loaded and read by the refresh goroutines.
+var pluginSources struct {
+	sync.RWMutex
+	m map[tradingSessionSource]plugin
+}
+
+// setPluginSources makes the plugins with sessions functions available as data sources.
+// The built-in sources keep their names if a plugin has the same name.
+func setPluginSources(ps []plugin) {
+	m := map[tradingSessionSource]plugin{}
+	for _, p := range ps {
+		if p.sessions == nil {
+			continue
+		}
+		if _, ok := sourceCapabilityMatrix[tradingSessionSource(p.name)]; ok || p.name == string(random) {
+			log.Printf("plugin %s: source name already used by a built-in source", p.name)
+			continue
+		}
+		m[tradingSessionSource(p.name)] = p
+	}
+
+	pluginSources.Lock()
+	pluginSources.m = m
+	pluginSources.Unlock()
+}
+
+// getPluginSource returns the plugin that is the data source with the name.
+func getPluginSource(source tradingSessionSource) (plugin, bool) {
+	pluginSources.RLock()
+	defer pluginSources.RUnlock()
+	p, ok := pluginSources.m[source]
+	return p, ok
+}
+
+// pluginValue is a plugin's computed value for a stock.
+type pluginValue struct {
+	// name is the plugin's name.
+	name string
+
+	// text is the formatted value.
+	text string
+}
+
+// getPluginDir returns the directory of the user's plugin scripts.
+func getPluginDir() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(u.HomeDir, ".ponzi.d", "plugins"), nil
+}
+
+// loadPlugins loads the *.star scripts in the plugin directory in name order.
+// Scripts that fail to load are logged and skipped.
+func loadPlugins() []plugin {
+	dir, err := getPluginDir()
+	if err != nil {
+		log.Printf("getPluginDir: %v", err)
+		return nil
+	}
+
+	files, err := filepath.Glob(path.Join(dir, "*.star"))
+	if err != nil {
+		log.Printf("filepath.Glob: %v", err)
+		return nil
+	}
+
+	var ps []plugin
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Printf("ioutil.ReadFile: %v", err)
+			continue
+		}
+
+		p, err := loadPlugin(file, src)
+		if err != nil {
+			log.Printf("loadPlugin: %v", err)
+			continue
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// loadPlugin runs the script and returns the plugin defined by it. The script may define:
+//
+// A column(symbol, sessions) function that returns a number or string to show for each stock.
+// The sessions are dicts with date, close, volume, change, and percent_change with the most recent last.
+//
+// A sessions(symbol, start, end) function that makes the plugin a data source named after it.
+// It returns a list of dicts with date, open, high, low, close, and volume between the dates.
+// The dates are strings like 2006-01-02. It can call http_get(url) to get the body of a response.
+//
+// A name to show and use as the data source's name instead of the file's name.
+func loadPlugin(file string, src []byte) (plugin, error) {
+	// Limit the top-level code too, so a script that loops forever can't hang the startup.
+	thread := &starlark.Thread{Name: file}
+	thread.SetMaxExecutionSteps(maxPluginSteps)
+
+	globals, err := starlark.ExecFile(thread, file, src, starlark.StringDict{
+		"http_get": starlark.NewBuiltin("http_get", pluginHTTPGet),
+	})
+	if err != nil {
+		return plugin{}, err
+	}
+	globals.Freeze()
+
+	p := plugin{
+		name:     strings.TrimSuffix(path.Base(file), ".star"),
+		column:   globals["column"],
+		sessions: globals["sessions"],
+	}
+	if v, ok := globals["name"]; ok {
+		if s, ok := starlark.AsString(v); ok {
+			p.name = s
+		}
+	}
+	if p.column == nil && p.sessions == nil {
+		return plugin{}, fmt.Errorf("plugin %s: no column or sessions function", file)
+	}
+	return p, nil
+}
+
+// pluginHTTPGet is the http_get(url) builtin that returns the body of the response as a string.
+// It is only available while getting trading sessions, since it needs the request's context.
+func pluginHTTPGet(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var u string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &u); err != nil {
+		return nil, err
+	}
+
+	ctx, ok := thread.Local(pluginContextKey).(context.Context)
+	if !ok {
+		return nil, fmt.Errorf("%s: only available in the sessions function", b.Name())
+	}
+
+	resp, err := httpGet(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.String(body), nil
+}
+
+// getTradingSessions calls the plugin's sessions function. It returns the most recent trading sessions
+// at the front without the anomalous ones like the other sources.
+func (p plugin) getTradingSessions(ctx context.Context, symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
+	thread := &starlark.Thread{Name: p.name}
+	thread.SetMaxExecutionSteps(maxPluginSteps)
+	thread.SetLocal(pluginContextKey, ctx)
+
+	// Stop the script if the request is canceled while it runs.
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	args := starlark.Tuple{
+		starlark.String(symbol),
+		starlark.String(startDate.Format("2006-01-02")),
+		starlark.String(endDate.Format("2006-01-02")),
+	}
+	v, err := starlark.Call(thread, p.sessions, args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tss, err := pluginTradingSessions(v)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s(%s): %v", p.name, symbol, err)
+	}
+	return tss, nil
+}
+
+// pluginTradingSessions converts the list of dicts returned by a sessions function to trading sessions.
+func pluginTradingSessions(v starlark.Value) ([]tradingSession, error) {
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("got %s, want a list of dicts", v.Type())
+	}
+
+	var tss []tradingSession
+	iter := iterable.Iterate()
+	defer iter.Done()
+
+	var x starlark.Value
+	for iter.Next(&x) {
+		d, ok := x.(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("got %s, want a dict", x.Type())
+		}
+
+		var ts tradingSession
+		dv, _, err := d.Get(starlark.String("date"))
+		if err != nil {
+			return nil, err
+		}
+		date, ok := starlark.AsString(dv)
+		if !ok {
+			return nil, errors.New("session without a date")
+		}
+		if ts.date, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, err
+		}
+
+		var volume float64
+		for _, f := range []struct {
+			key   string
+			field *float64
+		}{
+			{"open", &ts.open},
+			{"high", &ts.high},
+			{"low", &ts.low},
+			{"close", &ts.close},
+			{"volume", &volume},
+		} {
+			fv, found, err := d.Get(starlark.String(f.key))
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				continue
+			}
+			n, ok := starlark.AsFloat(fv)
+			if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, fmt.Errorf("session %s: bad %s: %s", date, f.key, fv)
+			}
+			*f.field = n
+		}
+		ts.volume = int64(volume)
+
+		tss = append(tss, ts)
+	}
+
+	// Most recent trading sessions at the front.
+	sort.Sort(sort.Reverse(sortableTradingSessions(tss)))
+
+	return dropAnomalousSessions(tss), nil
+}
+
+// runPlugins computes the plugins' values for the stocks.
+func runPlugins(sd *stockData) {
+	sd.RLock()
+	ps := sd.plugins
+	stocks := map[string]starlark.Value{}
+	if len(ps) > 0 {
+		for _, s := range sd.stocks {
+			var sessions []starlark.Value
+			for _, ts := range chartTradingSessions(s.tradingSessionMap, time.Time{}, chartToday()) {
+				d := starlark.NewDict(5)
+				d.SetKey(starlark.String("date"), starlark.String(ts.date.Format("2006-01-02")))
+				d.SetKey(starlark.String("close"), starlark.Float(ts.close))
+				d.SetKey(starlark.String("volume"), starlark.MakeInt64(ts.volume))
+				d.SetKey(starlark.String("change"), starlark.Float(ts.change))
+				d.SetKey(starlark.String("percent_change"), starlark.Float(ts.percentChange))
+				sessions = append(sessions, d)
+			}
+
+			// Freeze the sessions, so a plugin can't change what the next plugin sees.
+			l := starlark.NewList(sessions)
+			l.Freeze()
+			stocks[s.symbol] = l
+		}
+	}
+	sd.RUnlock()
+
+	if len(ps) == 0 {
+		return
+	}
+
+	values := map[string][]pluginValue{}
+	for symbol, sessions := range stocks {
+		for _, p := range ps {
+			if p.column == nil {
+				continue
+			}
+
+			thread := &starlark.Thread{Name: p.name}
+			thread.SetMaxExecutionSteps(maxPluginSteps)
+
+			v, err := starlark.Call(thread, p.column, starlark.Tuple{starlark.String(symbol), sessions}, nil)
+			if err != nil {
+				log.Printf("plugin %s(%s): %v", p.name, symbol, err)
+				values[symbol] = append(values[symbol], pluginValue{p.name, "ERR"})
+				continue
+			}
+			values[symbol] = append(values[symbol], pluginValue{p.name, formatPluginValue(v)})
+		}
+	}
+
+	sd.Lock()
+	for i, s := range sd.stocks {
+		if pvs, ok := values[s.symbol]; ok {
+			sd.stocks[i].pluginValues = pvs
+		}
+	}
+	sd.Unlock()
+}
+
+// formatPluginValue formats numbers with two decimal places and shows strings as they are.
+func formatPluginValue(v starlark.Value) string {
+	if s, ok := starlark.AsString(v); ok {
+		return s
+	}
+	if f, ok := starlark.AsFloat(v); ok {
+		return fmt.Sprintf("%.2f", f)
+	}
+	return v.String()
+}
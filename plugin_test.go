@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+func TestLoadPlugin(t *testing.T) {
+	for _, tt := range []struct {
+		desc         string
+		src          string
+		wantName     string
+		wantColumn   bool
+		wantSessions bool
+		wantErr      string
+	}{
+		{
+			desc:       "column function",
+			src:        "def column(symbol, sessions):\n  return 1\n",
+			wantName:   "test",
+			wantColumn: true,
+		},
+		{
+			desc:         "named sessions function",
+			src:          "name = 'csv'\ndef sessions(symbol, start, end):\n  return []\n",
+			wantName:     "csv",
+			wantSessions: true,
+		},
+		{
+			desc:    "no functions",
+			src:     "x = 1\n",
+			wantErr: "no column or sessions function",
+		},
+		{
+			desc:    "top-level code that never ends",
+			src:     "def spin():\n  for x in range(1000000000):\n    pass\nspin()\n",
+			wantErr: "too many steps",
+		},
+		{
+			desc:    "http_get at the top level",
+			src:     "body = http_get('http://localhost/')\n",
+			wantErr: "only available in the sessions function",
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			p, err := loadPlugin("test.star", []byte(tt.src))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadPlugin() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadPlugin() error = %v", err)
+			}
+			if p.name != tt.wantName {
+				t.Errorf("name = %q, want %q", p.name, tt.wantName)
+			}
+			if got := p.column != nil; got != tt.wantColumn {
+				t.Errorf("has column = %t, want %t", got, tt.wantColumn)
+			}
+			if got := p.sessions != nil; got != tt.wantSessions {
+				t.Errorf("has sessions = %t, want %t", got, tt.wantSessions)
+			}
+		})
+	}
+}
+
+func TestPluginGetTradingSessions(t *testing.T) {
+	p, err := loadPlugin("test.star", []byte(`
+def sessions(symbol, start, end):
+  return [
+    {"date": start, "open": 10, "high": 12, "low": 9, "close": 11, "volume": 100},
+    {"date": end, "open": 11, "high": 13, "low": 10.5, "close": 12.5, "volume": 200},
+  ]
+`))
+	if err != nil {
+		t.Fatalf("loadPlugin() error = %v", err)
+	}
+
+	start := time.Date(2020, 8, 27, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC)
+	tss, err := p.getTradingSessions(context.Background(), "AAPL", start, end)
+	if err != nil {
+		t.Fatalf("getTradingSessions() error = %v", err)
+	}
+	if len(tss) != 2 {
+		t.Fatalf("getTradingSessions() returned %d sessions, want 2", len(tss))
+	}
+
+	// The most recent session is at the front like the other sources.
+	want := tradingSession{date: end, open: 11, high: 13, low: 10.5, close: 12.5, volume: 200}
+	if tss[0] != want {
+		t.Errorf("latest session = %+v, want %+v", tss[0], want)
+	}
+	checkSessions(t, tss)
+}
+
+func TestPluginTradingSessions(t *testing.T) {
+	for _, tt := range []struct {
+		desc    string
+		expr    string
+		wantErr bool
+	}{
+		{"empty list", `[]`, false},
+		{"not a list", `"2020-08-28"`, true},
+		{"not a dict", `[1]`, true},
+		{"no date", `[{"close": 1}]`, true},
+		{"bad date", `[{"date": "08/28/2020", "close": 1}]`, true},
+		{"string price", `[{"date": "2020-08-28", "close": "1"}]`, true},
+		{"infinite price", `[{"date": "2020-08-28", "close": float("inf")}]`, true},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			v, err := starlark.Eval(&starlark.Thread{}, "test", tt.expr, nil)
+			if err != nil {
+				t.Fatalf("starlark.Eval: %v", err)
+			}
+			_, err = pluginTradingSessions(v)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("pluginTradingSessions() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunPluginsFreezesSessions(t *testing.T) {
+	useClock(t, newFakeClock(time.Date(2020, 8, 28, 17, 0, 0, 0, newYorkLoc)))
+
+	var ps []plugin
+	for _, src := range []string{
+		"name = 'add'\ndef column(symbol, sessions):\n  sessions.append({})\n  return len(sessions)\n",
+		"name = 'len'\ndef column(symbol, sessions):\n  return len(sessions)\n",
+	} {
+		p, err := loadPlugin("test.star", []byte(src))
+		if err != nil {
+			t.Fatalf("loadPlugin() error = %v", err)
+		}
+		ps = append(ps, p)
+	}
+
+	sd := fixtureStockData()
+	sd.plugins = ps
+	runPlugins(sd)
+
+	// The first plugin can't append and the second sees the unchanged sessions.
+	want := []pluginValue{{"add", "ERR"}, {"len", "5.00"}}
+	got := sd.stocks[0].pluginValues
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("pluginValues = %v, want %v", got, want)
+	}
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"math"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
-	"unicode"
 
 	"github.com/nsf/termbox-go"
 )
@@ -19,14 +20,29 @@ var (
 	// dataSource is a flag to set what data source to use.
 	dataSource = flag.String("data_source", string(google), "Data source to get quotes. Values: google, yahoo")
 
-	// getTradingSessions is the tradingSessionFunc set by the dataSource flag.
-	getTradingSessions tradingSessionFunc
+	// importPositionsPath is a flag to import positions from a broker statement and exit.
+	importPositionsPath = flag.String("import_positions", "", "Path of a broker CSV statement to import positions from before exiting.")
+
+	// importBroker is a flag to set the format of the statement to import.
+	importBroker = flag.String("import_broker", string(fidelity), "Format of the -import_positions statement. Values: fidelity, schwab, ibkr")
+
+	// realizedGainsCSV is a flag to export the realized gains report and exit.
+	realizedGainsCSV = flag.String("realized_gains_csv", "", "Path to export the realized gains report as CSV before exiting. Use - for stdout.")
+
+	// realizedGainsYear is a flag to set the tax year of the exported realized gains report.
+	realizedGainsYear = flag.Int("realized_gains_year", 0, "Tax year of the -realized_gains_csv report. Zero exports all years.")
+
+	// readOnly is a flag to prevent changes to the watchlist and the config.
+	readOnly = flag.Bool("readonly", false, "Disable adding, deleting, and reordering stocks, trading, and writing the config.")
 )
 
 const (
 	// symbolColumnWidth is the width of the leftmost column with the symbols.
 	symbolColumnWidth = 5
 
+	// perfColumnWidth is the width of the column with the changes over the performance periods.
+	perfColumnWidth = 10
+
 	// tsColumnWidth is the width of the columns that have trading session data.
 	tsColumnWidth = 8
 
@@ -41,7 +57,7 @@ const (
 )
 
 var (
-	// positiveColors are background colors for positive price changes. Requires 256 colors.
+	// positiveColors are background colors for positive price changes. Requires 256 or 24-bit colors.
 	positiveColors = [colorCount]termbox.Attribute{
 		termbox.Attribute(23),
 		termbox.Attribute(29),
@@ -50,7 +66,7 @@ var (
 		termbox.Attribute(47),
 	}
 
-	// negativeColors are background colors for negative price changes. Requires 256 colors.
+	// negativeColors are background colors for negative price changes. Requires 256 or 24-bit colors.
 	negativeColors = [colorCount]termbox.Attribute{
 		termbox.Attribute(53),
 		termbox.Attribute(89),
@@ -68,7 +84,7 @@ var (
 		0.5,
 	}
 
-	// weekdayColors are background colors for the weekdays. Requires 256 colors.
+	// weekdayColors are background colors for the weekdays. Requires 256 or 24-bit colors.
 	weekdayColors = map[time.Weekday]termbox.Attribute{
 		time.Monday:    termbox.Attribute(233),
 		time.Tuesday:   termbox.Attribute(234),
@@ -97,6 +113,70 @@ type stockData struct {
 	dow    stockTradingSession
 	sap    stockTradingSession
 	nasdaq stockTradingSession
+
+	// sectorETFs are the config's symbols of the sector strip.
+	sectorETFs []string
+
+	// sectorETFSessions is a map from sector strip symbol to its live trading session.
+	sectorETFSessions map[string]stockTradingSession
+
+	// futures is a map from index symbol to its futures' session if shown outside market hours.
+	futures map[string]stockTradingSession
+
+	// account is the user's Alpaca account or nil if not available.
+	account *alpacaAccount
+
+	// positions is a map from symbol to the user's Alpaca position.
+	positions map[string]alpacaPosition
+
+	// exchanges is a map from stock and index symbols to their exchange.
+	exchanges map[string]string
+
+	// layouts are the user's saved chart layouts.
+	layouts []chartLayout
+
+	// timeZone is the name of the display time zone from the config.
+	timeZone string
+
+	// periods are the labels of the performance periods from the config like "YTD" or "3M".
+	periods []string
+
+	// quoteURL is the URL template from the config of the page to open for a symbol.
+	quoteURL string
+
+	// hooks are the commands from the config to run when events happen.
+	hooks []hook
+
+	// configHooks are the hooks as they appear in the config to save them as is.
+	configHooks []configHook
+
+	// alpacaKeyID, alpacaSecretKey, and alpacaURL are the Alpaca settings from the config.
+	alpacaKeyID, alpacaSecretKey, alpacaURL string
+
+	// symbolMappings are the config's maps from source to display symbol to the source's symbol.
+	symbolMappings map[string]map[string]string
+
+	// econEvents are the economic events from the config.
+	econEvents []configEconEvent
+
+	// locale is the config's locale for formatting numbers.
+	locale string
+
+	// assetPrecisions is the config's map from asset class to the decimal places of its prices.
+	assetPrecisions map[string]int
+
+	// historyRetentionDays is how many days of sessions to keep in the history database or zero to keep them all.
+	historyRetentionDays int
+
+	// sales are the realized sales of all the stocks. They are kept apart from the stocks,
+	// so deleting a stock from the watchlist keeps its tax history.
+	sales []sale
+
+	// plugins are the user's scripts that compute values to show for each stock.
+	plugins []plugin
+
+	// benchmark is the stock that betas are calculated against.
+	benchmark stock
 }
 
 var (
@@ -113,10 +193,70 @@ var (
 type stock struct {
 	symbol            string
 	tradingSessionMap map[time.Time]stockTradingSession
+
+	// lots are the user's lots of the stock.
+	lots []lot
+
+	// dividends are the stock's manually entered dividends.
+	dividends []dividend
+
+	// fetchedDividends are the stock's dividends from the network.
+	fetchedDividends []dividend
+
+	// historyStart is the earliest start date of the fetched trading sessions.
+	historyStart time.Time
+
+	// historyEnd is the end date of the latest refresh of the trading sessions.
+	// Later refreshes only fetch the sessions after it.
+	historyEnd time.Time
+
+	// updateTime is when the stock's trading sessions were last fetched.
+	updateTime time.Time
+
+	// cached is whether the trading sessions are from the disk cache and have not been refreshed yet.
+	cached bool
+
+	// pluginValues are the values computed by the plugins after the last refresh.
+	pluginValues []pluginValue
+
+	// pinned is whether the stock is a favorite that stays at the top of the grid while scrolling.
+	// Pinned stocks are always before the unpinned ones.
+	pinned bool
+
+	// source is the data source of the stock's trading sessions or empty to use the default source.
+	source tradingSessionSource
+
+	// sector and industry are the stock's classification or empty if it has none.
+	sector, industry string
+
+	// filings are the stock's latest SEC filings from the most recent.
+	filings []secFiling
+
+	// analyst is the analysts' consensus rating and price target or nil if not fetched.
+	analyst *analystSummary
+
+	// fetchError is the error of the last refresh of the stock's trading sessions or empty if it succeeded.
+	fetchError string
+
+	// volatility is the standard deviation of the percent changes of the stock's recent sessions.
+	// It is updated when the sessions are loaded or refreshed rather than at each repaint.
+	volatility float64
+
+	// splits are the stock's confirmed splits, which its sessions are adjusted for.
+	splits []stockSplit
+
+	// checkedSplits is the set of dates of the jumps that were checked against the provider's split events.
+	checkedSplits map[time.Time]bool
+
+	// precision is the number of decimal places to show the stock's prices with or zero to use its asset class's.
+	precision int
 }
 
 type stockTradingSession struct {
 	date          time.Time
+	open          float64
+	high          float64
+	low           float64
 	close         float64
 	volume        int64
 	change        float64
@@ -128,400 +268,464 @@ func main() {
 
 	// Parse flags. Do initialization that we can do before termbox starts.
 	flag.Parse()
+	setClock()
 
-	getTradingSessions, err = getTradingSessionFunc(tradingSessionSource(*dataSource))
-	if err != nil {
-		log.Fatalf("getTradingSessionFunc: %v", err)
-	}
+	// ctx is canceled when main returns to stop any requests still in flight.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	// Redirect the logger since termbox will cover the screen.
-	logFile, err := initLogger()
-	if err != nil {
-		log.Fatalf("initLogger: %v", err)
+	if err := setDefaultSource(tradingSessionSource(*dataSource)); err != nil {
+		log.Fatalf("setDefaultSource: %v", err)
 	}
-	defer logFile.Close()
 
-	// Try to initialize termbox now.
-	if err := termbox.Init(); err != nil {
-		log.Fatalf("termbox.Init: %v", err)
+	if *checkFilings {
+		if _, err := edgarUserAgent(*edgarEmail); err != nil {
+			log.Fatalf("edgarUserAgent: %v", err)
+		}
 	}
-	defer termbox.Close()
-
-	// Set to InputAlt so that ESC + Key enables the ModAlt flag for EventKey events.
-	// ModAlt does not mean the ALT key as typically expected.
-	termbox.SetInputMode(termbox.InputAlt)
 
-	// Attempt to enable 256 color mode.
-	has256Colors := termbox.SetOutputMode(termbox.Output256) == termbox.Output256
-
-	cfg, err := loadConfig()
-	if err != nil {
-		log.Fatalf("loadConfig: %v", err)
+	// Prune the history database and exit without starting termbox.
+	if *pruneHistory {
+		cfg, err := loadConfig()
+		if err != nil {
+			log.Fatalf("loadConfig: %v", err)
+		}
+		if err := pruneHistoryFiles(newStockData(cfg)); err != nil {
+			log.Fatalf("pruneHistoryFiles: %v", err)
+		}
+		return
 	}
 
-	sd := &stockData{}
-	for _, cs := range cfg.Stocks {
-		sd.stocks = append(sd.stocks, stock{
-			symbol: cs.Symbol,
-		})
+	// Import downloaded sessions into the history database and exit without starting termbox.
+	if *importHistoryPath != "" {
+		n, err := importHistory(*importHistoryPath)
+		if err != nil {
+			log.Fatalf("importHistory: %v", err)
+		}
+		fmt.Printf("Imported %d files.\n", n)
+		return
 	}
 
-	// Launch a go routine to periodically refresh the stock data.
-	go func() {
-		// refreshDuration is the duration until the next refresh.
-		var refreshDuration time.Duration
+	// Import positions and exit without starting termbox.
+	if *importPositionsPath != "" {
+		ps, err := importPositions(*importPositionsPath, brokerFormat(*importBroker))
+		if err != nil {
+			log.Fatalf("importPositions: %v", err)
+		}
+		for _, p := range ps {
+			fmt.Printf("%-8s %12.4f %12.2f\n", p.symbol, p.quantity, p.cost)
+		}
+		fmt.Printf("Imported %d positions.\n", len(ps))
+		return
+	}
 
-		// getNextRefreshDuration returns a duration from now till the next refresh.
-		getNextRefreshDuration := func() time.Duration {
-			// Refresh at the top of the hour to be predictable.
-			now := time.Now()
-			nextRefreshTime := now.Add(1 * time.Hour).Truncate(time.Hour)
-			return nextRefreshTime.Sub(now)
+	// Export the realized gains and exit without starting termbox.
+	if *realizedGainsCSV != "" {
+		cfg, err := loadConfig()
+		if err != nil {
+			log.Fatalf("loadConfig: %v", err)
 		}
 
-		// refresh refreshes the stock data, repaints the screen, and calculates the next duration.
-		refresh := func() {
-			refreshStockData(sd, "")
+		file := os.Stdout
+		if *realizedGainsCSV != "-" {
+			file, err = os.Create(*realizedGainsCSV)
+			if err != nil {
+				log.Fatalf("os.Create: %v", err)
+			}
+			defer file.Close()
+		}
 
-			// Signal termbox to repaint by queuing an interrupt event.
-			termbox.Interrupt()
+		if err := writeRealizedGainsCSV(file, newStockData(cfg).sales, *realizedGainsYear); err != nil {
+			log.Fatalf("writeRealizedGainsCSV: %v", err)
+		}
+		return
+	}
 
-			// Calculate the next refresh duration at the top of the hour.
-			refreshDuration = getNextRefreshDuration()
+	// Print the stocks as plain text and exit without starting termbox.
+	if *plain {
+		// Keep the log output out of what the screen reader reads.
+		logFile, err := initLogger(logFileName)
+		if err != nil {
+			log.Fatalf("initLogger: %v", err)
 		}
+		defer logFile.Close()
 
-		// Do an initial refresh of the data.
-		refresh()
+		cfg, err := loadConfig()
+		if err != nil {
+			log.Fatalf("loadConfig: %v", err)
+		}
 
-		// Loop forever and perodically refresh.
-		for {
-			select {
-			case <-time.After(refreshDuration):
-				refresh()
-			}
+		setDisplayTimeZone(cfg.TimeZone)
+		setNumberLocale(cfg.Locale)
+		setAlpacaCredentials(cfg.AlpacaKeyID, cfg.AlpacaSecretKey, cfg.AlpacaURL)
+		setSymbolMappings(cfg.SymbolMappings)
+		setEconEvents(cfg.Events)
+		sd := newStockData(cfg)
+		if err := loadCache(sd); err != nil {
+			log.Printf("loadCache: %v", err)
 		}
-	}()
+		refreshStockData(ctx, sd, "")
 
-	// Variables and functions to set colors and print to the screen.
-	var (
-		fg, bg termbox.Attribute
+		sd.RLock()
+		printPlain(os.Stdout, sd)
+		sd.RUnlock()
+		return
+	}
 
-		resetColors = func() {
-			fg, bg = termbox.ColorDefault, termbox.ColorDefault
+	// Print the status line for status bars without starting termbox.
+	if *statusLine != "" {
+		// Keep the log output out of the status bar and out of the UI's log.
+		logFile, err := initLogger(statusLineLogFileName)
+		if err != nil {
+			log.Fatalf("initLogger: %v", err)
 		}
+		defer logFile.Close()
 
-		setFgColor = func(ts stockTradingSession) {
-			switch {
-			case ts.change > 0:
-				fg = termbox.ColorGreen
-			case ts.change < 0:
-				fg = termbox.ColorRed
-			default:
-				fg = termbox.ColorDefault
-			}
+		cfg, err := loadConfig()
+		if err != nil {
+			log.Fatalf("loadConfig: %v", err)
 		}
+		setNumberLocale(cfg.Locale)
 
-		setBgColor = func(ts stockTradingSession) {
-			c := 0
-			absChange := math.Abs(ts.percentChange)
-			for ; c < len(colorLevels)-1; c++ {
-				if absChange < colorLevels[c+1] {
-					break
-				}
-			}
+		sd := newStockData(cfg)
+		if err := loadCache(sd); err != nil {
+			log.Printf("loadCache: %v", err)
+		}
+		if err := printStatusLines(ctx, os.Stdout, sd, statusLineFormat(*statusLine), parseSymbolList(*statusSymbols), *statusInterval); err != nil {
+			log.Fatalf("printStatusLines: %v", err)
+		}
+		return
+	}
 
-			switch {
-			case has256Colors && ts.change > 0:
-				bg = positiveColors[c]
-			case has256Colors && ts.change < 0:
-				bg = negativeColors[c]
-			default:
-				bg = termbox.ColorDefault
-			}
+	// Run the data daemon for attached clients without starting termbox.
+	// It logs to stderr to keep out of the log of the clients.
+	if *daemon {
+		notifyShutdown(cancel)
+		if err := runDaemon(ctx); err != nil {
+			log.Fatalf("runDaemon: %v", err)
 		}
+		return
+	}
 
-		print = func(x, y int, format string, a ...interface{}) int {
-			for _, rune := range fmt.Sprintf(format, a...) {
-				termbox.SetCell(x, y, rune, fg, bg)
-				x++
+	// Backtest an allocation and exit without starting termbox.
+	if *backtestAllocation != "" {
+		start := chartToday().AddDate(-1, 0, 0)
+		if *backtestStart != "" {
+			start, err = time.Parse("2006-01-02", *backtestStart)
+			if err != nil {
+				log.Fatalf("time.Parse: %v", err)
 			}
-			return x
 		}
-	)
-
-	var (
-		// inputSymbol is the symbol the user is typing in.
-		inputSymbol string
+		if err := runBacktest(ctx, *backtestAllocation, start); err != nil {
+			log.Fatalf("runBacktest: %v", err)
+		}
+		return
+	}
 
-		// selectedIndex is the selected index of the user's stock list.
-		selectedIndex int
+	// Redirect the logger since termbox will cover the screen.
+	logFile, err := initLogger(logFileName)
+	if err != nil {
+		log.Fatalf("initLogger: %v", err)
+	}
+	defer logFile.Close()
 
-		// prevHeight tracks the previous height to detect window height changes.
-		prevHeight int
+	// Finish saving the config before exiting, since it is saved in the background.
+	defer configSaves.Wait()
 
-		// symbolOffset is the graphical offset to keep the selected index on-screen.
-		symbolOffset int
-	)
+	// Draw the first screen into memory and print it instead of using the terminal.
+	if err := setColorTheme(*colorThemeName); err != nil {
+		log.Fatalf("setColorTheme: %v", err)
+	}
 
-loop:
-	for {
-		if err := termbox.Clear(termbox.ColorDefault, termbox.ColorDefault); err != nil {
-			log.Fatalf("termbox.Clear: %v", err)
+	if *screenDump != "" {
+		w, h, err := parseScreenSize(*screenDump)
+		if err != nil {
+			log.Fatalf("parseScreenSize: %v", err)
 		}
+		bs := newBufferScreen(w, h, os.Stdout)
+		bs.exitAfterFlush = true
+		term = bs
+	} else if term, err = newScreen(*screenBackend); err != nil {
+		log.Fatalf("newScreen: %v", err)
+	}
 
-		w, h := termbox.Size()
+	// Try to initialize the screen now.
+	if err := term.init(); err != nil {
+		log.Fatalf("init: %v", err)
+	}
+	defer term.close()
 
-		sd.RLock()
+	// Exit the main loop to save the state when asked to stop like with kill.
+	notifyShutdown(cancel)
+	go func() {
+		<-ctx.Done()
+		term.interrupt()
+	}()
 
-		if !sd.refreshTime.IsZero() {
-			x := 0
+	// Restore the terminal and explain the crash if the program panics.
+	defer recoverCrash()
 
-			printIndex := func(symbol string, ts stockTradingSession) int {
-				resetColors()
-				x = print(x, 0, " %s ", symbol)
+	// Attempt to enable 24-bit or 256 color mode.
+	colors := setColorMode()
 
-				setBgColor(ts)
-				x = print(x, 0, " %.2f ", ts.close)
-				x = print(x, 0, "%+.2f %+.2f%% ", ts.change, ts.percentChange*100.0)
-				return x
-			}
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("loadConfig: %v", err)
+	}
+
+	setDisplayTimeZone(cfg.TimeZone)
+	setNumberLocale(cfg.Locale)
+	setAlpacaCredentials(cfg.AlpacaKeyID, cfg.AlpacaSecretKey, cfg.AlpacaURL)
+	setSymbolMappings(cfg.SymbolMappings)
+	setEconEvents(cfg.Events)
+
+	// Load the plugins before the stocks, since some may use them as their data sources.
+	plugins := loadPlugins()
+	setPluginSources(plugins)
+	sd := newStockData(cfg)
+
+	// Show just the watched symbol in large digits instead of the watchlist.
+	if *watchSymbol != "" {
+		runWatch(ctx, sd, strings.ToUpper(*watchSymbol))
+		return
+	}
 
-			x = printIndex("DOW", sd.dow)
-			x = printIndex("S&P", sd.sap)
-			x = printIndex("NASDAQ", sd.nasdaq)
+	sd.plugins = plugins
+
+	// Show the cached data until the first refresh finishes.
+	if err := loadCache(sd); err != nil {
+		log.Printf("loadCache: %v", err)
+	}
 
-			resetColors()
-			s := sd.refreshTime.Format("1/2/06 3:04 PM")
-			print(w-len(s), 0, s)
+	// Load the sessions fetched before, so longer charts don't have to be downloaded again.
+	if err := loadHistory(sd); err != nil {
+		log.Printf("loadHistory: %v", err)
+	}
+	defer func() {
+		if err := saveHistory(sd); err != nil {
+			log.Printf("saveHistory: %v", err)
 		}
+	}()
+	runPlugins(sd)
+
+	// Refresh right away when asked from outside like with kill -USR1 from a cron job.
+	refreshSignals := make(chan os.Signal, 1)
+	notifyRefresh(refreshSignals)
+
+	// Launch a go routine to periodically refresh the stock data.
+	go func() {
+		defer recoverCrash()
 
-		// Trim down trading dates to what fits the screen.
-		tsColumnCount := (w - symbolColumnWidth - padding) / (tsColumnWidth + padding)
-		if tsColumnCount > len(sd.tradingDates) {
-			tsColumnCount = len(sd.tradingDates)
+		// Keep showing the cached data if offline.
+		if *offline {
+			return
 		}
-		tradingDates := sd.tradingDates[len(sd.tradingDates)-tsColumnCount:]
 
-		// Print out the dates at the top.
-		x := symbolColumnWidth + padding*2
-		for _, td := range tradingDates {
-			if x+tsColumnWidth+padding > w {
-				break
+		// refresh refreshes the stock data and repaints the screen.
+		refresh := func() {
+			refreshStockData(ctx, sd, "")
+
+			// Leave the fetching to the daemon when attached, so the clients don't each make these requests.
+			if !*attach {
+				backfillPeriods(ctx, sd)
+
+				// Check for new filings and look up missing sectors in the background since there is a request for each stock.
+				go func() {
+					defer recoverCrash()
+					refreshFilings(ctx, sd)
+					refreshSectors(ctx, sd)
+					term.interrupt()
+				}()
+			}
+
+			// Save the data to show right away at the next startup.
+			if err := saveCache(sd); err != nil {
+				log.Printf("saveCache: %v", err)
+			}
+			if err := saveHistory(sd); err != nil {
+				log.Printf("saveHistory: %v", err)
 			}
 
-			switch {
-			case has256Colors:
-				bg = weekdayColors[td.Weekday()]
-			default:
-				bg = termbox.ColorDefault
+			// Save the watchlist's statistics once the trading day is over.
+			sd.RLock()
+			s, ok := takeSnapshot(sd, clk.now())
+			sd.RUnlock()
+			if ok {
+				if err := saveSnapshot(s); err != nil {
+					log.Printf("saveSnapshot: %v", err)
+				}
 			}
 
-			print(x, 2, "%[1]*s", tsColumnWidth, td.Format("1/2"))
-			print(x, 3, "%[1]*s", tsColumnWidth, td.Format("Mon"))
-			x = x + tsColumnWidth + padding
+			// Signal the screen to repaint by queuing an interrupt event.
+			term.interrupt()
 		}
 
-		// startY is the row after the refresh time(1) + padding(1) + date(2) + padding(1)
-		const startY = 5
+		// Do an initial refresh of the data.
+		refresh()
 
-		// getY gets the row's top y.
-		getY := func(row int) int {
-			return startY + (tsColumnHeight+padding)*row
-		}
+		// Loop forever and perodically refresh.
+		scheduleRefreshes(ctx, refresh, refreshSignals)
+	}()
 
-		// Reset the offset when the height changes to keep the screen filled.
-		if h != prevHeight {
-			symbolOffset = 0
+	// Repaint every minute to keep the market status countdown current.
+	go func() {
+		for {
+			<-clk.after(time.Minute)
+			term.interrupt()
 		}
-		prevHeight = h
+	}()
 
-		// Adjust the offset so that the selectedIndex is visible.
-		for getY(selectedIndex-symbolOffset) < startY {
-			symbolOffset--
-		}
-		for getY(selectedIndex-symbolOffset+1) > h {
-			symbolOffset++
-		}
+	u := newUI(ctx, sd, colors)
 
-		// Print out the symbols and the trading session cells.
-		for i, s := range sd.stocks[symbolOffset:] {
-			x, y := padding, getY(i)
-			if y+tsColumnHeight+padding > h {
-				break
-			}
+	// Restore where the user left off when ponzi last exited and save it when exiting normally.
+	u.loadState()
+	defer u.saveState()
 
-			if i+symbolOffset == selectedIndex {
-				fg = termbox.ColorYellow | termbox.AttrBold
-			} else {
-				fg = termbox.ColorDefault
-			}
-			bg = termbox.ColorDefault
+	// Render the UI and handle one event at a time until the user quits or ponzi is asked to stop.
+	for ctx.Err() == nil {
+		if err := term.clear(); err != nil {
+			log.Fatalf("clear: %v", err)
+		}
 
-			print(x, y, "%[1]*s", symbolColumnWidth, s.symbol)
-			x = x + symbolColumnWidth + padding
+		u.render(term.size())
 
-			for _, td := range tradingDates {
-				if x+tsColumnWidth+padding > w {
-					break
-				}
+		if err := term.flush(); err != nil {
+			log.Fatalf("flush: %v", err)
+		}
 
-				if ts, ok := s.tradingSessionMap[td]; ok {
-					fg = termbox.ColorDefault
-
-					// Print price and volume in default color.
-					setBgColor(ts)
-					print(x, y, "%[1]*.2f", tsColumnWidth, ts.close)
-					print(x, y+3, "%[1]*s", tsColumnWidth, shortenInt(ts.volume))
-
-					// Print change and % change in green or red.
-					setFgColor(ts)
-					print(x, y+1, "%+[1]*.2f", tsColumnWidth, ts.change)
-					print(x, y+2, "%+[1]*.2f%%", tsColumnWidth-1, ts.percentChange*100.0)
-				} else {
-					bg = placeholderColor
-					for i := 0; i < 4; i++ {
-						print(x, y+i, strings.Repeat(" ", tsColumnWidth))
-					}
-				}
-				x = x + tsColumnWidth + padding
-			}
+		if u.handleEvent(term.pollEvent()) {
+			break
 		}
+	}
+}
 
-		sd.RUnlock()
+// fullRefresh has the cancel function of the latest refresh of all the stocks.
+var fullRefresh struct {
+	sync.Mutex
+	cancel context.CancelFunc
+}
 
-		// Print out the input symbol in the center of the screen.
-		if inputSymbol != "" {
-			fg, bg = termbox.ColorWhite, termbox.ColorBlue
-			ps := strings.Repeat(" ", padding)
-			pr := ps + strings.Repeat(" ", len(inputSymbol)) + ps
-			cx, cy := w/2-len(pr)/2, h/2-1
-			print(cx, cy-1, pr)
-			print(cx, cy, ps+inputSymbol+ps)
-			print(cx, cy+1, pr)
-		}
-
-		if err := termbox.Flush(); err != nil {
-			log.Fatalf("termbox.Flush: %v", err)
-		}
-
-		switch ev := termbox.PollEvent(); ev.Type {
-		case termbox.EventKey:
-			switch ev.Key {
-			case termbox.KeyCtrlC, termbox.KeyCtrlD:
-				break loop
-
-			case termbox.KeyCtrlR, termbox.KeyF5:
-				refreshStockData(sd, "")
-
-			// TODO(btmura): remove code duplication with KeyArrowDown.
-			case termbox.KeyArrowUp:
-				sd.Lock()
-				if len(sd.stocks) > 0 {
-					swapIndex := selectedIndex - 1
-					if swapIndex < 0 {
-						swapIndex = len(sd.stocks) - 1
-					}
-					if ev.Mod == termbox.ModAlt {
-						sd.stocks[selectedIndex], sd.stocks[swapIndex] = sd.stocks[swapIndex], sd.stocks[selectedIndex]
-						saveStockData(sd)
-					}
-					selectedIndex = swapIndex
-				}
-				sd.Unlock()
-
-			case termbox.KeyArrowDown:
-				sd.Lock()
-				if len(sd.stocks) > 0 {
-					swapIndex := selectedIndex + 1
-					if swapIndex == len(sd.stocks) {
-						swapIndex = 0
-					}
-					if ev.Mod == termbox.ModAlt {
-						sd.stocks[selectedIndex], sd.stocks[swapIndex] = sd.stocks[swapIndex], sd.stocks[selectedIndex]
-						saveStockData(sd)
-					}
-					selectedIndex = swapIndex
-				}
-				sd.Unlock()
-
-			case termbox.KeyEnter:
-				if inputSymbol != "" {
-					sd.Lock()
-
-					// Expand the slice and insert at the selected index.
-					sd.stocks = append(sd.stocks, stock{})
-					copy(sd.stocks[selectedIndex+1:], sd.stocks[selectedIndex:])
-					sd.stocks[selectedIndex] = stock{symbol: inputSymbol}
-
-					// Swap with next element to simulate appending rather than insertion.
-					if selectedIndex+1 < len(sd.stocks) {
-						swapIndex := selectedIndex + 1
-						sd.stocks[selectedIndex], sd.stocks[swapIndex] = sd.stocks[swapIndex], sd.stocks[selectedIndex]
-					}
-
-					saveStockData(sd)
-					selectedIndex = (selectedIndex + 1) % len(sd.stocks)
-					sd.Unlock()
-
-					// Get initial data for the new stock.
-					refreshStockData(sd, inputSymbol)
-					inputSymbol = ""
-				}
+func refreshStockData(ctx context.Context, sd *stockData, oneSymbol string) {
+	// Keep the cached data rather than replacing it with empty results.
+	if *offline {
+		return
+	}
 
-			case termbox.KeyDelete:
-				sd.Lock()
-				if len(sd.stocks) > 0 {
-					sd.stocks = append(sd.stocks[:selectedIndex], sd.stocks[selectedIndex+1:]...)
-					saveStockData(sd)
-					if selectedIndex-1 >= 0 {
-						selectedIndex--
-					}
-				}
-				sd.Unlock()
+	// Get the data from the daemon rather than the data providers.
+	if *attach {
+		if err := refreshFromDaemon(ctx, sd); err != nil {
+			log.Printf("refreshFromDaemon: %v", err)
+			return
+		}
+		runPlugins(sd)
+		runHooks(sd, postRefreshEvent)
+		return
+	}
 
-			case termbox.KeyBackspace, termbox.KeyBackspace2:
-				if len(inputSymbol) > 0 {
-					inputSymbol = inputSymbol[:len(inputSymbol)-1]
-				}
+	// Cancel the outstanding requests of the previous refresh of all the stocks.
+	if oneSymbol == "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
 
-			default:
-				if unicode.IsLetter(ev.Ch) {
-					inputSymbol += strings.ToUpper(string(ev.Ch))
-				}
-			}
+		fullRefresh.Lock()
+		if fullRefresh.cancel != nil {
+			fullRefresh.cancel()
 		}
+		fullRefresh.cancel = cancel
+		fullRefresh.Unlock()
 	}
-}
 
-func refreshStockData(sd *stockData, oneSymbol string) {
 	// start and end times to set on the data requests.
 	var (
-		end   = midnight(time.Now().In(newYorkLoc))
+		end   = midnight(clk.now().In(newYorkLoc))
 		start = end.Add(-30 * 24 * time.Hour)
 	)
 
 	// Map from symbol to tradingSessions channel.
 	chm := map[string]chan []tradingSession{}
 
+	// fetchErrors is a map from symbol to the error getting its trading sessions.
+	var (
+		fetchErrors     = map[string]string{}
+		fetchErrorMutex sync.Mutex
+	)
+
 	// Collect the symbols for a batch call to get the real time trading data.
 	var symbols []string
 
-	// launchRequest records the requested symbol and launches a go routine for the data.
-	launchRequest := func(newSymbol string) {
+	// deltaStarts is a map from symbol to the date of its latest session before the end of its last refresh.
+	// Only the sessions from that date are fetched, since the earlier ones are already there. That session
+	// is fetched again to calculate the next one's change but is not updated, since its change is unknown.
+	deltaStarts := map[string]time.Time{}
+
+	// sources is a map from symbol to the data source set for the stock.
+	sources := map[string]tradingSessionSource{}
+
+	sd.RLock()
+	for _, s := range sd.stocks {
+		sources[s.symbol] = s.source
+		if s.historyEnd.IsZero() || s.historyStart.IsZero() || s.historyStart.After(start) {
+			continue
+		}
+		var ds time.Time
+		for date := range s.tradingSessionMap {
+			if date.Before(s.historyEnd) && date.After(ds) {
+				ds = date
+			}
+		}
+		if !ds.IsZero() {
+			deltaStarts[s.symbol] = ds
+		}
+	}
+	sd.RUnlock()
+
+	// onScreen is done when the data of the symbols on the screen has been fetched.
+	var onScreen sync.WaitGroup
+
+	// launchRequest records the requested symbol and launches a go routine for the data with the priority.
+	// The symbols on the screen must be launched before the rest, which wait for them.
+	launchRequest := func(newSymbol string, p requestPriority) {
 		// Avoid making redundant requests.
 		if _, ok := chm[newSymbol]; ok {
 			return
 		}
 
+		// Skip symbols that the data source can never serve.
+		source := stockSource(newSymbol, sources[newSymbol])
+		if err := checkSymbol(newSymbol, source); err != nil {
+			return
+		}
+
 		symbols = append(symbols, newSymbol)
 
 		// Launch a go routine that will stuff the tradingSessions into the channel.
 		ch := make(chan []tradingSession)
 		chm[newSymbol] = ch
+		if p != backgroundPriority {
+			onScreen.Add(1)
+		}
 		go func(symbol string, ch chan []tradingSession) {
-			tss, err := getTradingSessions(symbol, start, end)
+			// Defer the symbols off the screen so the ones being looked at are refreshed first.
+			if p == backgroundPriority {
+				onScreen.Wait()
+			}
+			s := start
+			if ds, ok := deltaStarts[symbol]; ok {
+				s = ds
+			}
+			tss, err := stockTradingSessionFunc(source)(withRequestPriority(ctx, p), symbol, s, end)
+			if p != backgroundPriority {
+				onScreen.Done()
+			}
 			if err != nil {
 				log.Printf("getTradingSessions(%s): %v", symbol, err)
+				fetchErrorMutex.Lock()
+				fetchErrors[symbol] = err.Error()
+				fetchErrorMutex.Unlock()
+				if ctx.Err() == nil {
+					runHooks(sd, fetchFailedEvent, "PONZI_SYMBOL="+symbol, "PONZI_ERROR="+err.Error())
+				}
 			}
 			ch <- tss
 		}(newSymbol, ch)
@@ -529,19 +733,35 @@ func refreshStockData(sd *stockData, oneSymbol string) {
 
 	// Launch requests for the specific symbol or all the symbols.
 	if oneSymbol != "" {
-		launchRequest(oneSymbol)
+		launchRequest(oneSymbol, symbolPriority(oneSymbol))
 	} else {
 		sd.RLock()
+		var all []string
 		for _, s := range sd.stocks {
-			launchRequest(s.symbol)
+			all = append(all, s.symbol)
 		}
 		sd.RUnlock()
+		all = append(all, *benchmarkSymbol)
+
+		// Launch the selected and visible symbols first. Get the priorities once, since the symbols
+		// on the screen can change while launching, and a symbol that became visible after the
+		// background requests started waiting would join the WaitGroup too late.
+		priorities := map[string]requestPriority{}
+		for _, symbol := range all {
+			priorities[symbol] = symbolPriority(symbol)
+		}
+		sort.SliceStable(all, func(i, j int) bool {
+			return priorities[all[i]] > priorities[all[j]]
+		})
+		for _, symbol := range all {
+			launchRequest(symbol, priorities[symbol])
+		}
 	}
 
 	// Get the live trading sessions for the stocks.
 	ch := make(chan []liveTradingSession)
 	go func(ch chan []liveTradingSession) {
-		tss, err := getLiveTradingSessions(symbols)
+		tss, err := getLiveTradingSessions(withRequestPriority(ctx, visiblePriority), symbols)
 		if err != nil {
 			log.Printf("getLiveTradingSessions: %v", err)
 		}
@@ -551,13 +771,46 @@ func refreshStockData(sd *stockData, oneSymbol string) {
 	// Get the live trading sessions for the major indices.
 	ich := make(chan []liveTradingSession)
 	go func(ch chan []liveTradingSession) {
-		tss, err := getLiveTradingSessions(indexSymbols)
+		tss, err := getLiveTradingSessions(withRequestPriority(ctx, visiblePriority), indexSymbols)
 		if err != nil {
 			log.Printf("getLiveTradingSessions: %v", err)
 		}
 		ch <- tss
 	}(ich)
 
+	// Get the live trading sessions for the sector strip independent of the stocks.
+	sd.RLock()
+	sectorETFs := displaySectorETFs(sd.sectorETFs)
+	sd.RUnlock()
+	ech := make(chan []liveTradingSession)
+	go func(ch chan []liveTradingSession) {
+		tss, err := getLiveTradingSessions(ctx, sectorETFs)
+		if err != nil {
+			log.Printf("getLiveTradingSessions: %v", err)
+		}
+		ch <- tss
+	}(ech)
+
+	// Get the index futures to show instead of the closing values outside market hours.
+	// Only Yahoo has quotes for futures.
+	fch := make(chan []liveTradingSession)
+	go func(ch chan []liveTradingSession) {
+		if !*showIndexFutures || isMarketHours(clk.now()) {
+			ch <- nil
+			return
+		}
+
+		var symbols []string
+		for _, s := range indexSymbols {
+			symbols = append(symbols, indexFutureSymbols[s])
+		}
+		tss, err := getLiveTradingSessionsFromYahoo(ctx, symbols)
+		if err != nil {
+			log.Printf("getLiveTradingSessionsFromYahoo: %v", err)
+		}
+		ch <- tss
+	}(fch)
+
 	// dates is the sorted set of trading dates that will be shown at the top.
 	// Use addDate to correctly modify the dates.
 	var (
@@ -587,32 +840,116 @@ func refreshStockData(sd *stockData, oneSymbol string) {
 				tsm[symbol] = map[time.Time]stockTradingSession{}
 			}
 			tsm[symbol][ts.date] = ts
+
 			addDate(ts.date)
 		}
 	)
 
+	// fetched is the set of symbols with trading sessions from start to end.
+	fetched := map[string]bool{}
+
 	// Extract the trading sessions from each channel and put them into the map.
 	for symbol, ch := range chm {
-		// TODO(btmura): detect error value from channel
-		for _, ts := range convertTradingSessions(<-ch) {
+		tss := <-ch
+		for _, ts := range convertTradingSessions(tss) {
+			// Skip the session fetched again only to calculate the changes.
+			if ds, ok := deltaStarts[symbol]; ok && !ts.date.After(ds) {
+				continue
+			}
 			addTradingSession(symbol, ts)
 		}
+		fetched[symbol] = len(tss) > 0
 	}
 
+	// exchanges is a map from symbol to exchange of the live trading sessions.
+	exchanges := map[string]string{}
+
 	// Extract the live trading sessions and put them into the map.
-	for symbol, ts := range convertLiveTradingSessions(<-ch) {
+	lts := <-ch
+	recordJournal(lts)
+	for symbol, ts := range convertLiveTradingSessions(lts) {
+		// Keep the day's range of the fetched session, since live quotes only have the price.
+		if fts, ok := tsm[symbol][ts.date]; ok {
+			ts = withDayRange(ts, fts)
+		}
 		addTradingSession(symbol, ts)
 	}
+	for _, lt := range lts {
+		exchanges[lt.symbol] = lt.exchange
+	}
 
 	// Extract the live trading sessions for the indices.
-	im := convertLiveTradingSessions(<-ich)
+	ilts := <-ich
+	im := convertLiveTradingSessions(ilts)
+	for _, lt := range ilts {
+		exchanges[lt.symbol] = lt.exchange
+	}
+
+	// Extract the live trading sessions for the sector strip.
+	ets := convertLiveTradingSessions(<-ech)
+
+	// Extract the index futures and map them to their indices.
+	var futures map[string]stockTradingSession
+	if fts := convertLiveTradingSessions(<-fch); len(fts) > 0 {
+		futures = map[string]stockTradingSession{}
+		for _, s := range indexSymbols {
+			if ts, ok := fts[indexFutureSymbols[s]]; ok {
+				futures[s] = ts
+			}
+		}
+	}
+
+	// Drop NYSE holidays that some sources have quotes dated on unless an international stock traded that day.
+	var tradingDates sortableTimes
+	for _, date := range dates {
+		keep := isTradingDay(date)
+		for symbol, m := range tsm {
+			if _, ok := m[date]; ok && !isUSExchange(exchanges[symbol]) {
+				keep = true
+			}
+		}
+		if keep {
+			tradingDates = append(tradingDates, date)
+		}
+	}
+	dates = tradingDates
+
+	// Get the account and positions if the user has an Alpaca account.
+	var (
+		account   *alpacaAccount
+		positions map[string]alpacaPosition
+	)
+	if hasAlpacaCredentials() {
+		var err error
+		account, err = getAlpacaAccount(ctx)
+		if err != nil {
+			log.Printf("getAlpacaAccount: %v", err)
+		}
+
+		// Keep showing the previous positions if the request fails rather than clearing them.
+		ps, err := getAlpacaPositions(ctx)
+		if err != nil {
+			log.Printf("getAlpacaPositions: %v", err)
+		} else {
+			positions = map[string]alpacaPosition{}
+			for _, p := range ps {
+				positions[p.symbol] = p
+			}
+		}
+	}
+
+	// Don't overwrite the data with the partial results of a canceled refresh.
+	if ctx.Err() != nil {
+		log.Printf("refreshStockData: %v", ctx.Err())
+		return
+	}
 
 	// Sort the trading dates with most recent at the back.
 	sort.Sort(dates)
 
 	// Acquire a write lock and write the updated data.
 	sd.Lock()
-	sd.refreshTime = time.Now()
+	sd.refreshTime = clk.now()
 	sd.tradingDates = dates
 	for i, s := range sd.stocks {
 		if sd.stocks[i].tradingSessionMap == nil {
@@ -621,11 +958,125 @@ func refreshStockData(sd *stockData, oneSymbol string) {
 		for date, ts := range tsm[s.symbol] {
 			sd.stocks[i].tradingSessionMap[date] = ts
 		}
+		if len(tsm[s.symbol]) > 0 {
+			sd.stocks[i].updateTime = sd.refreshTime
+			sd.stocks[i].cached = false
+			sd.stocks[i].volatility = volatility(sd.stocks[i].tradingSessionMap)
+		}
+		if fetched[s.symbol] && (s.historyStart.IsZero() || start.Before(s.historyStart)) {
+			sd.stocks[i].historyStart = start
+		}
+		if fetched[s.symbol] {
+			sd.stocks[i].historyEnd = end
+		}
+		if _, ok := chm[s.symbol]; ok {
+			sd.stocks[i].fetchError = fetchErrors[s.symbol]
+		}
+	}
+	sd.benchmark.symbol = *benchmarkSymbol
+	if sd.benchmark.tradingSessionMap == nil {
+		sd.benchmark.tradingSessionMap = map[time.Time]stockTradingSession{}
+	}
+	for date, ts := range tsm[*benchmarkSymbol] {
+		sd.benchmark.tradingSessionMap[date] = ts
 	}
 	sd.dow = im[dowSymbol]
 	sd.sap = im[sapSymbol]
 	sd.nasdaq = im[nasdaqSymbol]
+	sd.futures = futures
+	if len(ets) > 0 {
+		sd.sectorETFSessions = ets
+	}
+	if sd.exchanges == nil {
+		sd.exchanges = map[string]string{}
+	}
+	for symbol, e := range exchanges {
+		sd.exchanges[symbol] = e
+	}
+	if account != nil {
+		sd.account = account
+	}
+	if positions != nil {
+		sd.positions = positions
+	}
 	sd.Unlock()
+
+	refreshSplits(ctx, sd)
+	runPlugins(sd)
+	runHooks(sd, postRefreshEvent)
+}
+
+// backfillStockData fetches the symbol's trading sessions from start to end that were not fetched before.
+// It returns true if new trading sessions were added.
+func backfillStockData(ctx context.Context, sd *stockData, symbol string, start, end time.Time) bool {
+	// Show the sessions from the daemon, which does the fetching for attached clients.
+	if *attach {
+		return false
+	}
+
+	sd.RLock()
+	var (
+		historyStart time.Time
+		source       tradingSessionSource
+	)
+	for _, s := range sd.stocks {
+		if s.symbol == symbol {
+			historyStart, source = s.historyStart, s.source
+		}
+	}
+	sd.RUnlock()
+
+	// Use the cached trading sessions if they go back far enough.
+	if !historyStart.IsZero() && !start.Before(historyStart) {
+		countCacheLookup(true)
+		return false
+	}
+	countCacheLookup(false)
+
+	// Only fetch the missing trading sessions before what we already have.
+	if !historyStart.IsZero() && historyStart.Before(end) {
+		end = historyStart
+	}
+
+	tss, err := stockTradingSessionFunc(stockSource(symbol, source))(ctx, symbol, start, end)
+	if err != nil {
+		log.Printf("getTradingSessions(%s): %v", symbol, err)
+		return false
+	}
+
+	// The provider's sessions are already adjusted for the splits before now.
+	sd.Lock()
+	for i, s := range sd.stocks {
+		if s.symbol != symbol {
+			continue
+		}
+		if sd.stocks[i].tradingSessionMap == nil {
+			sd.stocks[i].tradingSessionMap = map[time.Time]stockTradingSession{}
+		}
+		for _, ts := range convertTradingSessions(tss) {
+			// Don't overwrite sessions with changes calculated from more data.
+			if _, ok := sd.stocks[i].tradingSessionMap[ts.date]; !ok {
+				sd.stocks[i].tradingSessionMap[ts.date] = ts
+			}
+		}
+		sd.stocks[i].historyStart = start
+	}
+	sd.Unlock()
+
+	refreshSplits(ctx, sd)
+	return true
+}
+
+// latestTradingSession returns the most recent trading session in the map.
+func latestTradingSession(tsm map[time.Time]stockTradingSession) (stockTradingSession, bool) {
+	var latest stockTradingSession
+	var ok bool
+	for date, ts := range tsm {
+		if !ok || date.After(latest.date) {
+			latest, ok = ts, true
+		}
+	}
+	return latest, ok
 }
 
 func convertTradingSessions(tss []tradingSession) []stockTradingSession {
@@ -633,6 +1084,9 @@ func convertTradingSessions(tss []tradingSession) []stockTradingSession {
 	for _, ts := range tss {
 		sts = append(sts, stockTradingSession{
 			date:   ts.date,
+			open:   ts.open,
+			high:   ts.high,
+			low:    ts.low,
 			close:  ts.close,
 			volume: ts.volume,
 		})
@@ -652,8 +1106,13 @@ func convertTradingSessions(tss []tradingSession) []stockTradingSession {
 func convertLiveTradingSessions(lts []liveTradingSession) map[string]stockTradingSession {
 	m := map[string]stockTradingSession{}
 	for _, lt := range lts {
+		// Keep the last good quote rather than showing a change to zero.
+		if lt.price <= 0 || lt.timestamp.IsZero() {
+			log.Printf("dropping live quote of %s: %+v", lt.symbol, lt)
+			continue
+		}
 		m[lt.symbol] = stockTradingSession{
-			date:          midnight(lt.timestamp),
+			date:          exchangeDate(lt.exchange, lt.timestamp),
 			close:         lt.price,
 			change:        lt.change,
 			percentChange: lt.percentChange,
@@ -662,28 +1121,195 @@ func convertLiveTradingSessions(lts []liveTradingSession) map[string]stockTradin
 	return m
 }
 
+// newStockData returns stockData with the stocks and settings from the user's config.
+func newStockData(cfg config) *stockData {
+	sd := &stockData{
+		timeZone:    cfg.TimeZone,
+		periods:     cfg.Periods,
+		sectorETFs:  cfg.SectorETFs,
+		quoteURL:    cfg.QuoteURL,
+		hooks:       newHooks(cfg.Hooks),
+		configHooks: cfg.Hooks,
+
+		alpacaKeyID:     cfg.AlpacaKeyID,
+		alpacaSecretKey: cfg.AlpacaSecretKey,
+		alpacaURL:       cfg.AlpacaURL,
+		symbolMappings:  cfg.SymbolMappings,
+		econEvents:      cfg.Events,
+
+		locale:               cfg.Locale,
+		assetPrecisions:      cfg.Precisions,
+		historyRetentionDays: cfg.HistoryRetentionDays,
+	}
+	for _, cl := range cfg.ChartLayouts {
+		sd.layouts = append(sd.layouts, chartLayout{
+			name:       cl.Name,
+			rangeLabel: cl.Range,
+			start:      cl.Start,
+			end:        cl.End,
+			options: chartOptions{
+				movingAverages: cl.MovingAverages,
+				logScale:       cl.LogScale,
+			},
+		})
+	}
+	for _, cs := range cfg.Sales {
+		sd.sales = append(sd.sales, newSale(cs))
+	}
+	for _, cs := range cfg.Stocks {
+		// Warn up front about symbols that will never refresh.
+		if err := checkSymbol(cs.Symbol, stockSource(cs.Symbol, tradingSessionSource(cs.Source))); err != nil {
+			log.Printf("checkSymbol: %v", err)
+		}
+
+		var lots []lot
+		for _, cl := range cs.Lots {
+			lots = append(lots, lot{
+				date:     cl.Date,
+				quantity: cl.Quantity,
+				cost:     cl.Cost,
+			})
+		}
+		var dividends []dividend
+		for _, cd := range cs.Dividends {
+			dividends = append(dividends, dividend{
+				date:   cd.Date,
+				amount: cd.Amount,
+			})
+		}
+		// Move the sales saved with the stock by older versions to the other sales.
+		for _, sl := range cs.Sales {
+			sl.Symbol = cs.Symbol
+			sd.sales = append(sd.sales, newSale(sl))
+		}
+		sd.stocks = append(sd.stocks, stock{
+			symbol:    cs.Symbol,
+			lots:      lots,
+			dividends: dividends,
+			pinned:    cs.Pinned,
+			source:    tradingSessionSource(cs.Source),
+			sector:    cs.Sector,
+			industry:  cs.Industry,
+			precision: cs.Precision,
+		})
+	}
+	return sd
+}
+
 func saveStockData(sd *stockData) {
-	cfg := config{}
+	// Leave the config as is for shared dashboards or configs managed elsewhere.
+	if *readOnly {
+		return
+	}
+
+	cfg := config{
+		TimeZone:   sd.timeZone,
+		Periods:    sd.periods,
+		SectorETFs: sd.sectorETFs,
+		QuoteURL:   sd.quoteURL,
+		Hooks:      sd.configHooks,
+
+		AlpacaKeyID:     sd.alpacaKeyID,
+		AlpacaSecretKey: sd.alpacaSecretKey,
+		AlpacaURL:       sd.alpacaURL,
+		SymbolMappings:  sd.symbolMappings,
+		Events:          sd.econEvents,
+
+		Locale:               sd.locale,
+		Precisions:           sd.assetPrecisions,
+		HistoryRetentionDays: sd.historyRetentionDays,
+	}
+	for _, cl := range sd.layouts {
+		cfg.ChartLayouts = append(cfg.ChartLayouts, configChartLayout{
+			Name:           cl.name,
+			Range:          cl.rangeLabel,
+			Start:          cl.start,
+			End:            cl.end,
+			MovingAverages: cl.options.movingAverages,
+			LogScale:       cl.options.logScale,
+		})
+	}
 	for _, s := range sd.stocks {
+		var lots []configLot
+		for _, l := range s.lots {
+			lots = append(lots, configLot{
+				Date:     l.date,
+				Quantity: l.quantity,
+				Cost:     l.cost,
+			})
+		}
+		var dividends []configDividend
+		for _, d := range s.dividends {
+			dividends = append(dividends, configDividend{
+				Date:   d.date,
+				Amount: d.amount,
+			})
+		}
 		cfg.Stocks = append(cfg.Stocks, configStock{
-			Symbol: s.symbol,
+			Symbol:    s.symbol,
+			Lots:      lots,
+			Dividends: dividends,
+			Pinned:    s.pinned,
+			Source:    string(s.source),
+			Sector:    s.sector,
+			Industry:  s.industry,
+			Precision: s.precision,
+		})
+	}
+	for _, sl := range sd.sales {
+		cfg.Sales = append(cfg.Sales, configSale{
+			Symbol:   sl.symbol,
+			Date:     sl.date,
+			Acquired: sl.acquired,
+			Quantity: sl.quantity,
+			Proceeds: sl.proceeds,
+			Cost:     sl.cost,
 		})
 	}
+	configSaves.Add(1)
 	go func() {
+		defer configSaves.Done()
 		if err := saveConfig(cfg); err != nil {
 			log.Printf("saveConfig: %v", err)
 		}
 	}()
 }
 
-// shortenInt shortens larger numbers and appends a quantity suffix.
+// shortenInt shortens larger numbers and appends a quantity suffix like 1.9M.
 func shortenInt(val int64) string {
-	switch {
-	case val/1e6 > 0:
-		return fmt.Sprintf("%dM", val/1e6)
-	case val/1e3 > 0:
-		return fmt.Sprintf("%dK", val/1e3)
-	default:
-		return strconv.FormatInt(val, 10)
+	return shortenNumber(float64(val))
+}
+
+// quantitySuffixes are the suffixes of shortened numbers from the smallest.
+var quantitySuffixes = []struct {
+	value  float64
+	suffix string
+}{
+	{1e3, "K"},
+	{1e6, "M"},
+	{1e9, "B"},
+	{1e12, "T"},
+}
+
+// shortenNumber shortens numbers of a thousand or more to one decimal place with a quantity suffix
+// like 12.3K, 1.9M, or 2.4B. Smaller numbers are returned as is.
+func shortenNumber(val float64) string {
+	abs := math.Abs(val)
+	if abs < 1e3 {
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+
+	sign := ""
+	if val < 0 {
+		sign = "-"
+	}
+
+	// Use the next suffix if rounding would show 1000.0 like 999.96K.
+	for i, q := range quantitySuffixes {
+		r := math.Round(abs/q.value*10) / 10
+		if r < 1e3 || i == len(quantitySuffixes)-1 {
+			return sign + strconv.FormatFloat(r, 'f', 1, 64) + q.suffix
+		}
 	}
+	return ""
 }
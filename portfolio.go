@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// lot is a quantity of shares of a stock bought together.
+type lot struct {
+	// date is when the lot was bought or zero if unknown like for imported positions.
+	date time.Time
+
+	// quantity is the number of shares in the lot.
+	quantity float64
+
+	// cost is the total cost basis of the lot.
+	cost float64
+}
+
+// totalQuantity returns the number of shares in all the lots.
+func totalQuantity(lots []lot) float64 {
+	var q float64
+	for _, l := range lots {
+		q += l.quantity
+	}
+	return q
+}
+
+// totalCost returns the cost basis of all the lots.
+func totalCost(lots []lot) float64 {
+	var c float64
+	for _, l := range lots {
+		c += l.cost
+	}
+	return c
+}
+
+// brokerFormat is a broker's CSV statement format.
+type brokerFormat string
+
+// List of possible brokerFormat values.
+const (
+	fidelity brokerFormat = "fidelity"
+	schwab   brokerFormat = "schwab"
+	ibkr     brokerFormat = "ibkr"
+)
+
+// brokerColumns are the possible header names of the columns needed from a broker's statement.
+type brokerColumns struct {
+	symbol   []string
+	quantity []string
+	cost     []string
+}
+
+// brokerFormatColumns is a map from brokerFormat to the columns in its positions statement.
+var brokerFormatColumns = map[brokerFormat]brokerColumns{
+	// Fidelity's Portfolio_Positions.csv download.
+	fidelity: {
+		symbol:   []string{"Symbol"},
+		quantity: []string{"Quantity"},
+		cost:     []string{"Cost Basis Total", "Cost Basis"},
+	},
+
+	// Schwab's positions export which starts with a title line before the header.
+	schwab: {
+		symbol:   []string{"Symbol"},
+		quantity: []string{"Quantity", "Qty (Quantity)"},
+		cost:     []string{"Cost Basis", "Cost Basis (CB)"},
+	},
+
+	// Interactive Brokers' Flex Query with the Open Positions section.
+	ibkr: {
+		symbol:   []string{"Symbol"},
+		quantity: []string{"Quantity", "Position"},
+		cost:     []string{"CostBasisMoney"},
+	},
+}
+
+// importedPosition is a position read from a broker statement.
+type importedPosition struct {
+	symbol   string
+	quantity float64
+	cost     float64
+}
+
+// readBrokerPositions reads the positions from a broker's CSV statement.
+// Rows that are not stock positions like cash, pending activity, and totals are skipped.
+func readBrokerPositions(r io.Reader, format brokerFormat) ([]importedPosition, error) {
+	cols, ok := brokerFormatColumns[format]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized broker format: %s", format)
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	// Indices of the needed columns once the header row is found.
+	symbolIndex, quantityIndex, costIndex := -1, -1, -1
+
+	// findColumn returns the index of the first matching header name or -1.
+	findColumn := func(record []string, names []string) int {
+		for _, n := range names {
+			for i, v := range record {
+				if strings.TrimSpace(v) == n {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	var ps []importedPosition
+	for {
+		record, err := cr.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		// Skip any title lines until the header row.
+		if symbolIndex == -1 {
+			symbolIndex = findColumn(record, cols.symbol)
+			quantityIndex = findColumn(record, cols.quantity)
+			costIndex = findColumn(record, cols.cost)
+			if symbolIndex == -1 || quantityIndex == -1 {
+				symbolIndex = -1
+			}
+			continue
+		}
+
+		if symbolIndex >= len(record) || quantityIndex >= len(record) {
+			continue
+		}
+
+		symbol := strings.TrimSpace(record[symbolIndex])
+		if !isPositionSymbol(symbol) {
+			continue
+		}
+
+		quantity, err := parseMoney(record[quantityIndex])
+		if err != nil || quantity == 0 {
+			continue
+		}
+
+		var cost float64
+		if costIndex != -1 && costIndex < len(record) {
+			// Cost basis is sometimes unavailable like "--" for transferred shares.
+			cost, _ = parseMoney(record[costIndex])
+		}
+
+		ps = append(ps, importedPosition{
+			symbol:   symbol,
+			quantity: quantity,
+			cost:     cost,
+		})
+	}
+
+	if symbolIndex == -1 {
+		return nil, fmt.Errorf("no %s header row with the %s and %s columns", format, cols.symbol[0], cols.quantity[0])
+	}
+
+	return ps, nil
+}
+
+// isPositionSymbol returns true if the value looks like a stock symbol rather than a label like "Account Total".
+// Money market symbols like "SPAXX**" are not considered positions.
+func isPositionSymbol(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsUpper(r) && !unicode.IsDigit(r) && r != '.' && r != '-' && r != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMoney parses values like "$1,234.56" or "-12.5" and "(12.50)" for negative values.
+func parseMoney(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	neg := strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")")
+	value = strings.Trim(value, "()")
+	value = strings.Replace(value, "$", "", -1)
+	value = strings.TrimPrefix(value, "+")
+	v, err := parseFloat(value)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// importPositions imports the positions from a broker's CSV statement into the user's config.
+// Lots of imported symbols replace any existing lots, and new symbols are added to the end.
+func importPositions(path string, format brokerFormat) ([]importedPosition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ps, err := readBrokerPositions(file, format)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the positions by symbol since a symbol can be held in multiple accounts.
+	lm := map[string][]configLot{}
+	var symbols []string
+	for _, p := range ps {
+		if _, ok := lm[p.symbol]; !ok {
+			symbols = append(symbols, p.symbol)
+		}
+		lm[p.symbol] = append(lm[p.symbol], configLot{
+			Quantity: p.quantity,
+			Cost:     p.cost,
+		})
+	}
+
+	for i, cs := range cfg.Stocks {
+		if lots, ok := lm[cs.Symbol]; ok {
+			cfg.Stocks[i].Lots = lots
+			delete(lm, cs.Symbol)
+		}
+	}
+	for _, symbol := range symbols {
+		if lots, ok := lm[symbol]; ok {
+			cfg.Stocks = append(cfg.Stocks, configStock{
+				Symbol: symbol,
+				Lots:   lots,
+			})
+		}
+	}
+
+	if err := saveConfig(cfg); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
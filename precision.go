@@ -0,0 +1,32 @@
+package main
+
+// defaultPrecision is the number of decimal places of prices without a configured precision.
+const defaultPrecision = 2
+
+// maxPrecision is the most decimal places shown, so prices still fit in their columns.
+const maxPrecision = 8
+
+// defaultAssetPrecisions is a map from asset class to its decimal places when the config has none.
+// Currency rates move by ten-thousandths, which two decimal places would hide.
+var defaultAssetPrecisions = map[assetClass]int{
+	currencyAsset: 4,
+}
+
+// pricePrecision returns the decimal places to show the stock's prices with. The stock's own
+// precision comes first, then the config's precision for its asset class, and then the defaults.
+func pricePrecision(s stock, assetPrecisions map[string]int) int {
+	p := defaultPrecision
+	ac := classifySymbol(s.symbol)
+	switch {
+	case s.precision > 0:
+		p = s.precision
+	case assetPrecisions[string(ac)] > 0:
+		p = assetPrecisions[string(ac)]
+	case defaultAssetPrecisions[ac] > 0:
+		p = defaultAssetPrecisions[ac]
+	}
+	if p > maxPrecision {
+		p = maxPrecision
+	}
+	return p
+}
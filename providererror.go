@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// errorPageSniffLength is how much of a response body is checked for an HTML error page.
+const errorPageSniffLength = 4096
+
+// errorPageTitleRegexp matches the title of an HTML error page.
+var errorPageTitleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// errorPageError is the error when a provider returns an HTML error page instead of its data.
+type errorPageError struct {
+	// host is the provider's host.
+	host string
+
+	// title is the title of the page or empty if it has none.
+	title string
+}
+
+// Error implements error.
+func (e *errorPageError) Error() string {
+	if e.title == "" {
+		return fmt.Sprintf("%s returned an error page", e.host)
+	}
+	return fmt.Sprintf("%s returned an error page: %s", e.host, e.title)
+}
+
+// checkErrorPage returns an errorPageError if the 200 OK response is an HTML page, which providers send
+// for problems like unknown symbols or too many requests. None of the providers return HTML for data.
+// The start of the body is peeked at without consuming it.
+func checkErrorPage(resp *http.Response) error {
+	br := bufio.NewReaderSize(resp.Body, errorPageSniffLength)
+	resp.Body = &bufferedReadCloser{Reader: br, Closer: resp.Body}
+
+	// Peek returns the shorter body along with an error, which the parser will see when reading.
+	start, _ := br.Peek(errorPageSniffLength)
+
+	isHTML := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") ||
+		bytes.HasPrefix(bytes.TrimSpace(start), []byte("<"))
+	if !isHTML {
+		return nil
+	}
+
+	e := &errorPageError{host: resp.Request.URL.Host}
+	if m := errorPageTitleRegexp.FindSubmatch(start); m != nil {
+		e.title = strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	}
+	return e
+}
+
+// bufferedReadCloser reads from a buffer of the body and closes the body.
+type bufferedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// yahooError is the error object that Yahoo's JSON APIs return in place of results.
+type yahooError struct {
+	// Code is the kind of error like "Not Found". Capitalized for JSON decoding.
+	Code string
+
+	// Description explains the error. Capitalized for JSON decoding.
+	Description string
+}
+
+// Error implements error.
+func (e *yahooError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("yahoo returned an error: %s", e.Code)
+	}
+	return fmt.Sprintf("yahoo returned an error: %s: %s", e.Code, e.Description)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimit is the number of requests a source allows before it starts refusing them.
+// Zero means no limit.
+type rateLimit struct {
+	perMinute, perDay int
+}
+
+// sourceRateLimits is a map from each source's host to its limits. Requests to other hosts are not limited.
+var sourceRateLimits = map[string]rateLimit{
+	"www.google.com":             {perMinute: 120},
+	"ichart.yahoo.com":           {perMinute: 60, perDay: 20000},
+	"download.finance.yahoo.com": {perMinute: 60, perDay: 20000},
+	"query1.finance.yahoo.com":   {perMinute: 60, perDay: 20000},
+	"www.sec.gov":                {perMinute: 300},
+	"data.sec.gov":               {perMinute: 300},
+	"paper-api.alpaca.markets":   {perMinute: 200},
+	"api.alpaca.markets":         {perMinute: 200},
+}
+
+// requestPriority is how soon a request should be sent when its source is near its limits.
+type requestPriority int
+
+// List of possible requestPriority values.
+const (
+	backgroundPriority requestPriority = iota
+	visiblePriority
+	selectedPriority
+)
+
+// requestPriorityKey is the context key of the requestPriority.
+type requestPriorityKey struct{}
+
+// withRequestPriority returns a context whose requests have the priority.
+func withRequestPriority(ctx context.Context, p requestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, p)
+}
+
+// rateLimiter spreads the requests to each source over time to stay within its limits.
+var rateLimiter = struct {
+	// Embedded mutex that guards the fields.
+	sync.Mutex
+
+	// sent is a map from host to the times of the requests sent to it within the last day.
+	sent map[string][]time.Time
+
+	// waiting is a map from host to the number of requests of each priority waiting to be sent.
+	waiting map[string]map[requestPriority]int
+
+	// changed is closed and replaced whenever a request is sent or stops waiting.
+	changed chan struct{}
+
+	// backoff is a map from host to the time it asked not to be sent requests until.
+	backoff map[string]time.Time
+}{
+	sent:    map[string][]time.Time{},
+	waiting: map[string]map[requestPriority]int{},
+	changed: make(chan struct{}),
+	backoff: map[string]time.Time{},
+}
+
+// defaultRetryAfter is how long to back off when a host says there are too many requests
+// without saying how long to wait.
+const defaultRetryAfter = time.Minute
+
+// backOffRateLimit holds the requests to the host until the time, like when it responded with
+// 429 Too Many Requests. Hosts without limits are held too.
+func backOffRateLimit(host string, until time.Time) {
+	rateLimiter.Lock()
+	defer rateLimiter.Unlock()
+	if until.After(rateLimiter.backoff[host]) {
+		rateLimiter.backoff[host] = until
+	}
+}
+
+// waitRateLimit blocks until a request to the host can be sent without exceeding its limits.
+// Requests with a higher priority in their context are sent first. It returns an error if the
+// context is done first.
+func waitRateLimit(ctx context.Context, host string) error {
+	lim, ok := sourceRateLimits[host]
+	rateLimiter.Lock()
+	_, backingOff := rateLimiter.backoff[host]
+	rateLimiter.Unlock()
+	if !ok && !backingOff {
+		return nil
+	}
+
+	p, _ := ctx.Value(requestPriorityKey{}).(requestPriority)
+
+	rateLimiter.Lock()
+	if rateLimiter.waiting[host] == nil {
+		rateLimiter.waiting[host] = map[requestPriority]int{}
+	}
+	rateLimiter.waiting[host][p]++
+	rateLimiter.Unlock()
+
+	defer func() {
+		rateLimiter.Lock()
+		rateLimiter.waiting[host][p]--
+		notifyRateLimitChanged()
+		rateLimiter.Unlock()
+	}()
+
+	for {
+		rateLimiter.Lock()
+		now := time.Now()
+		d := rateLimitDelay(lim, host, now)
+		if d == 0 && !higherPriorityWaiting(host, p) {
+			rateLimiter.sent[host] = append(rateLimiter.sent[host], now)
+			notifyRateLimitChanged()
+			rateLimiter.Unlock()
+			return nil
+		}
+		changed := rateLimiter.changed
+		rateLimiter.Unlock()
+
+		// Wait for the higher priority requests to go first.
+		if d == 0 {
+			d = time.Minute
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-changed:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// rateLimitDelay returns how long to wait before the next request to the host.
+// Requests are spaced evenly over the minute rather than sent in bursts and held while backing off.
+// The caller must hold the rateLimiter lock.
+func rateLimitDelay(lim rateLimit, host string, now time.Time) time.Duration {
+	// Forget the requests sent more than a day ago.
+	sent := rateLimiter.sent[host]
+	for len(sent) > 0 && now.Sub(sent[0]) >= 24*time.Hour {
+		sent = sent[1:]
+	}
+	rateLimiter.sent[host] = sent
+
+	var d time.Duration
+	if until, ok := rateLimiter.backoff[host]; ok {
+		if now.Before(until) {
+			d = until.Sub(now)
+		} else {
+			delete(rateLimiter.backoff, host)
+		}
+	}
+
+	if len(sent) == 0 {
+		return d
+	}
+
+	if lim.perMinute > 0 {
+		if dd := sent[len(sent)-1].Add(time.Minute / time.Duration(lim.perMinute)).Sub(now); dd > d {
+			d = dd
+		}
+	}
+	if lim.perDay > 0 && len(sent) >= lim.perDay {
+		if dd := sent[len(sent)-lim.perDay].Add(24 * time.Hour).Sub(now); dd > d {
+			d = dd
+		}
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// higherPriorityWaiting returns whether requests to the host with a higher priority are waiting.
+// The caller must hold the rateLimiter lock.
+func higherPriorityWaiting(host string, p requestPriority) bool {
+	for wp, n := range rateLimiter.waiting[host] {
+		if wp > p && n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyRateLimitChanged wakes up the waiting requests to check whether they can be sent.
+// The caller must hold the rateLimiter lock.
+func notifyRateLimitChanged() {
+	close(rateLimiter.changed)
+	rateLimiter.changed = make(chan struct{})
+}
+
+// visibleSymbols are the symbols on the screen during the last repaint, whose data is fetched first.
+var visibleSymbols = struct {
+	// Embedded mutex that guards the fields.
+	sync.Mutex
+
+	// m is the set of symbols.
+	m map[string]bool
+
+	// selected is the selected symbol, whose data is fetched before the others.
+	selected string
+}{}
+
+// setVisibleSymbols sets the symbols on the screen and the selected symbol.
+func setVisibleSymbols(symbols []string, selected string) {
+	m := map[string]bool{}
+	for _, s := range symbols {
+		m[s] = true
+	}
+	visibleSymbols.Lock()
+	visibleSymbols.m = m
+	visibleSymbols.selected = selected
+	visibleSymbols.Unlock()
+}
+
+// symbolPriority returns the request priority of the symbol's data depending on whether it is selected or on the screen.
+func symbolPriority(symbol string) requestPriority {
+	visibleSymbols.Lock()
+	defer visibleSymbols.Unlock()
+	switch {
+	case symbol == visibleSymbols.selected:
+		return selectedPriority
+	case visibleSymbols.m[symbol]:
+		return visiblePriority
+	}
+	return backgroundPriority
+}
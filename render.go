@@ -0,0 +1,742 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+)
+
+// resetColors sets the colors of the next cells back to the default colors.
+func (u *ui) resetColors() {
+	u.fg, u.bg = termbox.ColorDefault, termbox.ColorDefault
+}
+
+// setFgColor sets the foreground color to the color of the session's change.
+func (u *ui) setFgColor(ts stockTradingSession) {
+	u.fg = changeFgColor(ts.change)
+}
+
+// setBgColor sets the background color to the color of the session's change.
+func (u *ui) setBgColor(ts stockTradingSession) {
+	u.bg = u.colors.changeColor(ts)
+}
+
+// print prints the formatted text at the position and returns the x after it.
+func (u *ui) print(x, y int, format string, a ...interface{}) int {
+	for _, rune := range fmt.Sprintf(format, a...) {
+		term.setCell(x, y, rune, u.fg, u.bg)
+		x += runeWidth(rune)
+	}
+	return x
+}
+
+// printPopup prints the lines in a box in the center of the screen.
+func (u *ui) printPopup(w, h int, lines ...string) {
+	width := 0
+	for _, l := range lines {
+		if len(l) > width {
+			width = len(l)
+		}
+	}
+
+	u.fg, u.bg = termbox.ColorWhite, termbox.ColorBlue
+	ps := strings.Repeat(" ", padding)
+	pr := ps + strings.Repeat(" ", width) + ps
+	cx, cy := w/2-len(pr)/2, h/2-len(lines)/2-1
+	u.print(cx, cy, pr)
+	for i, l := range lines {
+		u.print(cx, cy+i+1, "%s%-[2]*s%s", ps, width, l, ps)
+	}
+	u.print(cx, cy+len(lines)+1, pr)
+}
+
+// printTransactionPopup prints the transaction popup for the selected stock.
+func (u *ui) printTransactionPopup(w, h int) {
+	u.sd.RLock()
+	symbol := u.sd.stocks[u.selectedIndex].symbol
+	u.sd.RUnlock()
+
+	lines := []string{
+		fmt.Sprintf("%s: %s_", symbol, u.txInput),
+		"buy|sell QTY PRICE [YYYY-MM-DD] [LOT], Esc: Cancel",
+	}
+	if u.txStatus != "" {
+		lines = append(lines, u.txStatus)
+	}
+	u.printPopup(w, h, lines...)
+}
+
+// render draws the open view, the selected stock's detail view, or the grid and its popups.
+func (u *ui) render(w, h int) {
+	switch {
+	case u.openView != nil:
+		u.openView.render(w, h)
+	case u.detailOpen:
+		u.renderDetail(w, h)
+	default:
+		u.renderGrid(w, h)
+		u.renderPopups(w, h)
+	}
+}
+
+// renderDetail draws the chart of the selected stock and its range and layout keys.
+func (u *ui) renderDetail(w, h int) {
+	u.sd.RLock()
+	s := u.sd.stocks[u.selectedIndex]
+	tss, mas := chartData(s.tradingSessionMap, u.detailStart, u.detailEnd, u.detailOptions)
+	layouts := u.sd.layouts
+	u.sd.RUnlock()
+
+	u.resetColors()
+	x := u.print(0, 0, " %s %s ", s.symbol, u.detailLabel)
+	x = u.print(x, 0, "%s - %s ", u.detailStart.Format("1/2/06"), u.detailEnd.Format("1/2/06"))
+	for i, p := range u.detailOptions.movingAverages {
+		u.fg = movingAverageColors[i%len(movingAverageColors)]
+		x = u.print(x, 0, " SMA %d", p)
+	}
+	u.resetColors()
+	if u.detailOptions.logScale {
+		x = u.print(x, 0, " Log")
+	}
+
+	// Show the analysts' mean price target's upside or downside from the latest close.
+	u.sd.RLock()
+	var price float64
+	if ts, ok := latestTradingSession(s.tradingSessionMap); ok {
+		price = ts.close
+	}
+	label := analystLabel(s.analyst, price)
+	u.sd.RUnlock()
+	if label != "" {
+		x = u.print(x, 0, "  %s", label)
+	}
+
+	// Explain why the chart may be missing or out of date.
+	if s.fetchError != "" {
+		u.fg = termbox.ColorRed
+		u.print(x, 0, "  %s", s.fetchError)
+		u.resetColors()
+	}
+
+	u.sd.RLock()
+	precision := pricePrecision(s, u.sd.assetPrecisions)
+	u.sd.RUnlock()
+	printChart(0, 2, w-padding, h-5, tss, mas, precision, u.detailOptions.logScale, u.detailMarkDate)
+
+	x = 0
+	for _, cr := range chartRanges {
+		x = u.print(x, h-2, " %c:%s", unicode.ToUpper(cr.key), cr.label)
+	}
+	u.print(x, h-2, "  C:Custom  I:Indicators  L:Log  S:Save  Esc:Back")
+
+	x = 0
+	for i, cl := range layouts {
+		if i >= 9 {
+			break
+		}
+		x = u.print(x, h-1, " %d:%s", i+1, cl.name)
+	}
+
+	// Print out the layout name input in the center of the screen.
+	if u.layoutNameOpen {
+		u.printPopup(w, h, fmt.Sprintf("Layout Name: %s_", u.layoutName), "Enter: Save, Esc: Cancel")
+	}
+
+	// Print out the custom range input in the center of the screen.
+	if u.detailInputOpen {
+		lines := []string{
+			fmt.Sprintf("Range: %s_", u.detailInput),
+			"YYYY-MM-DD [YYYY-MM-DD], Esc: Cancel",
+		}
+		if u.detailStatus != "" {
+			lines = append(lines, u.detailStatus)
+		}
+		u.printPopup(w, h, lines...)
+	}
+}
+
+// renderHeader draws the indexes, market status, and exchanges at the top of the grid.
+// The stock data must be read locked.
+func (u *ui) renderHeader(w int) {
+	x := 0
+
+	printIndex := func(symbol string, ts stockTradingSession) int {
+		u.resetColors()
+		x = u.print(x, 0, " %s ", symbol)
+
+		u.setBgColor(ts)
+		x = u.print(x, 0, " %s ", formatNumber(ts.close, defaultPrecision))
+		x = u.print(x, 0, "%s %s%% ", markChange(formatChange(ts.change, defaultPrecision), ts.change), markChange(fmt.Sprintf("%+.2f", ts.percentChange*100.0), ts.change))
+		return x
+	}
+
+	// Show the index futures instead if they were fetched outside market hours.
+	for _, idx := range []struct {
+		label  string
+		symbol string
+		ts     stockTradingSession
+	}{
+		{"DOW", dowSymbol, u.sd.dow},
+		{"S&P", sapSymbol, u.sd.sap},
+		{"NASDAQ", nasdaqSymbol, u.sd.nasdaq},
+	} {
+		if ts, ok := u.sd.futures[idx.symbol]; ok {
+			x = printIndex(idx.label+" FUT", ts)
+			continue
+		}
+		x = printIndex(idx.label, idx.ts)
+	}
+
+	u.resetColors()
+	s := formatDisplayTime(u.sd.refreshTime, "1/2/06 3:04 PM")
+	if *offline {
+		u.fg = termbox.ColorMagenta
+		s = "OFFLINE " + s
+	}
+	rx := w - len(s)
+	u.print(rx, 0, s)
+
+	// Print the market status badge and countdown to the left of the refresh time.
+	now := clk.now()
+	status, next := getMarketStatus(now)
+	u.resetColors()
+	if c := marketCountdown(status, next, now); c != "" && rx-len(c)-2 > x {
+		rx -= len(c) + 2
+		u.print(rx, 0, " %s ", c)
+	}
+	switch status {
+	case marketOpen:
+		u.fg, u.bg = termbox.ColorBlack, termbox.ColorGreen
+	case marketPre, marketAfter:
+		u.fg, u.bg = termbox.ColorBlack, termbox.ColorYellow
+	default:
+		u.fg, u.bg = termbox.ColorWhite, termbox.ColorRed
+	}
+	badge := " " + status.String() + " "
+	rx -= len(badge)
+	if rx > x {
+		u.print(rx, 0, badge)
+	}
+	u.resetColors()
+
+	// Print the sector ETFs' changes in place of the exchanges' delays if toggled.
+	x = 0
+	if u.sectorStrip {
+		for _, symbol := range displaySectorETFs(u.sd.sectorETFs) {
+			ts, ok := u.sd.sectorETFSessions[symbol]
+			if !ok || x >= w {
+				continue
+			}
+			u.resetColors()
+			x = u.print(x, 1, " %s ", symbol)
+			u.setBgColor(ts)
+			x = u.print(x, 1, "%+.2f%%", ts.percentChange*100.0)
+		}
+	} else {
+		// Print whether each exchange's quotes are real-time or delayed.
+		for _, e := range sortedExchanges(u.sd.exchanges) {
+			u.resetColors()
+			x = u.print(x, 1, " %s ", e)
+
+			switch d, ok := exchangeDelay(e); {
+			case ok && d == 0:
+				u.fg = termbox.ColorGreen
+				x = u.print(x, 1, "real-time ")
+			case ok:
+				u.fg = termbox.ColorYellow
+				x = u.print(x, 1, "delayed %s ", exchangeDelayLabel(e))
+			default:
+				x = u.print(x, 1, "delay unknown ")
+			}
+
+			// Show when international exchanges are closed since their hours differ from the US.
+			if !isUSExchange(e) && !isExchangeOpen(e, clk.now()) {
+				u.fg = termbox.ColorRed
+				x = u.print(x, 1, "closed ")
+			}
+		}
+	}
+	u.resetColors()
+
+	if u.sd.account != nil {
+		s := fmt.Sprintf("Buying Power %s Cash %s Value %s",
+			formatNumber(u.sd.account.buyingPower, 2), formatNumber(u.sd.account.cash, 2), formatNumber(u.sd.account.portfolioValue, 2))
+		u.print(w-len(s), 1, s)
+	}
+}
+
+// renderGrid draws the dates, the stocks' cells, and the summary of the selected date.
+func (u *ui) renderGrid(w, h int) {
+	u.sd.RLock()
+	defer u.sd.RUnlock()
+
+	if !u.sd.refreshTime.IsZero() {
+		u.renderHeader(w)
+	}
+
+	// Use the daily sessions for the performance periods even if the grid is aggregated.
+	periods, today := displayPeriods(u.sd.periods), chartToday()
+
+	// Combine the stocks' sessions into weekly or monthly ones if the grid is aggregated.
+	stocks, allDates := u.sd.stocks, u.sd.tradingDates
+	if u.gridAggregation != dailyAggregation {
+		stocks = make([]stock, len(u.sd.stocks))
+		for i, s := range u.sd.stocks {
+			stocks[i] = s
+			stocks[i].tradingSessionMap = aggregateSessions(s.tradingSessionMap, u.gridAggregation)
+		}
+		allDates = aggregateDates(stocks)
+	}
+
+	// dateColumnLeft is the left edge of the date columns after the symbol and performance columns.
+	const dateColumnLeft = symbolColumnWidth + padding + perfColumnWidth + padding
+
+	// Trim down trading dates to what fits the screen.
+	tsColumnCount := (w - dateColumnLeft) / (tsColumnWidth + padding)
+	if tsColumnCount > len(allDates) {
+		tsColumnCount = len(allDates)
+	}
+	if tsColumnCount < 0 {
+		tsColumnCount = 0
+	}
+	tradingDates := allDates[len(allDates)-tsColumnCount:]
+	u.visibleDates = tradingDates
+
+	// Move the selected date onto the screen if a resize left it off the left edge.
+	if !u.selectedDate.IsZero() && len(tradingDates) > 0 && u.selectedDate.Before(tradingDates[0]) {
+		u.selectedDate = tradingDates[0]
+	}
+
+	// Print out the performance column's header and the dates at the top.
+	u.fg, u.bg = termbox.ColorDefault, termbox.ColorDefault
+	u.print(symbolColumnWidth+padding*2, 3, "%[1]*s", perfColumnWidth, "Perf")
+
+	x := dateColumnLeft + padding
+	for _, td := range tradingDates {
+		if x+tsColumnWidth+padding > w {
+			break
+		}
+
+		u.bg = termbox.ColorDefault
+		if u.gridAggregation == dailyAggregation {
+			u.bg = u.colors.color(weekdayColors[td.Weekday()])
+		}
+
+		u.fg = termbox.ColorDefault
+		if td.Equal(u.selectedDate) {
+			u.fg = termbox.ColorYellow | termbox.AttrBold
+		}
+
+		l1, l2 := u.gridAggregation.dateLabels(td)
+		u.print(x, 2, "%[1]*s", tsColumnWidth, l1)
+		u.print(x, 3, "%[1]*s", tsColumnWidth, l2)
+
+		// Mark the dates of economic events that can move the whole market.
+		if u.gridAggregation == dailyAggregation && len(getEconEvents(td)) > 0 {
+			u.fg = termbox.ColorMagenta | termbox.AttrBold
+			u.print(x, 2, "◆")
+		}
+		x = x + tsColumnWidth + padding
+	}
+
+	// startY is the row after the refresh time(1) + padding(1) + date(2) + padding(1)
+	const startY = 5
+
+	// rowHeight is the height of the rows including the day's range line if it is shown.
+	rowHeight := tsColumnHeight
+	if *dayRange {
+		rowHeight++
+	}
+
+	// getY gets the row's top y.
+	getY := func(row int) int {
+		return startY + (rowHeight+padding)*row
+	}
+
+	// bottom is the row after the last row for stocks which leaves room for the footer if needed.
+	bottom := h
+	if !u.selectedDate.IsZero() {
+		bottom = h - 1
+	}
+
+	// Reset the offset when the height changes to keep the screen filled.
+	if h != u.prevHeight {
+		u.symbolOffset = 0
+	}
+	u.prevHeight = h
+
+	// shown are the indexes of the stocks that match the filter.
+	var shown []int
+	for i, s := range stocks {
+		if matchesFilter(s, u.filter) {
+			shown = append(shown, i)
+		}
+	}
+
+	// pinnedCount is the number of pinned stocks at the front that stay at the top while scrolling.
+	// Pinned stocks scroll like the rest if they leave no room for an unpinned stock.
+	pinnedCount := 0
+	for pinnedCount < len(shown) && stocks[shown[pinnedCount]].pinned {
+		pinnedCount++
+	}
+	for pinnedCount > 0 && getY(pinnedCount+1) > bottom {
+		pinnedCount--
+	}
+
+	// selectedRow is the selected stock's position among the shown stocks.
+	selectedRow := 0
+	for j, si := range shown {
+		if si == u.selectedIndex {
+			selectedRow = j
+		}
+	}
+
+	// Adjust the offset so that the selected stock is visible below the pinned stocks.
+	if pinnedCount+u.symbolOffset > len(shown) {
+		u.symbolOffset = 0
+	}
+	if selectedRow >= pinnedCount {
+		for u.symbolOffset > 0 && selectedRow-u.symbolOffset < pinnedCount {
+			u.symbolOffset--
+		}
+		for getY(selectedRow-u.symbolOffset+1) > bottom {
+			u.symbolOffset++
+		}
+	}
+
+	// rows are the indexes of the stocks in the order they are printed.
+	rows := append(shown[:pinnedCount:pinnedCount], shown[pinnedCount+u.symbolOffset:]...)
+
+	// Print the filter above the symbols so it is clear that some stocks are hidden.
+	if u.filter != "" {
+		u.fg, u.bg = termbox.ColorYellow, termbox.ColorDefault
+		u.print(padding, 2, "/%s", u.filter)
+	}
+
+	// rowCount is the number of stock rows that fit on the screen.
+	rowCount := 0
+
+	// Print out the symbols and the trading session cells.
+	for i, si := range rows {
+		s := stocks[si]
+		x, y := padding, getY(i)
+		if y+rowHeight+padding > bottom {
+			break
+		}
+		rowCount++
+
+		if si == u.selectedIndex {
+			u.fg = termbox.ColorYellow | termbox.AttrBold
+		} else {
+			u.fg = termbox.ColorDefault
+		}
+		u.bg = termbox.ColorDefault
+
+		// Underline the pinned symbols to set them apart from the scrolling ones.
+		if s.pinned {
+			u.fg |= termbox.AttrUnderline
+		}
+
+		// Highlight the symbols marked to compare.
+		if u.compareMarks[s.symbol] {
+			u.bg = termbox.ColorBlue
+		}
+
+		u.print(x, y, "%[1]*s", symbolColumnWidth, s.symbol)
+		u.bg = termbox.ColorDefault
+
+		// Flag the stocks with recent SEC filings to the left of the symbol.
+		if hasRecentFiling(s.filings, clk.now()) {
+			u.fg = termbox.ColorMagenta | termbox.AttrBold
+			u.print(x-1, y, "§")
+		}
+
+		// Print how delayed the quotes are under the symbol if they are not real-time.
+		// Warn instead if the symbol can never be served by the data source.
+		if err := checkSymbol(s.symbol, stockSource(s.symbol, s.source)); err != nil {
+			u.fg = termbox.ColorRed
+			u.print(x, y+3, "%[1]*s", symbolColumnWidth, "N/A")
+		} else if s.fetchError != "" {
+			u.fg = termbox.ColorRed
+			u.print(x, y+3, "%[1]*s", symbolColumnWidth, "ERR")
+		} else if now := clk.now(); s.cached || now.Sub(s.updateTime) > staleAge && !s.updateTime.IsZero() {
+			u.fg = termbox.ColorMagenta
+			u.print(x, y+3, "%[1]*s", symbolColumnWidth, ageLabel(s.updateTime, now))
+		} else if e, ok := u.sd.exchanges[s.symbol]; ok {
+			if d, ok := exchangeDelay(e); !ok || d != 0 {
+				u.fg = termbox.ColorYellow
+				u.print(x, y+3, "%[1]*s", symbolColumnWidth, exchangeDelayLabel(e))
+			}
+		}
+
+		// Print the quantity and unrealized gain or loss of any position under the symbol.
+		// Prefer the Alpaca position over the user's lots since it is more current.
+		var quantity, gain float64
+		if p, ok := u.sd.positions[s.symbol]; ok {
+			quantity, gain = p.quantity, p.unrealizedPL
+		} else if len(s.lots) > 0 {
+			quantity = totalQuantity(s.lots)
+			if ts, ok := latestTradingSession(s.tradingSessionMap); ok {
+				gain = ts.close*quantity - totalCost(s.lots)
+			}
+		}
+		if quantity != 0 {
+			u.fg = termbox.ColorDefault
+			u.print(x, y+1, "%[1]*s", symbolColumnWidth, shortenNumber(quantity))
+			u.fg = changeFgColor(gain)
+			u.print(x, y+2, "%+[1]*.0f", symbolColumnWidth, gain)
+		}
+
+		x = x + symbolColumnWidth + padding
+
+		// Print the changes over the performance periods like YTD in the fixed column.
+		u.bg = termbox.ColorDefault
+		for j, label := range periods {
+			if c, ok := periodChange(u.sd.stocks[si].tradingSessionMap, label, today); ok {
+				u.fg = changeFgColor(c)
+				u.print(x, y+j, "%-3s%+[2]*.1f%%", label, perfColumnWidth-4, c*100.0)
+			} else {
+				u.fg = termbox.ColorDefault
+				u.print(x, y+j, "%-3s%[2]*s", label, perfColumnWidth-3, "--")
+			}
+		}
+
+		// Print the plugins' values in the rows left after the periods.
+		u.fg = termbox.ColorDefault
+		for j, pv := range s.pluginValues {
+			if len(periods)+j >= rowHeight {
+				break
+			}
+			name, text := pv.name, pv.text
+			if len(name) > 3 {
+				name = name[:3]
+			}
+			if len(text) > perfColumnWidth-3 {
+				text = text[:perfColumnWidth-3]
+			}
+			u.print(x, y+len(periods)+j, "%-3s%[2]*s", name, perfColumnWidth-3, text)
+		}
+
+		x = x + perfColumnWidth + padding
+
+		// vol scales the change colors if they are relative to the stock's volatility.
+		// Aggregated sessions are combined at each repaint, so their volatility is too.
+		vol := s.volatility
+		if *relativeColors && u.gridAggregation != dailyAggregation {
+			vol = volatility(s.tradingSessionMap)
+		}
+
+		for _, td := range tradingDates {
+			if x+tsColumnWidth+padding > w {
+				break
+			}
+
+			// hl is the attribute to highlight the selected stock's cell on the selected date.
+			// All the stocks' cells on the selected date are highlighted in column highlight mode.
+			var hl termbox.Attribute
+			if (u.highlightColumn || si == u.selectedIndex) && td.Equal(u.selectedDate) {
+				hl = termbox.AttrReverse
+			}
+
+			if ts, ok := s.tradingSessionMap[td]; ok {
+				u.fg = termbox.ColorDefault | hl
+
+				// Print price and volume in default color.
+				u.setBgColor(colorSession(ts, vol))
+				u.print(x, y, "%[1]*s", tsColumnWidth, fitNumber(formatNumber(ts.close, pricePrecision(s, u.sd.assetPrecisions)), tsColumnWidth))
+				if *accessible {
+					u.print(x, y+3, "%-3s%[2]*s", changeMarker(colorSession(ts, vol)), tsColumnWidth-3, shortenInt(ts.volume))
+				} else {
+					u.print(x, y+3, "%[1]*s", tsColumnWidth, shortenInt(ts.volume))
+				}
+
+				// Mark the split that the earlier sessions were adjusted for.
+				if sp, ok := splitOn(s.splits, td); ok {
+					u.fg = termbox.ColorYellow | termbox.AttrBold | hl
+					u.print(x, y+3, "%s", sp.label())
+				}
+
+				// Print change and % change in green or red.
+				u.setFgColor(ts)
+				u.fg |= hl
+				u.print(x, y+1, "%[1]*s", tsColumnWidth, fitNumber(markChange(formatChange(ts.change, pricePrecision(s, u.sd.assetPrecisions)), ts.change), tsColumnWidth))
+				u.print(x, y+2, "%[1]*s%%", tsColumnWidth-1, markChange(fmt.Sprintf("%+.2f", ts.percentChange*100.0), ts.change))
+
+				// Print where the close is in the day's range.
+				if *dayRange {
+					u.fg = termbox.ColorDefault | hl
+					u.print(x, y+4, "%[1]*s", tsColumnWidth, dayRangeBar(ts, tsColumnWidth))
+				}
+			} else {
+				u.fg = termbox.ColorDefault | hl
+				u.bg = u.colors.color(placeholderColor)
+				for i := 0; i < rowHeight; i++ {
+					u.print(x, y+i, strings.Repeat(" ", tsColumnWidth))
+				}
+			}
+			x = x + tsColumnWidth + padding
+		}
+	}
+
+	// Remember the symbols on the screen to fetch their data first.
+	var onScreen []string
+	for _, si := range rows[:rowCount] {
+		onScreen = append(onScreen, stocks[si].symbol)
+	}
+	var selected string
+	if u.selectedIndex >= 0 && u.selectedIndex < len(stocks) {
+		selected = stocks[u.selectedIndex].symbol
+	}
+	setVisibleSymbols(onScreen, selected)
+
+	// Print out borders in the padding between the dates and cells.
+	if *borders {
+		left := dateColumnLeft
+		xs := []int{left}
+		right := left
+		for range tradingDates {
+			if right+tsColumnWidth+padding*2 > w {
+				break
+			}
+			right += tsColumnWidth + padding
+			xs = append(xs, right)
+		}
+
+		ys := []int{startY - padding}
+		for i := 0; i < rowCount; i++ {
+			ys = append(ys, getY(i)+rowHeight)
+		}
+
+		printBorders(xs, ys, 0, 2, right+1, getY(rowCount))
+	}
+
+	// Print out a summary of the selected date at the bottom.
+	if !u.selectedDate.IsZero() {
+		ds := summarizeDay(stocks, u.selectedDate)
+
+		colorChange := func(v float64) {
+			u.fg = changeFgColor(v)
+		}
+
+		u.resetColors()
+		x := u.print(0, h-1, " %s  Avg ", u.selectedDate.Format("Mon 1/2/06"))
+		colorChange(ds.avgPercentChange)
+		x = u.print(x, h-1, "%+.2f%%", ds.avgPercentChange*100.0)
+		u.resetColors()
+		x = u.print(x, h-1, "  Adv ")
+		u.fg = upColor
+		x = u.print(x, h-1, "%d", ds.advancers)
+		u.resetColors()
+		x = u.print(x, h-1, " Dec ")
+		u.fg = downColor
+		x = u.print(x, h-1, "%d", ds.decliners)
+		u.resetColors()
+		if ds.biggestMover != "" {
+			x = u.print(x, h-1, "  Biggest %s ", ds.biggestMover)
+			colorChange(ds.biggestMove.percentChange)
+			x = u.print(x, h-1, "%+.2f%%", ds.biggestMove.percentChange*100.0)
+		}
+		for _, e := range getEconEvents(u.selectedDate) {
+			u.fg = termbox.ColorMagenta | termbox.AttrBold
+			x = u.print(x, h-1, "  ◆ %s", e.name)
+			u.resetColors()
+			if e.description != "" {
+				x = u.print(x, h-1, ": %s", e.description)
+			}
+		}
+	}
+}
+
+// renderPopups draws the popups that are open over the grid.
+func (u *ui) renderPopups(w, h int) {
+	// Print out the input symbol in the center of the screen.
+	if u.inputSymbol != "" {
+		if u.inputStatus != "" {
+			u.printPopup(w, h, u.inputSymbol, u.inputStatus)
+		} else {
+			u.printPopup(w, h, u.inputSymbol)
+		}
+	}
+
+	// Print out the command input in the center of the screen.
+	if u.commandOpen {
+		lines := []string{fmt.Sprintf(":%s_", u.command), "Enter: Run, Esc: Cancel, help: Commands"}
+		if u.commandStatus != "" {
+			lines = append(lines, u.commandStatus)
+		}
+		u.printPopup(w, h, lines...)
+	}
+
+	// Print out the filter input in the center of the screen.
+	if u.filterOpen {
+		u.printPopup(w, h, fmt.Sprintf("Filter: %s_", u.filter), "Enter: Keep, Esc: Clear")
+	}
+
+	// Print out the order popup in the center of the screen.
+	if u.orderOpen {
+		u.sd.RLock()
+		symbol := u.sd.stocks[u.selectedIndex].symbol
+		u.sd.RUnlock()
+
+		lines := []string{
+			fmt.Sprintf("%s %s: %s_", strings.ToUpper(string(u.orderSide)), symbol, u.orderInput),
+			"Qty or Qty@Limit, Tab: Buy/Sell, Esc: Cancel",
+		}
+		if u.orderConfirm != nil {
+			lines = []string{
+				strings.ToUpper(u.orderConfirm.String()),
+				"Enter: Submit, Esc: Cancel",
+			}
+		}
+		if u.orderStatus != "" {
+			lines = append(lines, u.orderStatus)
+		}
+		u.printPopup(w, h, lines...)
+	}
+
+	// Print out the transaction popup in the center of the screen.
+	if u.txOpen {
+		u.printTransactionPopup(w, h)
+	}
+
+	// Print out the backtest popup in the center of the screen.
+	if u.backtestOpen {
+		lines := []string{
+			fmt.Sprintf("Allocation: %s_", u.backtestInput),
+			"SYMBOL=WEIGHT ..., Enter: Run, Esc: Close",
+		}
+		u.printPopup(w, h, append(lines, u.backtestLines...)...)
+	}
+
+	// Print out the selected stock's SEC filings in the center of the screen.
+	if u.filingsOpen {
+		u.sd.RLock()
+		if len(u.sd.stocks) > 0 {
+			u.printPopup(w, h, filingLines(u.sd.stocks[u.selectedIndex])...)
+		}
+		u.sd.RUnlock()
+	}
+
+	// Print out the selected stock's quotes observed today in the center of the screen.
+	if u.timelineOpen {
+		u.sd.RLock()
+		var symbol string
+		if len(u.sd.stocks) > 0 {
+			symbol = u.sd.stocks[u.selectedIndex].symbol
+		}
+		u.sd.RUnlock()
+		if symbol != "" {
+			u.printPopup(w, h, timelineLines(symbol, journalTimeline(symbol))...)
+		}
+	}
+
+	// Print out the stats overlay in the center of the screen.
+	if u.statsOpen {
+		u.sd.RLock()
+		lines := statsLines(u.sd)
+		u.sd.RUnlock()
+		u.printPopup(w, h, lines...)
+	}
+}
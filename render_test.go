@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// update is a flag to rewrite the golden files with the screens drawn by the tests.
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata with the current screens.")
+
+// fixtureStockData returns a small watchlist with a week of sessions to draw.
+func fixtureStockData() *stockData {
+	day := func(d int) time.Time { return time.Date(2020, 8, d, 0, 0, 0, 0, time.UTC) }
+	dates := []time.Time{day(24), day(25), day(26), day(27), day(28)}
+
+	sessions := func(closes ...float64) map[time.Time]stockTradingSession {
+		tsm := map[time.Time]stockTradingSession{}
+		for i, c := range closes {
+			ts := stockTradingSession{date: dates[i], close: c, volume: int64(1000000 * (i + 1))}
+			if i > 0 {
+				ts.change = c - closes[i-1]
+				ts.percentChange = ts.change / closes[i-1]
+			}
+			ts.open, ts.high, ts.low = c, c+1, c-1
+			tsm[dates[i]] = ts
+		}
+		return tsm
+	}
+
+	index := func(close, change float64) stockTradingSession {
+		return stockTradingSession{date: day(28), close: close, change: change, percentChange: change / (close - change)}
+	}
+
+	return &stockData{
+		refreshTime:  time.Date(2020, 8, 28, 16, 30, 0, 0, newYorkLoc),
+		tradingDates: dates,
+		stocks: []stock{
+			{symbol: "SPY", pinned: true, tradingSessionMap: sessions(342.92, 343.92, 347.57, 348.33, 350.58)},
+			{symbol: "AAPL", tradingSessionMap: sessions(503.43, 499.30, 506.09, 500.04, 499.23)},
+			{symbol: "MSFT", tradingSessionMap: sessions(213.69, 216.47, 221.15, 226.58, 228.91)},
+			{symbol: "TSLA", tradingSessionMap: sessions(2014.20, 2023.34, 2049.35, 2097.92, 2213.40)},
+		},
+		dow:    index(28653.87, 161.60),
+		sap:    index(3508.01, 23.46),
+		nasdaq: index(11695.63, 70.30),
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	useClock(t, newFakeClock(time.Date(2020, 8, 28, 17, 0, 0, 0, newYorkLoc)))
+
+	oldLoc := displayLoc
+	displayLoc = newYorkLoc
+	defer func() { displayLoc = oldLoc }()
+
+	for _, tt := range []struct {
+		name  string
+		w, h  int
+		setUp func(u *ui)
+	}{
+		{
+			name: "grid",
+			w:    160,
+			h:    24,
+		},
+		{
+			name: "grid_narrow",
+			w:    60,
+			h:    12,
+		},
+		{
+			name: "grid_selected_date",
+			w:    120,
+			h:    24,
+			setUp: func(u *ui) {
+				u.selectedIndex = 1
+				u.selectedDate = time.Date(2020, 8, 27, 0, 0, 0, 0, time.UTC)
+			},
+		},
+		{
+			name: "grid_filter",
+			w:    120,
+			h:    24,
+			setUp: func(u *ui) {
+				u.filter = "S"
+			},
+		},
+		{
+			name: "command_popup",
+			w:    120,
+			h:    24,
+			setUp: func(u *ui) {
+				u.commandOpen, u.command = true, "sort change"
+			},
+		},
+		{
+			name: "detail",
+			w:    80,
+			h:    20,
+			setUp: func(u *ui) {
+				u.selectedIndex = 2
+				u.detailOpen = true
+				u.detailLabel = "1W"
+				u.detailStart = time.Date(2020, 8, 24, 0, 0, 0, 0, time.UTC)
+				u.detailEnd = time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC)
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			oldTerm := term
+			term = newBufferScreen(tt.w, tt.h, &buf)
+			defer func() { term = oldTerm }()
+
+			u := newUI(context.Background(), fixtureStockData(), normalColors)
+			if tt.setUp != nil {
+				tt.setUp(u)
+			}
+			u.render(tt.w, tt.h)
+			if err := term.flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("screen differs from %s, rerun with -update if the change is intended:\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// benchmarkSymbol is a flag to set the symbol that betas are calculated against.
+var benchmarkSymbol = flag.String("benchmark", "SPY", "Symbol of the benchmark used to calculate betas in the risk view.")
+
+// tradingDaysPerYear is used to annualize daily volatility.
+const tradingDaysPerYear = 252
+
+// riskMetrics are risk statistics calculated from daily closing prices.
+type riskMetrics struct {
+	// days is the number of daily returns the metrics are based on.
+	days int
+
+	// volatility is the annualized standard deviation of the daily returns.
+	volatility float64
+
+	// beta is the sensitivity of the daily returns to the benchmark's daily returns.
+	// It is NaN if there is not enough overlapping benchmark data.
+	beta float64
+
+	// maxDrawdown is the largest peak to trough decline as a positive fraction.
+	maxDrawdown float64
+}
+
+// dailyReturns returns a map from date to the change in closing price from the previous session.
+func dailyReturns(tsm map[time.Time]stockTradingSession) map[time.Time]float64 {
+	tss := chartTradingSessions(tsm, time.Time{}, chartToday())
+	rm := map[time.Time]float64{}
+	for i := 1; i < len(tss); i++ {
+		if tss[i-1].close != 0 {
+			rm[tss[i].date] = tss[i].close/tss[i-1].close - 1
+		}
+	}
+	return rm
+}
+
+// calculateRisk calculates the risk metrics of the daily returns against the benchmark's daily returns.
+func calculateRisk(returns, benchmark map[time.Time]float64) riskMetrics {
+	var dates sortableTimes
+	for date := range returns {
+		dates = append(dates, date)
+	}
+	sort.Sort(dates)
+
+	rm := riskMetrics{days: len(dates), beta: math.NaN()}
+	if len(dates) < 2 {
+		return rm
+	}
+
+	// Calculate the volatility and drawdown from the chronological returns.
+	var sum, sumSq float64
+	value, peak := 1.0, 1.0
+	for _, date := range dates {
+		r := returns[date]
+		sum += r
+		sumSq += r * r
+
+		value *= 1 + r
+		peak = math.Max(peak, value)
+		rm.maxDrawdown = math.Max(rm.maxDrawdown, 1-value/peak)
+	}
+	n := float64(len(dates))
+	variance := (sumSq - sum*sum/n) / (n - 1)
+	rm.volatility = math.Sqrt(math.Max(variance, 0) * tradingDaysPerYear)
+
+	// Calculate the beta from the dates that both have returns.
+	var xs, ys []float64
+	for _, date := range dates {
+		if b, ok := benchmark[date]; ok {
+			xs = append(xs, b)
+			ys = append(ys, returns[date])
+		}
+	}
+	if len(xs) >= 2 {
+		var mx, my float64
+		for i := range xs {
+			mx += xs[i]
+			my += ys[i]
+		}
+		mx /= float64(len(xs))
+		my /= float64(len(ys))
+
+		var cov, vx float64
+		for i := range xs {
+			cov += (xs[i] - mx) * (ys[i] - my)
+			vx += (xs[i] - mx) * (xs[i] - mx)
+		}
+		if vx != 0 {
+			rm.beta = cov / vx
+		}
+	}
+
+	return rm
+}
+
+// portfolioWeights returns a map from symbol to the fraction of the portfolio's market value.
+// Stocks are weighted equally if the user has no positions.
+func portfolioWeights(sd *stockData) map[string]float64 {
+	wm := map[string]float64{}
+	var total float64
+	for _, s := range sd.stocks {
+		var quantity float64
+		if p, ok := sd.positions[s.symbol]; ok {
+			quantity = p.quantity
+		} else {
+			quantity = totalQuantity(s.lots)
+		}
+
+		if ts, ok := latestTradingSession(s.tradingSessionMap); ok && quantity != 0 {
+			wm[s.symbol] += quantity * ts.close
+			total += quantity * ts.close
+		}
+	}
+
+	if total == 0 {
+		for _, s := range sd.stocks {
+			wm[s.symbol] = 1
+			total++
+		}
+	}
+
+	for symbol := range wm {
+		wm[symbol] /= total
+	}
+	return wm
+}
+
+// portfolioReturns returns the daily returns of the weighted stocks.
+// Weights are renormalized on dates when some stocks have no data.
+func portfolioReturns(stockReturns map[string]map[time.Time]float64, weights map[string]float64) map[time.Time]float64 {
+	sums, totals := map[time.Time]float64{}, map[time.Time]float64{}
+	for symbol, rm := range stockReturns {
+		w := weights[symbol]
+		if w == 0 {
+			continue
+		}
+		for date, r := range rm {
+			sums[date] += w * r
+			totals[date] += w
+		}
+	}
+
+	pm := map[time.Time]float64{}
+	for date, sum := range sums {
+		pm[date] = sum / totals[date]
+	}
+	return pm
+}
+
+// printRisk prints the risk metrics of each stock and the portfolio.
+// The caller must hold the stockData read lock.
+func printRisk(sd *stockData, w, h int) {
+	print := func(x, y int, fg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, termbox.ColorDefault)
+			x++
+		}
+		return x
+	}
+
+	formatBeta := func(beta float64) string {
+		if math.IsNaN(beta) {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", beta)
+	}
+
+	const format = " %-8s %6s %10s %8s %10s %8s"
+
+	benchmark := dailyReturns(sd.benchmark.tradingSessionMap)
+	weights := portfolioWeights(sd)
+
+	print(0, 0, termbox.ColorDefault, " Risk vs %s over the fetched window", *benchmarkSymbol)
+	print(0, 2, termbox.ColorDefault, format, "Symbol", "Days", "Volatility", "Beta", "Drawdown", "Weight")
+
+	stockReturns := map[string]map[time.Time]float64{}
+	y := 3
+	for _, s := range sd.stocks {
+		returns := dailyReturns(s.tradingSessionMap)
+		stockReturns[s.symbol] = returns
+		if y >= h-3 {
+			continue
+		}
+
+		rm := calculateRisk(returns, benchmark)
+		print(0, y, termbox.ColorDefault, format,
+			s.symbol,
+			fmt.Sprint(rm.days),
+			fmt.Sprintf("%.2f%%", rm.volatility*100),
+			formatBeta(rm.beta),
+			fmt.Sprintf("%.2f%%", -rm.maxDrawdown*100),
+			fmt.Sprintf("%.1f%%", weights[s.symbol]*100))
+		y++
+	}
+
+	rm := calculateRisk(portfolioReturns(stockReturns, weights), benchmark)
+	print(0, y+1, termbox.ColorDefault|termbox.AttrBold, format,
+		"Total",
+		fmt.Sprint(rm.days),
+		fmt.Sprintf("%.2f%%", rm.volatility*100),
+		formatBeta(rm.beta),
+		fmt.Sprintf("%.2f%%", -rm.maxDrawdown*100),
+		"100.0%")
+
+	print(0, h-1, termbox.ColorDefault, " Esc:Back")
+}
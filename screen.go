@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nsf/termbox-go"
+)
+
+// screenBackend is a flag to choose the library that draws on the terminal.
+var screenBackend = flag.String("screen", "auto", "Library to draw on the terminal with. Values: auto, termbox, tcell. Auto uses tcell on Windows and termbox elsewhere.")
+
+// screen is the terminal that the UI draws cells on and polls events from.
+// The UI calls the screen rather than termbox, so the termbox, tcell, and buffer screens can be swapped.
+// Attributes and events use the termbox types to keep the drawing code unchanged.
+type screen interface {
+	// init initializes the terminal for drawing.
+	init() error
+
+	// close restores the terminal.
+	close()
+
+	// setOutputMode sets the color mode and returns the mode the terminal supports.
+	setOutputMode(mode termbox.OutputMode) termbox.OutputMode
+
+	// size returns the width and height of the terminal.
+	size() (w, h int)
+
+	// clear clears the back buffer.
+	clear() error
+
+	// setCell sets a cell in the back buffer.
+	setCell(x, y int, ch rune, fg, bg termbox.Attribute)
+
+	// flush draws the back buffer on the terminal.
+	flush() error
+
+	// pollEvent waits for the next key, resize, or interrupt event.
+	pollEvent() termbox.Event
+
+	// interrupt queues an interrupt event to trigger a repaint.
+	interrupt()
+}
+
+// term is the screen that the UI is drawn on.
+var term screen = &termboxScreen{}
+
+// newScreen returns the screen of the backend with the name.
+func newScreen(name string) (screen, error) {
+	if name == "auto" {
+		name = defaultScreenBackend
+	}
+	switch name {
+	case "termbox":
+		return &termboxScreen{}, nil
+	case "tcell":
+		return &tcellScreen{}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized screen: %s", name)
+	}
+}
+
+// skipRepaints skips repaint requests that are followed by other events, since every event repaints everything.
+// It returns whether a skipped event was a resize, so the next flush still redraws the whole terminal.
+func skipRepaints(ev termbox.Event, events <-chan termbox.Event) (next termbox.Event, resized bool) {
+	for ev.Type == termbox.EventInterrupt || ev.Type == termbox.EventResize {
+		if ev.Type == termbox.EventResize {
+			resized = true
+		}
+		select {
+		case ev = <-events:
+		default:
+			return ev, resized
+		}
+	}
+	return ev, resized
+}
+
+// termboxScreen is a screen using termbox.
+//
+// Its flush only writes the cells that differ from what is on the terminal,
+// so clearing and redrawing everything each frame doesn't repaint the whole terminal.
+type termboxScreen struct {
+	// events receives the polled events in the background, so repaints don't wait on input.
+	events chan termbox.Event
+
+	// resized is whether the terminal was resized since the last flush.
+	resized bool
+
+	// outputMode is the color mode to restore when resuming after a suspend.
+	outputMode termbox.OutputMode
+}
+
+func (s *termboxScreen) init() error {
+	if err := termbox.Init(); err != nil {
+		return err
+	}
+
+	// Set to InputAlt so that ESC + Key enables the ModAlt flag for EventKey events.
+	// ModAlt does not mean the ALT key as typically expected.
+	termbox.SetInputMode(termbox.InputAlt)
+
+	s.events = make(chan termbox.Event, 64)
+	go func() {
+		for {
+			s.events <- termbox.PollEvent()
+		}
+	}()
+
+	// Treat requests to suspend from outside like Ctrl+Z, since the terminal must be restored first.
+	sigs := make(chan os.Signal, 1)
+	notifySuspend(sigs)
+	go func() {
+		for range sigs {
+			s.events <- termbox.Event{Type: termbox.EventKey, Key: termbox.KeyCtrlZ}
+		}
+	}()
+	return nil
+}
+
+func (*termboxScreen) close() {
+	termbox.Close()
+}
+
+func (s *termboxScreen) setOutputMode(mode termbox.OutputMode) termbox.OutputMode {
+	s.outputMode = termbox.SetOutputMode(mode)
+	return s.outputMode
+}
+
+func (*termboxScreen) size() (w, h int) {
+	return termbox.Size()
+}
+
+func (*termboxScreen) clear() error {
+	return termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+}
+
+func (s *termboxScreen) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	if s.outputMode == termbox.OutputRGB {
+		fg, bg = trueColorAttribute(fg), trueColorAttribute(bg)
+	}
+	termbox.SetCell(x, y, ch, fg, bg)
+}
+
+func (s *termboxScreen) flush() error {
+	// Redraw every cell after a resize, since the terminal may have wrapped or scrolled
+	// what was on it and only drawing the changed cells would leave a garbled frame.
+	if s.resized {
+		s.resized = false
+		return termbox.Sync()
+	}
+	return termbox.Flush()
+}
+
+func (s *termboxScreen) pollEvent() termbox.Event {
+	ev := <-s.events
+
+	// Suspend to the shell with Ctrl+Z, since the terminal's raw mode delivers it as a key rather than a signal.
+	// Report a resize when resumed, so the whole terminal is redrawn at its possibly new size.
+	if canSuspend && ev.Type == termbox.EventKey && ev.Key == termbox.KeyCtrlZ {
+		if err := s.suspend(); err != nil {
+			log.Printf("suspend: %v", err)
+		}
+		s.resized = true
+		return termbox.Event{Type: termbox.EventResize}
+	}
+
+	ev, resized := skipRepaints(ev, s.events)
+	s.resized = s.resized || resized
+	return ev
+}
+
+// suspend restores the terminal, stops the process until it is continued, and then reinitializes the terminal.
+// The polling goroutine keeps working across the restart, since termbox keeps its event channels.
+func (s *termboxScreen) suspend() error {
+	termbox.Close()
+	serr := suspendProcess()
+
+	if err := termbox.Init(); err != nil {
+		return err
+	}
+	termbox.SetInputMode(termbox.InputAlt)
+	if s.outputMode != termbox.OutputCurrent {
+		termbox.SetOutputMode(s.outputMode)
+	}
+	return serr
+}
+
+func (*termboxScreen) interrupt() {
+	termbox.Interrupt()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nsf/termbox-go"
+)
+
+func TestNewScreen(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		want    screen
+		wantErr bool
+	}{
+		{name: "termbox", want: &termboxScreen{}},
+		{name: "tcell", want: &tcellScreen{}},
+		{name: "curses", wantErr: true},
+	} {
+		got, err := newScreen(tt.name)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("newScreen(%q) error = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newScreen(%q) = %T, want %T", tt.name, got, tt.want)
+		}
+	}
+
+	// Windows uses tcell, since termbox misreads keys in Windows Terminal and ConPTY.
+	got, err := newScreen("auto")
+	if err != nil {
+		t.Fatalf("newScreen(auto) error = %v", err)
+	}
+	if _, isTcell := got.(*tcellScreen); isTcell != (runtime.GOOS == "windows") {
+		t.Errorf("newScreen(auto) = %T on %s", got, runtime.GOOS)
+	}
+}
+
+func TestTcellColor(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		attr termbox.Attribute
+		want tcell.Color
+	}{
+		{"default", termbox.ColorDefault, tcell.ColorDefault},
+		{"named color", termbox.ColorRed, tcell.ColorMaroon},
+		{"bright named color", termbox.ColorLightGreen, tcell.ColorLime},
+		{"named color with attributes", termbox.ColorGreen | termbox.AttrBold | termbox.AttrUnderline, tcell.ColorGreen},
+		{"palette color", termbox.Attribute(197), tcell.PaletteColor(196)},
+		{"rgb color", termbox.RGBToAttribute(12, 34, 56), tcell.NewRGBColor(12, 34, 56)},
+		{"black rgb color", termbox.RGBToAttribute(0, 0, 0), tcell.NewRGBColor(0, 0, 0)},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := tcellColor(tt.attr); got != tt.want {
+				t.Errorf("tcellColor(%#x) = %v, want %v", tt.attr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTcellStyle(t *testing.T) {
+	got := tcellStyle(termbox.ColorWhite|termbox.AttrBold|termbox.AttrReverse, termbox.ColorBlue)
+	want := tcell.StyleDefault.Foreground(tcell.ColorSilver).Background(tcell.ColorNavy).Attributes(tcell.AttrBold | tcell.AttrReverse)
+	if got != want {
+		t.Errorf("tcellStyle() = %v, want %v", got, want)
+	}
+}
+
+func TestTermboxEvent(t *testing.T) {
+	for _, tt := range []struct {
+		desc   string
+		tev    tcell.Event
+		want   termbox.Event
+		wantOK bool
+	}{
+		{
+			desc:   "rune",
+			tev:    tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+			want:   termbox.Event{Type: termbox.EventKey, Ch: 'a'},
+			wantOK: true,
+		},
+		{
+			desc:   "space",
+			tev:    tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone),
+			want:   termbox.Event{Type: termbox.EventKey, Key: termbox.KeySpace},
+			wantOK: true,
+		},
+		{
+			desc:   "alt arrow",
+			tev:    tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModAlt),
+			want:   termbox.Event{Type: termbox.EventKey, Key: termbox.KeyArrowUp, Mod: termbox.ModAlt},
+			wantOK: true,
+		},
+		{
+			desc:   "control key",
+			tev:    tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl),
+			want:   termbox.Event{Type: termbox.EventKey, Key: termbox.KeyCtrlC},
+			wantOK: true,
+		},
+		{
+			desc:   "enter",
+			tev:    tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+			want:   termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEnter},
+			wantOK: true,
+		},
+		{
+			desc:   "backspace",
+			tev:    tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone),
+			want:   termbox.Event{Type: termbox.EventKey, Key: termbox.KeyBackspace2},
+			wantOK: true,
+		},
+		{
+			desc:   "function key",
+			tev:    tcell.NewEventKey(tcell.KeyF12, 0, tcell.ModNone),
+			want:   termbox.Event{Type: termbox.EventKey, Key: termbox.KeyF12},
+			wantOK: true,
+		},
+		{
+			desc: "unknown key",
+			tev:  tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModShift),
+		},
+		{
+			desc:   "resize",
+			tev:    tcell.NewEventResize(80, 24),
+			want:   termbox.Event{Type: termbox.EventResize, Width: 80, Height: 24},
+			wantOK: true,
+		},
+		{
+			desc:   "interrupt",
+			tev:    tcell.NewEventInterrupt(nil),
+			want:   termbox.Event{Type: termbox.EventInterrupt},
+			wantOK: true,
+		},
+		{
+			desc: "mouse",
+			tev:  tcell.NewEventMouse(1, 1, tcell.Button1, tcell.ModNone),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, ok := termboxEvent(tt.tev)
+			if ok != tt.wantOK {
+				t.Fatalf("termboxEvent() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("termboxEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTcellScreen(t *testing.T) {
+	useClock(t, newFakeClock(time.Date(2020, 8, 28, 17, 0, 0, 0, newYorkLoc)))
+
+	oldLoc := displayLoc
+	displayLoc = newYorkLoc
+	defer func() { displayLoc = oldLoc }()
+
+	sim := tcell.NewSimulationScreen("UTF-8")
+	s := &tcellScreen{screen: sim}
+	if err := s.init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	defer s.close()
+	sim.SetSize(160, 24)
+
+	oldTerm := term
+	term = s
+	defer func() { term = oldTerm }()
+
+	// Draw the same screen as the buffer screen's grid golden test.
+	if err := s.clear(); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	u := newUI(context.Background(), fixtureStockData(), normalColors)
+	u.render(s.size())
+	if err := s.flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	cells, w, h := sim.GetContents()
+	var (
+		got    strings.Builder
+		styled bool
+	)
+	for y := 0; y < h; y++ {
+		var row []rune
+		for x := 0; x < w; x++ {
+			row = append(row, cells[y*w+x].Runes...)
+			styled = styled || cells[y*w+x].Style != tcell.StyleDefault
+		}
+		got.WriteString(strings.TrimRight(string(row), " ") + "\n")
+	}
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "grid.golden"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("screen differs from the buffer screen's:\ngot:\n%s\nwant:\n%s", got.String(), want)
+	}
+
+	// The colors are drawn too.
+	if !styled {
+		t.Error("screen only has the default style, want colors")
+	}
+
+	// Keys from the terminal arrive as termbox events.
+	sim.InjectKey(tcell.KeyDown, 0, tcell.ModAlt)
+	if got, want := s.pollEvent(), (termbox.Event{Type: termbox.EventKey, Key: termbox.KeyArrowDown, Mod: termbox.ModAlt}); got != want {
+		t.Errorf("pollEvent() = %+v, want %+v", got, want)
+	}
+
+	s.interrupt()
+	if got := s.pollEvent(); got.Type != termbox.EventInterrupt {
+		t.Errorf("pollEvent() = %+v, want an interrupt", got)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// defaultScreenBackend is the screen used unless -screen picks one.
+const defaultScreenBackend = "termbox"
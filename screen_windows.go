@@ -0,0 +1,6 @@
+package main
+
+// defaultScreenBackend is the screen used unless -screen picks one. tcell reads the console's
+// input records, so arrow keys and Alt combinations work in Windows Terminal and ConPTY,
+// and it uses 24-bit colors when the console supports VT sequences.
+const defaultScreenBackend = "tcell"
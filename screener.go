@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// screenerIDs is a map from the screener names that can be typed to Yahoo's predefined screener IDs.
+var screenerIDs = map[string]string{
+	"gainers": "day_gainers",
+	"losers":  "day_losers",
+	"active":  "most_actives",
+}
+
+// screenerQuote is a stock found by a screener.
+type screenerQuote struct {
+	symbol        string
+	name          string
+	price         float64
+	percentChange float64
+	marketCap     float64
+	pe            float64
+}
+
+// screenerFilter is a condition like "pe<15" that the screener's quotes must meet.
+type screenerFilter struct {
+	field string
+	less  bool
+	value float64
+}
+
+// parseScreenerFilter parses a filter like "pe<15" or "cap>10B" with an optional K, M, B, or T suffix.
+func parseScreenerFilter(s string) (screenerFilter, error) {
+	i := strings.IndexAny(s, "<>")
+	if i <= 0 {
+		return screenerFilter{}, fmt.Errorf("bad filter: %q", s)
+	}
+	f := screenerFilter{
+		field: strings.ToLower(s[:i]),
+		less:  s[i] == '<',
+	}
+	if f.field != "pe" && f.field != "cap" {
+		return screenerFilter{}, fmt.Errorf("unknown filter field: %q", f.field)
+	}
+
+	v := strings.ToUpper(s[i+1:])
+	mult := 1.0
+	if n := len(v); n > 0 {
+		switch v[n-1] {
+		case 'K':
+			mult = 1e3
+		case 'M':
+			mult = 1e6
+		case 'B':
+			mult = 1e9
+		case 'T':
+			mult = 1e12
+		}
+		if mult != 1 {
+			v = v[:n-1]
+		}
+	}
+	value, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return screenerFilter{}, fmt.Errorf("bad filter value: %q", s)
+	}
+	f.value = value * mult
+	return f, nil
+}
+
+// matches returns whether the quote meets the condition. Quotes without the field never match.
+func (f screenerFilter) matches(q screenerQuote) bool {
+	v := q.pe
+	if f.field == "cap" {
+		v = q.marketCap
+	}
+	if v == 0 {
+		return false
+	}
+	if f.less {
+		return v < f.value
+	}
+	return v > f.value
+}
+
+// getScreenerQuotes gets the quotes of Yahoo's predefined screener that meet all the filters.
+func getScreenerQuotes(ctx context.Context, name string, filters []screenerFilter) ([]screenerQuote, error) {
+	id, ok := screenerIDs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown screener: %q", name)
+	}
+
+	v := url.Values{}
+	v.Set("scrIds", id)
+	v.Set("count", "100")
+
+	u, err := url.Parse("https://query1.finance.yahoo.com/v1/finance/screener/predefined/saved")
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = v.Encode()
+	resp, err := httpGet(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		Finance struct {
+			Error  *yahooError
+			Result []struct {
+				Quotes []struct {
+					Symbol                     string
+					ShortName                  string
+					RegularMarketPrice         float64
+					RegularMarketChangePercent float64
+					MarketCap                  float64
+					TrailingPE                 float64
+				}
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if parsed.Finance.Error != nil {
+		return nil, parsed.Finance.Error
+	}
+	if len(parsed.Finance.Result) == 0 {
+		return nil, fmt.Errorf("no results for screener: %q", name)
+	}
+
+	var qs []screenerQuote
+	for _, p := range parsed.Finance.Result[0].Quotes {
+		q := screenerQuote{
+			symbol:        p.Symbol,
+			name:          p.ShortName,
+			price:         p.RegularMarketPrice,
+			percentChange: p.RegularMarketChangePercent / 100,
+			marketCap:     p.MarketCap,
+			pe:            p.TrailingPE,
+		}
+
+		ok := true
+		for _, f := range filters {
+			ok = ok && f.matches(q)
+		}
+		if ok {
+			qs = append(qs, q)
+		}
+	}
+	return qs, nil
+}
+
+// formatMarketCap formats the market cap like "12.3B" or "-" if it is unknown.
+func formatMarketCap(v float64) string {
+	if v == 0 {
+		return "-"
+	}
+	return shortenNumber(math.Round(v))
+}
+
+// printScreener prints the screener's quotes, highlighting the selected one and marking the watched ones.
+func printScreener(title string, qs []screenerQuote, status string, selected int, watched map[string]bool, w, h int) {
+	print := func(x, y int, fg, bg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, bg)
+			x++
+		}
+		return x
+	}
+
+	const format = " %-8s %-24s %10s %9s %8s %7s "
+
+	print(0, 0, termbox.ColorDefault, termbox.ColorDefault, " Screener: %s", title)
+	print(0, 2, termbox.ColorDefault, termbox.ColorDefault, format, "Symbol", "Name", "Price", "Change", "Cap", "P/E")
+
+	// Scroll to keep the selected quote on the screen.
+	rows := h - 5
+	offset := 0
+	if selected >= rows {
+		offset = selected - rows + 1
+	}
+
+	y := 3
+	for i := offset; i < len(qs) && y < h-2; i++ {
+		q := qs[i]
+		fg, bg := termbox.ColorDefault, termbox.ColorDefault
+		if watched[q.symbol] {
+			fg = termbox.ColorCyan
+		}
+		if i == selected {
+			bg = termbox.ColorBlue
+		}
+
+		name := q.name
+		if len(name) > 24 {
+			name = name[:24]
+		}
+		pe := "-"
+		if q.pe != 0 {
+			pe = fmt.Sprintf("%.1f", q.pe)
+		}
+		print(0, y, fg, bg, format,
+			q.symbol,
+			name,
+			fmt.Sprintf("%.2f", q.price),
+			fmt.Sprintf("%+.2f%%", q.percentChange*100),
+			formatMarketCap(q.marketCap),
+			pe)
+		y++
+	}
+
+	if status != "" {
+		print(0, h-2, termbox.ColorYellow, termbox.ColorDefault, " %s", status)
+	}
+	print(0, h-1, termbox.ColorDefault, termbox.ColorDefault, " Up/Down: Select  A: Add to watchlist  Esc: Close")
+}
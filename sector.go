@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nsf/termbox-go"
+)
+
+// unclassifiedSector is the group of stocks without a sector or industry.
+const unclassifiedSector = "Unclassified"
+
+// sectorLookups has the symbols whose sector and industry were already looked up,
+// so the ones without a profile like ETFs aren't looked up again each time.
+var sectorLookups struct {
+	sync.Mutex
+	symbols map[string]bool
+}
+
+// getSectorFromYahoo gets the symbol's sector and industry from Yahoo's asset profile.
+func getSectorFromYahoo(ctx context.Context, symbol string) (sector, industry string, err error) {
+	var result struct {
+		AssetProfile struct {
+			Sector, Industry string
+		}
+	}
+	if err := getQuoteSummary(ctx, symbol, "assetProfile", &result); err != nil {
+		return "", "", err
+	}
+	return result.AssetProfile.Sector, result.AssetProfile.Industry, nil
+}
+
+// refreshSectors fetches the sector and industry of the stocks that have neither and saves them.
+// Sectors and industries set by the user are kept. It returns true if any stock was classified.
+func refreshSectors(ctx context.Context, sd *stockData) bool {
+	if *offline {
+		return false
+	}
+
+	sd.RLock()
+	var symbols []string
+	sectorLookups.Lock()
+	for _, s := range sd.stocks {
+		if s.sector == "" && s.industry == "" && classifySymbol(s.symbol) == equityAsset && !sectorLookups.symbols[s.symbol] {
+			symbols = append(symbols, s.symbol)
+		}
+	}
+	sectorLookups.Unlock()
+	sd.RUnlock()
+
+	type classification struct {
+		sector, industry string
+	}
+	cm := map[string]classification{}
+	for _, symbol := range symbols {
+		sector, industry, err := getSectorFromYahoo(ctx, symbol)
+		if err != nil {
+			log.Printf("getSectorFromYahoo(%s): %v", symbol, err)
+			continue
+		}
+
+		sectorLookups.Lock()
+		if sectorLookups.symbols == nil {
+			sectorLookups.symbols = map[string]bool{}
+		}
+		sectorLookups.symbols[symbol] = true
+		sectorLookups.Unlock()
+
+		if sector != "" || industry != "" {
+			cm[symbol] = classification{sector, industry}
+		}
+	}
+	if len(cm) == 0 {
+		return false
+	}
+
+	sd.Lock()
+	defer sd.Unlock()
+	for i, s := range sd.stocks {
+		if c, ok := cm[s.symbol]; ok && s.sector == "" && s.industry == "" {
+			sd.stocks[i].sector, sd.stocks[i].industry = c.sector, c.industry
+		}
+	}
+	saveStockData(sd)
+	return true
+}
+
+// sectorSummary is how a sector or industry's stocks did on their latest trading day.
+type sectorSummary struct {
+	// name is the sector or industry.
+	name string
+
+	// symbols are the symbols of the stocks in the group.
+	symbols []string
+
+	// avgPercentChange is the average percent change of the stocks with data.
+	avgPercentChange float64
+
+	// count is the number of stocks with data in the average.
+	count int
+}
+
+// stockGroup returns the stock's sector or industry or unclassifiedSector if it has none.
+func stockGroup(s stock, byIndustry bool) string {
+	g := s.sector
+	if byIndustry {
+		g = s.industry
+	}
+	if g == "" {
+		return unclassifiedSector
+	}
+	return g
+}
+
+// summarizeSectors groups the stocks by sector or industry and returns the groups
+// from the best to the worst average percent change with the unclassified stocks last.
+func summarizeSectors(stocks []stock, byIndustry bool) []sectorSummary {
+	gm := map[string]*sectorSummary{}
+	var names []string
+	for _, s := range stocks {
+		name := stockGroup(s, byIndustry)
+		ss, ok := gm[name]
+		if !ok {
+			ss = &sectorSummary{name: name}
+			gm[name] = ss
+			names = append(names, name)
+		}
+		ss.symbols = append(ss.symbols, s.symbol)
+		if ts, ok := latestTradingSession(s.tradingSessionMap); ok {
+			ss.avgPercentChange += ts.percentChange
+			ss.count++
+		}
+	}
+
+	var sss []sectorSummary
+	for _, name := range names {
+		ss := gm[name]
+		if ss.count > 0 {
+			ss.avgPercentChange /= float64(ss.count)
+		}
+		sss = append(sss, *ss)
+	}
+	sort.SliceStable(sss, func(i, j int) bool {
+		if (sss[i].name == unclassifiedSector) != (sss[j].name == unclassifiedSector) {
+			return sss[j].name == unclassifiedSector
+		}
+		return sss[i].avgPercentChange > sss[j].avgPercentChange
+	})
+	return sss
+}
+
+// printSectors prints the average latest percent change of each sector or industry and its stocks.
+func printSectors(sd *stockData, byIndustry bool, w, h int) {
+	print := func(x, y int, fg termbox.Attribute, format string, a ...interface{}) int {
+		for _, rune := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, rune, fg, termbox.ColorDefault)
+			x++
+		}
+		return x
+	}
+
+	group := "Sector"
+	if byIndustry {
+		group = "Industry"
+	}
+
+	const format = " %-24s %8s  %s"
+
+	print(0, 0, termbox.ColorDefault, " Latest change by %s", strings.ToLower(group))
+	print(0, 2, termbox.ColorDefault, format, group, "Avg", "Stocks")
+
+	y := 3
+	for _, ss := range summarizeSectors(sd.stocks, byIndustry) {
+		if y >= h-2 {
+			break
+		}
+
+		fg := termbox.ColorDefault
+		change := "-"
+		if ss.count > 0 {
+			change = markChange(fmt.Sprintf("%+.2f%%", ss.avgPercentChange*100), ss.avgPercentChange)
+			fg = changeFgColor(ss.avgPercentChange)
+		}
+
+		name := ss.name
+		if len(name) > 24 {
+			name = name[:24]
+		}
+		x := print(0, y, termbox.ColorDefault, " %-24s ", name)
+		x = print(x, y, fg, "%8s", change)
+		members := strings.Join(ss.symbols, " ")
+		if avail := w - x - 2; avail > 0 && len(members) > avail {
+			members = members[:avail]
+		}
+		print(x, y, termbox.ColorDefault, "  %s", members)
+		y++
+	}
+
+	print(0, h-1, termbox.ColorDefault, " Tab: Sector/Industry  F11: Close")
+}
@@ -0,0 +1,12 @@
+package main
+
+// defaultSectorETFs are the SPDR sector ETFs shown in the sector strip when the config has none.
+var defaultSectorETFs = []string{"XLB", "XLC", "XLE", "XLF", "XLI", "XLK", "XLP", "XLRE", "XLU", "XLV", "XLY"}
+
+// displaySectorETFs returns the configured sector ETFs or the default ones if there are none.
+func displaySectorETFs(symbols []string) []string {
+	if len(symbols) == 0 {
+		return defaultSectorETFs
+	}
+	return symbols
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyShutdown cancels the context when the process is asked to stop like with kill,
+// so that the fetches in flight stop and the state is saved before exiting.
+// A second signal stops the process right away.
+func notifyShutdown(cancel context.CancelFunc) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		signal.Stop(sigs)
+		cancel()
+	}()
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyRefresh relays requests to refresh from outside like kill -USR1 to the channel.
+func notifyRefresh(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// notifyRefresh does nothing, since Windows has no user defined signals.
+func notifyRefresh(c chan<- os.Signal) {}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// snapshot has the watchlist's aggregate statistics at the end of a trading day.
+type snapshot struct {
+	// Date is the trading date. Capitalized for JSON decoding.
+	Date time.Time
+
+	// AvgPercentChange is the average percent change of the stocks. Capitalized for JSON decoding.
+	AvgPercentChange float64
+
+	// Advancers and Decliners are the number of stocks that went up and down. Capitalized for JSON decoding.
+	Advancers, Decliners int
+
+	// PortfolioValue is the market value of the user's positions. Capitalized for JSON decoding.
+	PortfolioValue float64
+
+	// Values is a map from symbol to the market value of the user's position in it.
+	// Capitalized for JSON decoding.
+	Values map[string]float64
+}
+
+// snapshotMutex prevents snapshot file reads and writes from conflicting.
+var snapshotMutex sync.Mutex
+
+// loadSnapshots loads the saved snapshots in chronological order.
+func loadSnapshots() ([]snapshot, error) {
+	snapshotMutex.Lock()
+	defer snapshotMutex.Unlock()
+	return loadSnapshotsLocked()
+}
+
+func loadSnapshotsLocked() ([]snapshot, error) {
+	p, err := getUserSnapshotsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(p)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	defer file.Close()
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var ss []snapshot
+	if err := json.NewDecoder(file).Decode(&ss); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// saveSnapshot adds or replaces the snapshot for its date and saves all the snapshots.
+func saveSnapshot(s snapshot) error {
+	snapshotMutex.Lock()
+	defer snapshotMutex.Unlock()
+
+	ss, err := loadSnapshotsLocked()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range ss {
+		if ss[i].Date.Equal(s.Date) {
+			ss[i], replaced = s, true
+		}
+	}
+	if !replaced {
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].Date.Before(ss[j].Date)
+	})
+
+	p, err := getUserSnapshotsPath()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(ss)
+}
+
+func getUserSnapshotsPath() (string, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dirPath, "snapshots.json"), nil
+}
+
+// portfolioValue returns the market value of the user's Alpaca positions and lots at the latest closes.
+// The caller must hold the stockData read lock.
+func portfolioValue(sd *stockData) float64 {
+	var value float64
+	for _, v := range positionValues(sd) {
+		value += v
+	}
+	return value
+}
+
+// positionValues returns a map from symbol to the market value of the user's Alpaca position or lots
+// at the latest close. The caller must hold the stockData read lock.
+func positionValues(sd *stockData) map[string]float64 {
+	vm := map[string]float64{}
+	for _, s := range sd.stocks {
+		ts, ok := latestTradingSession(s.tradingSessionMap)
+		if !ok {
+			continue
+		}
+		if p, ok := sd.positions[s.symbol]; ok {
+			vm[s.symbol] = p.quantity * ts.close
+			continue
+		}
+		if q := totalQuantity(s.lots); q != 0 {
+			vm[s.symbol] = q * ts.close
+		}
+	}
+	return vm
+}
+
+// takeSnapshot returns a snapshot of the most recent trading date if the market has closed for the day.
+// The caller must hold the stockData read lock.
+func takeSnapshot(sd *stockData, now time.Time) (snapshot, bool) {
+	if len(sd.tradingDates) == 0 {
+		return snapshot{}, false
+	}
+
+	// Only take a snapshot of today once the closing prices are in.
+	date := sd.tradingDates[len(sd.tradingDates)-1]
+	ny := now.In(newYorkLoc)
+	today := time.Date(ny.Year(), ny.Month(), ny.Day(), 0, 0, 0, 0, time.UTC)
+	if date.Equal(today) && ny.Hour() < 16 {
+		return snapshot{}, false
+	}
+
+	ds := summarizeDay(sd.stocks, date)
+	if ds.count == 0 {
+		return snapshot{}, false
+	}
+
+	return snapshot{
+		Date:             date,
+		AvgPercentChange: ds.avgPercentChange,
+		Advancers:        ds.advancers,
+		Decliners:        ds.decliners,
+		PortfolioValue:   portfolioValue(sd),
+		Values:           positionValues(sd),
+	}, true
+}
+
+// snapshotSeries is a statistic of the snapshots that can be charted.
+type snapshotSeries int
+
+// List of possible snapshotSeries values.
+const (
+	portfolioValueSeries snapshotSeries = iota
+	avgChangeSeries
+	breadthSeries
+	positionValueSeries
+	snapshotSeriesCount
+)
+
+// label returns the name of the series. The symbol is the one of the position value series.
+func (ss snapshotSeries) label(symbol string) string {
+	switch ss {
+	case portfolioValueSeries:
+		return "Portfolio Value"
+	case avgChangeSeries:
+		return "Cumulative Avg % Change"
+	case positionValueSeries:
+		return symbol + " Position Value"
+	default:
+		return "Cumulative Advancers - Decliners"
+	}
+}
+
+// snapshotSessions converts the snapshots into sessions whose closes are the series values so they can be charted.
+// The symbol is the one of the position value series. Snapshots before the start are skipped.
+func snapshotSessions(ss []snapshot, series snapshotSeries, symbol string, start time.Time) []stockTradingSession {
+	var tss []stockTradingSession
+	index, breadth := 100.0, 0.0
+	for _, s := range ss {
+		if s.Date.Before(start) {
+			continue
+		}
+
+		var v float64
+		switch series {
+		case portfolioValueSeries:
+			v = s.PortfolioValue
+		case positionValueSeries:
+			var ok bool
+			if v, ok = s.Values[symbol]; !ok {
+				continue
+			}
+		case avgChangeSeries:
+			index *= 1 + s.AvgPercentChange
+			v = index
+		default:
+			breadth += float64(s.Advancers - s.Decliners)
+			v = breadth
+		}
+		tss = append(tss, stockTradingSession{date: s.Date, close: v})
+	}
+	return tss
+}
+
+// printSnapshots prints a chart of the series of the snapshots over the chart range.
+func printSnapshots(ss []snapshot, series snapshotSeries, symbol string, cr chartRange, w, h int) {
+	for x, rune := range " Watchlist " + series.label(symbol) + " " + cr.label {
+		term.setCell(x, 0, rune, termbox.ColorDefault, termbox.ColorDefault)
+	}
+
+	printChart(0, 2, w-padding, h-4, snapshotSessions(ss, series, symbol, cr.start(chartToday())), nil, defaultPrecision, false, time.Time{})
+
+	keys := " Tab:Series"
+	for _, cr := range chartRanges {
+		keys += fmt.Sprintf("  %c:%s", cr.key, cr.label)
+	}
+	for x, rune := range keys + "  Esc:Back" {
+		term.setCell(x, h-1, rune, termbox.ColorDefault, termbox.ColorDefault)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stockSplit is a stock split found from the jump between two sessions' closes and confirmed by the provider.
+type stockSplit struct {
+	// date is the date of the first session after the split.
+	date time.Time
+
+	// ratio is the number of new shares for each old share like 2 for a 2-for-1 split
+	// or 0.1 for a 1-for-10 reverse split.
+	ratio float64
+}
+
+// splitRatios are the split ratios that are detected, since other jumps are more likely real moves.
+var splitRatios = []float64{
+	1.5, 2, 3, 4, 5, 7, 8, 10, 15, 20,
+	1.0 / 2, 1.0 / 3, 1.0 / 4, 1.0 / 5, 1.0 / 8, 1.0 / 10, 1.0 / 15, 1.0 / 20,
+}
+
+// splitTolerance is how far the jump can be from a split ratio to allow for the day's move.
+const splitTolerance = 0.08
+
+// label returns a short label of the split like "2:1" or "1:10".
+func (sp stockSplit) label() string {
+	if sp.ratio >= 1 {
+		if sp.ratio == math.Trunc(sp.ratio) {
+			return fmt.Sprintf("%g:1", sp.ratio)
+		}
+		return fmt.Sprintf("%g:2", sp.ratio*2)
+	}
+	return fmt.Sprintf("1:%g", math.Round(1/sp.ratio))
+}
+
+// splitOn returns the split on the date if there is one.
+func splitOn(splits []stockSplit, date time.Time) (stockSplit, bool) {
+	for _, sp := range splits {
+		if sp.date.Equal(date) {
+			return sp, true
+		}
+	}
+	return stockSplit{}, false
+}
+
+// adjustForSplits returns raw trading sessions like imported files with the prices and volumes before
+// each known split adjusted to the shares after it, so that they match the adjusted history.
+// Providers' responses are already adjusted and must not be adjusted again.
+func adjustForSplits(tss []tradingSession, splits []stockSplit) []tradingSession {
+	if len(splits) == 0 {
+		return tss
+	}
+
+	adjusted := make([]tradingSession, len(tss))
+	for i, ts := range tss {
+		r := 1.0
+		for _, sp := range splits {
+			if ts.date.Before(sp.date) {
+				r *= sp.ratio
+			}
+		}
+		ts.open /= r
+		ts.high /= r
+		ts.low /= r
+		ts.close /= r
+		ts.volume = int64(float64(ts.volume) * r)
+		adjusted[i] = ts
+	}
+	return adjusted
+}
+
+// splitCandidates returns the jumps in the stock's closes that look like splits and were not checked
+// against the provider's split events yet. The jump must match a split ratio, the volume must jump by
+// about the same ratio, and the next session must not reverse the jump. The caller must hold the read lock.
+func splitCandidates(s stock) []stockSplit {
+	var dates []time.Time
+	for date := range s.tradingSessionMap {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	var sps []stockSplit
+	for i := 1; i < len(dates); i++ {
+		if _, ok := splitOn(s.splits, dates[i]); ok || s.checkedSplits[dates[i]] {
+			continue
+		}
+
+		prev, cur := s.tradingSessionMap[dates[i-1]], s.tradingSessionMap[dates[i]]
+		ratio, ok := matchSplitRatio(prev, cur)
+		if !ok {
+			continue
+		}
+
+		// A split leaves the price at its new level while a bad quote or a crash often bounces back.
+		reversed := func(j int) bool {
+			if j < 1 || j >= len(dates) {
+				return false
+			}
+			r, ok := matchSplitRatio(s.tradingSessionMap[dates[j-1]], s.tradingSessionMap[dates[j]])
+			return ok && math.Abs(r*ratio-1) <= splitTolerance
+		}
+		if reversed(i-1) || reversed(i+1) {
+			continue
+		}
+
+		sps = append(sps, stockSplit{date: dates[i], ratio: ratio})
+	}
+	return sps
+}
+
+// matchSplitRatio returns the split ratio that the jump from the previous session matches.
+// The volume must also jump by about the ratio, since the share count changes with the price.
+// It can be off by up to the square root of the ratio either way, so 2:1 allows 1.4x to 2.8x.
+func matchSplitRatio(prev, cur stockTradingSession) (float64, bool) {
+	if prev.close <= 0 || cur.close <= 0 || prev.volume <= 0 || cur.volume <= 0 {
+		return 0, false
+	}
+
+	jump := prev.close / cur.close
+	v := float64(cur.volume) / float64(prev.volume)
+	for _, r := range splitRatios {
+		if math.Abs(jump/r-1) > splitTolerance {
+			continue
+		}
+		if math.Abs(math.Log(v/r)) > math.Abs(math.Log(r))/2 {
+			continue
+		}
+		return r, true
+	}
+	return 0, false
+}
+
+// refreshSplits checks the stocks' split candidates against the provider's split events and adjusts
+// the stocks' sessions for the confirmed ones. Jumps without a split event are real moves, so they
+// are remembered and never adjusted.
+func refreshSplits(ctx context.Context, sd *stockData) {
+	sd.RLock()
+	cm := map[string][]stockSplit{}
+	for _, s := range sd.stocks {
+		if sps := splitCandidates(s); len(sps) > 0 {
+			cm[s.symbol] = sps
+		}
+	}
+	sd.RUnlock()
+
+	confirmed := map[string][]stockSplit{}
+	checked := map[string][]stockSplit{}
+	for symbol, sps := range cm {
+		// Allow for the provider's date being a session off from the jump.
+		start, end := sps[0].date.AddDate(0, 0, -7), sps[len(sps)-1].date.AddDate(0, 0, 7)
+		events, err := getSplitsFromYahoo(ctx, symbol, start, end)
+		if err != nil {
+			// Check the candidates again after the next refresh.
+			log.Printf("getSplitsFromYahoo(%s): %v", symbol, err)
+			continue
+		}
+		checked[symbol] = sps
+		for _, sp := range sps {
+			if e, ok := matchSplitEvent(sp, events); ok {
+				confirmed[symbol] = append(confirmed[symbol], e)
+			} else {
+				log.Printf("no split event for the %s jump of %s on %s", sp.label(), symbol, sp.date.Format("2006-01-02"))
+			}
+		}
+	}
+
+	sd.Lock()
+	defer sd.Unlock()
+	for i, s := range sd.stocks {
+		for _, sp := range checked[s.symbol] {
+			if sd.stocks[i].checkedSplits == nil {
+				sd.stocks[i].checkedSplits = map[time.Time]bool{}
+			}
+			sd.stocks[i].checkedSplits[sp.date] = true
+		}
+		for _, sp := range confirmed[s.symbol] {
+			if _, ok := splitOn(sd.stocks[i].splits, sp.date); ok {
+				continue
+			}
+			log.Printf("adjusting %s for its %s split on %s", s.symbol, sp.label(), sp.date.Format("2006-01-02"))
+			applySplit(sd.stocks[i].tradingSessionMap, sp)
+			sd.stocks[i].splits = append(sd.stocks[i].splits, sp)
+		}
+	}
+}
+
+// matchSplitEvent returns the provider's split event within a week of the jump with about the same ratio.
+// The jump's session is used as the split's date, since that is where the sessions change shares.
+func matchSplitEvent(sp stockSplit, events []stockSplit) (stockSplit, bool) {
+	for _, e := range events {
+		if d := e.date.Sub(sp.date); d < -7*24*time.Hour || d > 7*24*time.Hour {
+			continue
+		}
+		if math.Abs(e.ratio/sp.ratio-1) > splitTolerance {
+			continue
+		}
+		return stockSplit{date: sp.date, ratio: e.ratio}, true
+	}
+	return stockSplit{}, false
+}
+
+// getSplitsFromYahoo gets the symbol's split events from start to end.
+func getSplitsFromYahoo(ctx context.Context, symbol string, startDate, endDate time.Time) ([]stockSplit, error) {
+	v := url.Values{}
+	v.Set("period1", strconv.FormatInt(startDate.Unix(), 10))
+	v.Set("period2", strconv.FormatInt(endDate.Unix(), 10))
+	v.Set("interval", "1d")
+	v.Set("events", "split")
+
+	u, err := url.Parse("https://query1.finance.yahoo.com/v7/finance/download/" + url.PathEscape(providerSymbol(yahoo, symbol)))
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = v.Encode()
+	resp, err := httpGet(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return readSplitsCSV(resp.Body)
+}
+
+// readSplitsCSV reads split events with ratios like 4:1 or 1:10.
+func readSplitsCSV(r io.Reader) ([]stockSplit, error) {
+	var (
+		sps []stockSplit
+		sp  stockSplit
+	)
+	columns := csvColumns{
+		"Date": csvDate(&sp.date, "2006-01-02"),
+		"Stock Splits": func(value string) error {
+			var err error
+			sp.ratio, err = parseSplitRatio(value)
+			return err
+		},
+	}
+	if err := readCSV(r, columns, func() { sps = append(sps, sp) }); err != nil {
+		return nil, err
+	}
+	return sps, nil
+}
+
+// parseSplitRatio parses a ratio of new to old shares like 4:1 or 1/10.
+func parseSplitRatio(value string) (float64, error) {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r == ':' || r == '/' })
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("bad split ratio: %q", value)
+	}
+	n, err := parseFloat(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	d, err := parseFloat(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 || d <= 0 {
+		return 0, fmt.Errorf("bad split ratio: %q", value)
+	}
+	return n / d, nil
+}
+
+// applySplit adjusts the sessions before the split to the shares after it and
+// recalculates the change of the split's session from the adjusted close.
+func applySplit(tsm map[time.Time]stockTradingSession, sp stockSplit) {
+	var prev stockTradingSession
+	for date, ts := range tsm {
+		if !date.Before(sp.date) {
+			continue
+		}
+		ts.open /= sp.ratio
+		ts.high /= sp.ratio
+		ts.low /= sp.ratio
+		ts.close /= sp.ratio
+		ts.change /= sp.ratio
+		ts.volume = int64(float64(ts.volume) * sp.ratio)
+		tsm[date] = ts
+
+		if date.After(prev.date) {
+			prev = ts
+		}
+	}
+
+	if ts, ok := tsm[sp.date]; ok && prev.close > 0 {
+		ts.change = ts.close - prev.close
+		ts.percentChange = ts.change / prev.close
+		tsm[sp.date] = ts
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCandidates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 8, d, 0, 0, 0, 0, time.UTC) }
+	session := func(d int, close float64, volume int64) stockTradingSession {
+		return stockTradingSession{date: day(d), close: close, volume: volume}
+	}
+
+	for _, tt := range []struct {
+		desc     string
+		sessions []stockTradingSession
+		checked  map[time.Time]bool
+		want     []stockSplit
+	}{
+		{
+			desc: "4:1 split with volume jump",
+			sessions: []stockTradingSession{
+				session(27, 500, 1000),
+				session(28, 499, 1100),
+				session(31, 128, 4400),
+				session(32, 130, 4000),
+			},
+			want: []stockSplit{{day(31), 4}},
+		},
+		{
+			desc: "crash without volume jump near the ratio",
+			sessions: []stockTradingSession{
+				session(27, 100, 1000),
+				session(28, 50, 12000),
+				session(31, 48, 9000),
+			},
+		},
+		{
+			desc: "3:2 sized drop on ordinary volume",
+			sessions: []stockTradingSession{
+				session(27, 100, 1000),
+				session(28, 67, 1000),
+				session(31, 66, 1000),
+			},
+		},
+		{
+			desc: "jump that reverses the next session",
+			sessions: []stockTradingSession{
+				session(27, 100, 1000),
+				session(28, 50, 2000),
+				session(31, 100, 1000),
+			},
+		},
+		{
+			desc: "jump already checked against the provider",
+			sessions: []stockTradingSession{
+				session(27, 500, 1000),
+				session(28, 125, 4000),
+			},
+			checked: map[time.Time]bool{day(28): true},
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			s := stock{
+				symbol:            "AAPL",
+				tradingSessionMap: map[time.Time]stockTradingSession{},
+				checkedSplits:     tt.checked,
+			}
+			for _, ts := range tt.sessions {
+				s.tradingSessionMap[ts.date] = ts
+			}
+
+			got := splitCandidates(s)
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitCandidates() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].date.Equal(tt.want[i].date) || got[i].ratio != tt.want[i].ratio {
+					t.Errorf("splitCandidates()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchSplitEvent(t *testing.T) {
+	jump := stockSplit{date: time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC), ratio: 4}
+
+	for _, tt := range []struct {
+		desc   string
+		events []stockSplit
+		wantOK bool
+	}{
+		{"same day", []stockSplit{{time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC), 4}}, true},
+		{"a session off", []stockSplit{{time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC), 4}}, true},
+		{"different ratio", []stockSplit{{time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC), 2}}, false},
+		{"months away", []stockSplit{{time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC), 4}}, false},
+		{"no events", nil, false},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, ok := matchSplitEvent(jump, tt.events)
+			if ok != tt.wantOK {
+				t.Fatalf("matchSplitEvent() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if ok && !got.date.Equal(jump.date) {
+				t.Errorf("matchSplitEvent() date = %v, want the jump's date %v", got.date, jump.date)
+			}
+		})
+	}
+}
+
+func TestReadSplitsCSV(t *testing.T) {
+	sps, err := readSplitsCSV(strings.NewReader("Date,Stock Splits\n2020-08-31,4:1\n2011-05-09,1:10\n"))
+	if err != nil {
+		t.Fatalf("readSplitsCSV() error = %v", err)
+	}
+	if len(sps) != 2 || sps[0].ratio != 4 || sps[1].ratio != 0.1 {
+		t.Errorf("readSplitsCSV() = %v, want ratios 4 and 0.1", sps)
+	}
+
+	if _, err := readSplitsCSV(strings.NewReader("Date,Stock Splits\n2020-08-31,0:1\n")); err == nil {
+		t.Error("readSplitsCSV() with a zero ratio error = nil, want an error")
+	}
+}
+
+func TestApplySplit(t *testing.T) {
+	before := time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC)
+	split := time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC)
+	tsm := map[time.Time]stockTradingSession{
+		before: {date: before, close: 500, volume: 1000},
+		split:  {date: split, close: 128, volume: 4400},
+	}
+
+	applySplit(tsm, stockSplit{date: split, ratio: 4})
+
+	if got := tsm[before]; got.close != 125 || got.volume != 4000 {
+		t.Errorf("session before the split = %+v, want close 125 and volume 4000", got)
+	}
+	if got := tsm[split]; got.change != 3 || got.percentChange != 3.0/125 {
+		t.Errorf("split session change = %v %v, want 3 and %v", got.change, got.percentChange, 3.0/125)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/nsf/termbox-go"
+)
+
+// uiState is where the user left off in the UI, restored at the next startup.
+// It is kept apart from the config, since it changes all the time and isn't a setting.
+type uiState struct {
+	// SelectedSymbol is the selected stock's symbol. Capitalized for JSON decoding.
+	SelectedSymbol string
+
+	// SymbolOffset is how far the grid was scrolled. Capitalized for JSON decoding.
+	SymbolOffset int
+
+	// View is the function key like "F2" of the open full screen view or empty for the grid.
+	// Capitalized for JSON decoding.
+	View string
+
+	// Aggregation is whether the grid showed daily, weekly, or monthly sessions. Capitalized for JSON decoding.
+	Aggregation aggregation
+
+	// Filter is the watchlist filter. Capitalized for JSON decoding.
+	Filter string
+
+	// SectorStrip is whether the sector strip was shown. Capitalized for JSON decoding.
+	SectorStrip bool
+}
+
+// uiStateMutex prevents state file reads and writes from conflicting.
+var uiStateMutex sync.Mutex
+
+// loadUIState loads the UI state saved when ponzi last exited.
+func loadUIState() (uiState, error) {
+	uiStateMutex.Lock()
+	defer uiStateMutex.Unlock()
+
+	p, err := getUserUIStatePath()
+	if err != nil {
+		return uiState{}, err
+	}
+
+	file, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return uiState{}, nil
+	}
+	if err != nil {
+		return uiState{}, err
+	}
+	defer file.Close()
+
+	var st uiState
+	if err := json.NewDecoder(file).Decode(&st); err != nil && err != io.EOF {
+		return uiState{}, err
+	}
+	return st, nil
+}
+
+// saveUIState saves the UI state to restore at the next startup.
+func saveUIState(st uiState) error {
+	uiStateMutex.Lock()
+	defer uiStateMutex.Unlock()
+
+	p, err := getUserUIStatePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return json.NewEncoder(file).Encode(st)
+}
+
+func getUserUIStatePath() (string, error) {
+	dirPath, err := getUserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dirPath, "state.json"), nil
+}
+
+// viewKeyName returns the name like "F2" of a view's function key.
+func viewKeyName(k termbox.Key) string {
+	return fmt.Sprintf("F%d", termbox.KeyF1-k+1)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// requestStats counts the HTTP requests and history cache lookups made during the session.
+var requestStats = struct {
+	// Embedded mutex that guards the counts.
+	sync.Mutex
+
+	// hosts is a map from host to the number of requests made to it.
+	hosts map[string]int
+
+	// cacheHits and cacheMisses are the number of history lookups that did and didn't need a fetch.
+	cacheHits, cacheMisses int
+
+	// notModified is the number of requests served from the response cache after a 304.
+	notModified int
+}{
+	hosts: map[string]int{},
+}
+
+// countRequest counts a request to the host.
+func countRequest(host string) {
+	requestStats.Lock()
+	requestStats.hosts[host]++
+	requestStats.Unlock()
+}
+
+// countNotModified counts a request that was served from the response cache.
+func countNotModified() {
+	requestStats.Lock()
+	requestStats.notModified++
+	requestStats.Unlock()
+}
+
+// countCacheLookup counts a history lookup that was or wasn't already cached.
+func countCacheLookup(hit bool) {
+	requestStats.Lock()
+	if hit {
+		requestStats.cacheHits++
+	} else {
+		requestStats.cacheMisses++
+	}
+	requestStats.Unlock()
+}
+
+// statsLines returns lines describing the cached data, memory usage, and requests for the stats overlay.
+// The caller must hold the stockData read lock.
+func statsLines(sd *stockData) []string {
+	sessions := 0
+	for _, s := range sd.stocks {
+		sessions += len(s.tradingSessionMap)
+	}
+	sessions += len(sd.benchmark.tradingSessionMap)
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	const mb = 1024 * 1024
+	lines := []string{
+		fmt.Sprintf("Stocks             %d", len(sd.stocks)),
+		fmt.Sprintf("Cached Sessions    %d", sessions),
+		fmt.Sprintf("Sessions Estimate  %.2f MB", float64(sessions)*float64(unsafe.Sizeof(stockTradingSession{}))/mb),
+		fmt.Sprintf("Heap In Use        %.2f MB", float64(ms.HeapInuse)/mb),
+	}
+
+	requestStats.Lock()
+	defer requestStats.Unlock()
+
+	hitRate := "-"
+	if total := requestStats.cacheHits + requestStats.cacheMisses; total > 0 {
+		hitRate = fmt.Sprintf("%.0f%% of %d", float64(requestStats.cacheHits)/float64(total)*100, total)
+	}
+	lines = append(lines, fmt.Sprintf("Cache Hit Rate     %s", hitRate), "", "Requests")
+
+	var hosts []string
+	for host := range requestStats.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		lines = append(lines, fmt.Sprintf("%-18s %d", host, requestStats.hosts[host]))
+	}
+	if len(hosts) == 0 {
+		lines = append(lines, "None")
+	}
+	if requestStats.notModified > 0 {
+		lines = append(lines, fmt.Sprintf("%-18s %d", "Not Modified", requestStats.notModified))
+	}
+
+	lines = append(lines, "", "Live Sources")
+	lines = append(lines, liveSourceLines()...)
+
+	return append(lines, "", "F9: Close")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// statusLine is a flag to print a compact line of quotes for status bars and exit.
+	statusLine = flag.String("status_line", "", "Print a compact line of quotes for status bars like tmux, polybar, or i3status and exit. Values: tmux, plain. Use -attach to get the data from the daemon instead of fetching it on every call.")
+
+	// statusSymbols is a flag to choose the symbols of the status line.
+	statusSymbols = flag.String("status_symbols", "", "Comma separated symbols to show in the -status_line. Shows the whole watchlist if empty.")
+
+	// statusInterval is a flag to keep printing the status line for status bars that read lines from a long running command.
+	statusInterval = flag.Duration("status_interval", 0, "Print the -status_line again every interval like 5m instead of exiting.")
+)
+
+// statusLineFormat is how the status line is formatted.
+type statusLineFormat string
+
+// List of possible statusLineFormat values.
+const (
+	tmuxStatusLine  statusLineFormat = "tmux"
+	plainStatusLine                  = "plain"
+)
+
+// formatStatusLine returns a line like "AAPL 112.34 +1.1% MSFT 57.42 -0.3%" of the stocks' latest
+// quotes. The tmux format colors the changes green and red. Prices have the stocks' precisions.
+func formatStatusLine(stocks []stock, assetPrecisions map[string]int, symbols []string, format statusLineFormat) string {
+	sm := map[string]stock{}
+	for _, s := range stocks {
+		sm[s.symbol] = s
+	}
+	if len(symbols) == 0 {
+		for _, s := range stocks {
+			symbols = append(symbols, s.symbol)
+		}
+	}
+
+	var parts []string
+	for _, symbol := range symbols {
+		ts, ok := latestTradingSession(sm[symbol].tradingSessionMap)
+		if !ok {
+			parts = append(parts, symbol+" --")
+			continue
+		}
+
+		change := fmt.Sprintf("%+.1f%%", ts.percentChange*100.0)
+		if format == tmuxStatusLine {
+			switch {
+			case ts.change > 0:
+				change = "#[fg=green]" + change + "#[default]"
+			case ts.change < 0:
+				change = "#[fg=red]" + change + "#[default]"
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", symbol, formatNumber(ts.close, pricePrecision(sm[symbol], assetPrecisions)), change))
+	}
+	return strings.Join(parts, " ")
+}
+
+// printStatusLines prints the status line once or every interval until the context is done.
+// Symbols that are not in the watchlist are added to what is fetched.
+func printStatusLines(ctx context.Context, w io.Writer, sd *stockData, format statusLineFormat, symbols []string, interval time.Duration) error {
+	if format != tmuxStatusLine && format != plainStatusLine {
+		return fmt.Errorf("unrecognized status line format: %s", format)
+	}
+
+	sd.Lock()
+	for _, symbol := range symbols {
+		found := false
+		for _, s := range sd.stocks {
+			if s.symbol == symbol {
+				found = true
+			}
+		}
+		if !found {
+			sd.stocks = append(sd.stocks, stock{symbol: symbol})
+		}
+	}
+	sd.Unlock()
+
+	for {
+		refreshStockData(ctx, sd, "")
+
+		sd.RLock()
+		line := formatStatusLine(sd.stocks, sd.assetPrecisions, symbols, format)
+		sd.RUnlock()
+		fmt.Fprintln(w, line)
+
+		if interval <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clk.after(interval):
+		}
+	}
+}
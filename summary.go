@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// daySummary summarizes how the stocks did on a single trading date.
+type daySummary struct {
+	// count is the number of stocks with a session on the date.
+	count int
+
+	// avgPercentChange is the average percent change of the stocks.
+	avgPercentChange float64
+
+	// advancers and decliners are the number of stocks that went up and down.
+	advancers, decliners int
+
+	// biggestMover is the stock with the largest absolute percent change.
+	biggestMover string
+
+	// biggestMove is the session of the biggest mover.
+	biggestMove stockTradingSession
+}
+
+// summarizeDay summarizes the stocks' sessions on the date.
+func summarizeDay(stocks []stock, date time.Time) daySummary {
+	var ds daySummary
+	var total float64
+	for _, s := range stocks {
+		ts, ok := s.tradingSessionMap[date]
+		if !ok {
+			continue
+		}
+
+		ds.count++
+		total += ts.percentChange
+
+		switch {
+		case ts.change > 0:
+			ds.advancers++
+		case ts.change < 0:
+			ds.decliners++
+		}
+
+		if ds.biggestMover == "" || math.Abs(ts.percentChange) > math.Abs(ds.biggestMove.percentChange) {
+			ds.biggestMover, ds.biggestMove = s.symbol, ts
+		}
+	}
+
+	if ds.count > 0 {
+		ds.avgPercentChange = total / float64(ds.count)
+	}
+	return ds
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// canSuspend is whether the process can be suspended to the shell.
+const canSuspend = true
+
+// notifySuspend relays requests to suspend from outside like kill -TSTP to the channel.
+func notifySuspend(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGTSTP)
+}
+
+// suspendProcess stops the process and returns when the shell continues it like with fg.
+func suspendProcess() error {
+	return syscall.Kill(syscall.Getpid(), syscall.SIGSTOP)
+}
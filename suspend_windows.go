@@ -0,0 +1,17 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// canSuspend is whether the process can be suspended to the shell.
+const canSuspend = false
+
+// notifySuspend does nothing, since Windows has no job control signals.
+func notifySuspend(c chan<- os.Signal) {}
+
+// suspendProcess returns an error, since Windows has no job control signals.
+func suspendProcess() error {
+	return errors.New("suspend not supported")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// shareClassRegexp matches symbols with a share class suffix like "BRK.B".
+var shareClassRegexp = regexp.MustCompile(`^[A-Z]+\.[A-Z]$`)
+
+// symbolMappings is a map from source to a map from display symbol to the source's symbol.
+// The mappings are from the config and take precedence over the default mappings.
+var symbolMappings = struct {
+	// Embedded mutex that guards the map.
+	sync.RWMutex
+	m map[tradingSessionSource]map[string]string
+}{}
+
+// setSymbolMappings sets the config's map from source name to its map of display symbol to the source's symbol.
+func setSymbolMappings(mappings map[string]map[string]string) {
+	m := map[tradingSessionSource]map[string]string{}
+	for source, sm := range mappings {
+		m[tradingSessionSource(source)] = sm
+	}
+	symbolMappings.Lock()
+	symbolMappings.m = m
+	symbolMappings.Unlock()
+}
+
+// providerSymbol returns the symbol the source uses for the display symbol.
+func providerSymbol(source tradingSessionSource, symbol string) string {
+	symbolMappings.RLock()
+	ps, ok := symbolMappings.m[source][symbol]
+	symbolMappings.RUnlock()
+	if ok {
+		return ps
+	}
+
+	if source == yahoo {
+		if ys, ok := yahooSymbols[symbol]; ok {
+			return ys
+		}
+		// Yahoo separates share classes with a dash, since it uses dots for exchange suffixes like ".TO".
+		if shareClassRegexp.MatchString(symbol) {
+			return strings.Replace(symbol, ".", "-", 1)
+		}
+	}
+	return symbol
+}
+
+// providerSymbols returns the symbols the source uses for the display symbols and a map back from them.
+func providerSymbols(source tradingSessionSource, symbols []string) ([]string, map[string]string) {
+	var pss []string
+	dm := map[string]string{}
+	for _, s := range symbols {
+		ps := providerSymbol(source, s)
+		pss = append(pss, ps)
+		dm[ps] = s
+	}
+	return pss, dm
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/nsf/termbox-go"
+)
+
+// tcellScreen is a screen using tcell. It converts the termbox attributes and events the UI uses,
+// so the UI draws the same way on either backend.
+type tcellScreen struct {
+	// screen is the tcell screen. It is created by init if not set.
+	screen tcell.Screen
+
+	// events receives the converted events in the background, so repaints don't wait on input.
+	events chan termbox.Event
+
+	// resized is whether the terminal was resized since the last flush.
+	resized bool
+
+	// outputMode is the color mode that the attributes are in.
+	outputMode termbox.OutputMode
+}
+
+// tcellKeys is a map from tcell's special keys to the termbox keys the UI handles.
+// The control keys share their ASCII values in both, so they are converted as is.
+var tcellKeys = map[tcell.Key]termbox.Key{
+	tcell.KeyF1:     termbox.KeyF1,
+	tcell.KeyF2:     termbox.KeyF2,
+	tcell.KeyF3:     termbox.KeyF3,
+	tcell.KeyF4:     termbox.KeyF4,
+	tcell.KeyF5:     termbox.KeyF5,
+	tcell.KeyF6:     termbox.KeyF6,
+	tcell.KeyF7:     termbox.KeyF7,
+	tcell.KeyF8:     termbox.KeyF8,
+	tcell.KeyF9:     termbox.KeyF9,
+	tcell.KeyF10:    termbox.KeyF10,
+	tcell.KeyF11:    termbox.KeyF11,
+	tcell.KeyF12:    termbox.KeyF12,
+	tcell.KeyInsert: termbox.KeyInsert,
+	tcell.KeyDelete: termbox.KeyDelete,
+	tcell.KeyHome:   termbox.KeyHome,
+	tcell.KeyEnd:    termbox.KeyEnd,
+	tcell.KeyPgUp:   termbox.KeyPgup,
+	tcell.KeyPgDn:   termbox.KeyPgdn,
+	tcell.KeyUp:     termbox.KeyArrowUp,
+	tcell.KeyDown:   termbox.KeyArrowDown,
+	tcell.KeyLeft:   termbox.KeyArrowLeft,
+	tcell.KeyRight:  termbox.KeyArrowRight,
+}
+
+func (s *tcellScreen) init() error {
+	if s.screen == nil {
+		ts, err := tcell.NewScreen()
+		if err != nil {
+			return err
+		}
+		s.screen = ts
+	}
+	if err := s.screen.Init(); err != nil {
+		return err
+	}
+
+	s.events = make(chan termbox.Event, 64)
+	go func() {
+		for {
+			tev := s.screen.PollEvent()
+			if tev == nil {
+				// The screen was finalized.
+				return
+			}
+			if ev, ok := termboxEvent(tev); ok {
+				s.events <- ev
+			}
+		}
+	}()
+
+	// Treat requests to suspend from outside like Ctrl+Z, since the terminal must be restored first.
+	sigs := make(chan os.Signal, 1)
+	notifySuspend(sigs)
+	go func() {
+		for range sigs {
+			s.events <- termbox.Event{Type: termbox.EventKey, Key: termbox.KeyCtrlZ}
+		}
+	}()
+	return nil
+}
+
+// termboxEvent converts a tcell event into the termbox event the UI handles.
+// It returns false for events the UI doesn't use like mouse events and unknown keys.
+func termboxEvent(tev tcell.Event) (termbox.Event, bool) {
+	switch tev := tev.(type) {
+	case *tcell.EventKey:
+		ev := termbox.Event{Type: termbox.EventKey}
+		if tev.Modifiers()&tcell.ModAlt != 0 {
+			ev.Mod = termbox.ModAlt
+		}
+
+		switch k := tev.Key(); {
+		case k == tcell.KeyRune && tev.Rune() == ' ':
+			// termbox reports the space bar as a key rather than a rune.
+			ev.Key = termbox.KeySpace
+		case k == tcell.KeyRune:
+			ev.Ch = tev.Rune()
+		case k <= tcell.KeyDEL:
+			ev.Key = termbox.Key(k)
+		default:
+			key, ok := tcellKeys[k]
+			if !ok {
+				return termbox.Event{}, false
+			}
+			ev.Key = key
+		}
+		return ev, true
+
+	case *tcell.EventResize:
+		w, h := tev.Size()
+		return termbox.Event{Type: termbox.EventResize, Width: w, Height: h}, true
+
+	case *tcell.EventInterrupt:
+		return termbox.Event{Type: termbox.EventInterrupt}, true
+
+	case *tcell.EventError:
+		return termbox.Event{Type: termbox.EventError, Err: tev}, true
+	}
+	return termbox.Event{}, false
+}
+
+func (s *tcellScreen) close() {
+	s.screen.Fini()
+}
+
+// setOutputMode uses the mode if the terminal has enough colors for it and falls back to the normal mode otherwise.
+func (s *tcellScreen) setOutputMode(mode termbox.OutputMode) termbox.OutputMode {
+	switch n := s.screen.Colors(); {
+	case mode == termbox.OutputCurrent:
+		return s.outputMode
+	case mode == termbox.OutputRGB && n >= 1<<24:
+	case mode == termbox.Output256 && n >= 256:
+	default:
+		mode = termbox.OutputNormal
+	}
+	s.outputMode = mode
+	return mode
+}
+
+func (s *tcellScreen) size() (w, h int) {
+	return s.screen.Size()
+}
+
+func (s *tcellScreen) clear() error {
+	s.screen.Clear()
+	return nil
+}
+
+func (s *tcellScreen) setCell(x, y int, ch rune, fg, bg termbox.Attribute) {
+	s.screen.SetContent(x, y, ch, nil, tcellStyle(fg, bg))
+}
+
+// tcellStyle converts the termbox foreground and background attributes into a tcell style.
+func tcellStyle(fg, bg termbox.Attribute) tcell.Style {
+	var attrs tcell.AttrMask
+	for _, a := range []struct {
+		attr  termbox.Attribute
+		tattr tcell.AttrMask
+	}{
+		{termbox.AttrBold, tcell.AttrBold},
+		{termbox.AttrBlink, tcell.AttrBlink},
+		{termbox.AttrDim, tcell.AttrDim},
+		{termbox.AttrUnderline, tcell.AttrUnderline},
+		{termbox.AttrCursive, tcell.AttrItalic},
+		{termbox.AttrReverse, tcell.AttrReverse},
+	} {
+		if fg&a.attr != 0 {
+			attrs |= a.tattr
+		}
+	}
+	return tcell.StyleDefault.Foreground(tcellColor(fg)).Background(tcellColor(bg)).Attributes(attrs)
+}
+
+// tcellColor converts the color of a termbox attribute into a tcell color.
+func tcellColor(a termbox.Attribute) tcell.Color {
+	// RGB colors are stored above the attribute bits.
+	c := a &^ textAttributes
+	if c > textAttributes {
+		r, g, b := termbox.AttributeToRGB(c)
+		return tcell.NewRGBColor(int32(r), int32(g), int32(b))
+	}
+
+	// The named and palette colors are both offset by one, because zero is the default color.
+	if c == termbox.ColorDefault {
+		return tcell.ColorDefault
+	}
+	return tcell.PaletteColor(int(c) - 1)
+}
+
+func (s *tcellScreen) flush() error {
+	// Redraw every cell after a resize like the termbox screen.
+	if s.resized {
+		s.resized = false
+		s.screen.Sync()
+		return nil
+	}
+	s.screen.Show()
+	return nil
+}
+
+func (s *tcellScreen) pollEvent() termbox.Event {
+	ev := <-s.events
+
+	// Suspend to the shell with Ctrl+Z like the termbox screen.
+	if canSuspend && ev.Type == termbox.EventKey && ev.Key == termbox.KeyCtrlZ {
+		if err := s.suspend(); err != nil {
+			log.Printf("suspend: %v", err)
+		}
+		s.resized = true
+		return termbox.Event{Type: termbox.EventResize}
+	}
+
+	ev, resized := skipRepaints(ev, s.events)
+	s.resized = s.resized || resized
+	return ev
+}
+
+// suspend restores the terminal, stops the process until it is continued, and then takes over the terminal again.
+func (s *tcellScreen) suspend() error {
+	if err := s.screen.Suspend(); err != nil {
+		return err
+	}
+	serr := suspendProcess()
+	if err := s.screen.Resume(); err != nil {
+		return err
+	}
+	return serr
+}
+
+func (s *tcellScreen) interrupt() {
+	// Ignore a full queue, since a repaint is already coming then.
+	s.screen.PostEvent(tcell.NewEventInterrupt(nil))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+var (
+	// colorThemeName is a flag to pick the colors that price changes are shown with.
+	colorThemeName = flag.String("color_theme", "default", "Colors to show price changes with: default for green and red or deuteranopia for blue and orange.")
+
+	// changeArrows is a flag to show price changes with ▲ and ▼ instead of + and - signs.
+	changeArrows = flag.Bool("change_arrows", false, "Show price changes with ▲ and ▼ instead of + and - so their direction doesn't depend on color.")
+)
+
+// colorTheme is a set of colors that price changes are shown with.
+type colorTheme struct {
+	// positive and negative are the background colors of the change levels. Requires 256 or 24-bit colors.
+	positive, negative [colorCount]termbox.Attribute
+
+	// up and down are the text colors of gains and losses.
+	up, down termbox.Attribute
+}
+
+// colorThemes is a map from theme name to its colors.
+var colorThemes = map[string]colorTheme{
+	"default": {
+		positive: positiveColors,
+		negative: negativeColors,
+		up:       termbox.ColorGreen,
+		down:     termbox.ColorRed,
+	},
+
+	// Blue and orange stay distinct for red-green color blindness.
+	"deuteranopia": {
+		positive: [colorCount]termbox.Attribute{
+			termbox.Attribute(25),
+			termbox.Attribute(26),
+			termbox.Attribute(27),
+			termbox.Attribute(28),
+			termbox.Attribute(34),
+		},
+		negative: [colorCount]termbox.Attribute{
+			termbox.Attribute(95),
+			termbox.Attribute(131),
+			termbox.Attribute(167),
+			termbox.Attribute(203),
+			termbox.Attribute(209),
+		},
+		up:   termbox.ColorCyan,
+		down: termbox.ColorYellow,
+	},
+}
+
+var (
+	// upColor is the text color of gains.
+	upColor = termbox.ColorGreen
+
+	// downColor is the text color of losses.
+	downColor = termbox.ColorRed
+)
+
+// setColorTheme sets the change colors to the theme's.
+func setColorTheme(name string) error {
+	t, ok := colorThemes[name]
+	if !ok {
+		return fmt.Errorf("unknown color theme: %q", name)
+	}
+	positiveColors, negativeColors = t.positive, t.negative
+	upColor, downColor = t.up, t.down
+	return nil
+}
+
+// changeFgColor returns the text color of the change.
+func changeFgColor(v float64) termbox.Attribute {
+	switch {
+	case v > 0:
+		return upColor
+	case v < 0:
+		return downColor
+	default:
+		return termbox.ColorDefault
+	}
+}
+
+// markChange replaces the sign of the formatted change v like +1.25 with ▲ or ▼ if -change_arrows is set.
+// Unchanged values have no marker.
+func markChange(s string, v float64) string {
+	if !*changeArrows {
+		return s
+	}
+	s = strings.TrimLeft(s, "+-")
+	switch {
+	case v > 0:
+		return "▲" + s
+	case v < 0:
+		return "▼" + s
+	default:
+		return s
+	}
+}
@@ -1,22 +1,132 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log"
+	"os"
 	"time"
 )
 
+// simulatedNow is a flag to run as if the current time was a different time.
+var simulatedNow = flag.String("now", "", "Run as if it is this RFC 3339 time like 2016-11-06T01:30:00-04:00 to check market hours and DST handling.")
+
 // newYorkLoc is the New York timezone.
 var newYorkLoc *time.Location = mustLoadLocation("America/New_York")
 
 // mustLoadLocation loads the requested tz location or panics.
 func mustLoadLocation(name string) *time.Location {
-	loc, err := time.LoadLocation("America/New_York")
+	loc, err := time.LoadLocation(name)
 	if err != nil {
 		panic(fmt.Sprintf("time.LoadLocation: %v", err))
 	}
 	return loc
 }
 
+// displayLoc is the time zone that times are shown in.
+var displayLoc = time.Local
+
+// setDisplayTimeZone sets the time zone to show times in. It keeps the local time zone if the name is empty or invalid.
+func setDisplayTimeZone(name string) {
+	if name == "" {
+		return
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("time.LoadLocation: %v", err)
+		return
+	}
+	displayLoc = loc
+}
+
+// formatDisplayTime formats the time in the display time zone followed by the zone's abbreviation.
+func formatDisplayTime(t time.Time, layout string) string {
+	return t.In(displayLoc).Format(layout + " MST")
+}
+
+// clock tells the current time and waits for durations to pass.
+// The refresh scheduler and market hours logic use it rather than calling time directly.
+type clock interface {
+	// now returns the current time.
+	now() time.Time
+
+	// after returns a channel that receives the time after the duration passes.
+	after(d time.Duration) <-chan time.Time
+}
+
+// clk is the clock used by the app. It is a simulatedClock if the -now flag is set.
+var clk clock = realClock{}
+
+// realClock is a clock using the system time.
+type realClock struct{}
+
+func (realClock) now() time.Time {
+	return time.Now()
+}
+
+func (realClock) after(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// simulatedClock is a clock offset from the system time by a fixed amount.
+type simulatedClock struct {
+	offset time.Duration
+}
+
+func (c simulatedClock) now() time.Time {
+	return time.Now().Add(c.offset)
+}
+
+func (c simulatedClock) after(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	go func() {
+		t := <-time.After(d)
+		ch <- t.Add(c.offset)
+	}()
+	return ch
+}
+
+// setClock sets the clock to simulate the -now flag's time if it is set.
+func setClock() {
+	if *simulatedNow == "" {
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, *simulatedNow)
+	if err != nil {
+		log.Fatalf("bad -now time: %v", err)
+	}
+	clk = simulatedClock{offset: t.Sub(time.Now())}
+}
+
+// nextRefreshDuration returns a duration from now till the next refresh.
+func nextRefreshDuration(now time.Time) time.Duration {
+	// Refresh at the top of the hour to be predictable.
+	nextRefreshTime := now.Add(1 * time.Hour).Truncate(time.Hour)
+
+	// Skip the days that the market is closed, since there won't be any new data.
+	for i := 0; i < 14*24 && !isTradingDay(nextRefreshTime.In(newYorkLoc)); i++ {
+		nextRefreshTime = nextRefreshTime.Add(time.Hour)
+	}
+	return nextRefreshTime.Sub(now)
+}
+
+// scheduleRefreshes calls refresh at the next refresh time according to the clock
+// or when a signal arrives until the context is done.
+func scheduleRefreshes(ctx context.Context, refresh func(), signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clk.after(nextRefreshDuration(clk.now())):
+			refresh()
+		case <-signals:
+			refresh()
+		}
+	}
+}
+
 // midnight returns the given time with the hours, minutes, seconds, and nanoseconds set to zero.
 func midnight(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock for tests that only moves forward when advanced.
+type fakeClock struct {
+	sync.Mutex
+
+	// t is the current time of the clock.
+	t time.Time
+
+	// timers are the channels waiting for the clock to reach their times.
+	timers []fakeTimer
+
+	// changed is broadcast when a timer is added, so tests can wait for goroutines to block.
+	changed *sync.Cond
+}
+
+// fakeTimer is a channel waiting for a fakeClock to reach a time.
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// newFakeClock returns a fakeClock stopped at the given time.
+func newFakeClock(t time.Time) *fakeClock {
+	c := &fakeClock{t: t}
+	c.changed = sync.NewCond(&c.Mutex)
+	return c
+}
+
+func (c *fakeClock) now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.t
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{c.t.Add(d), ch})
+	c.changed.Broadcast()
+	return ch
+}
+
+// advance moves the clock forward and fires the pending timers that are due in time order.
+func (c *fakeClock) advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.t = c.t.Add(d)
+	sort.Slice(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+
+	var pending []fakeTimer
+	for _, tm := range c.timers {
+		if tm.at.After(c.t) {
+			pending = append(pending, tm)
+			continue
+		}
+		tm.ch <- tm.at
+	}
+	c.timers = pending
+}
+
+// waitForTimers blocks until at least n timers are pending.
+func (c *fakeClock) waitForTimers(n int) {
+	c.Lock()
+	defer c.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+// pendingTimers returns how many timers have not fired yet.
+func (c *fakeClock) pendingTimers() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.timers)
+}
+
+// useClock sets the app's clock for the duration of the test.
+func useClock(t *testing.T, c clock) {
+	old := clk
+	clk = c
+	t.Cleanup(func() { clk = old })
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2020, 8, 28, 9, 0, 0, 0, newYorkLoc)
+	c := newFakeClock(start)
+
+	soon := c.after(time.Minute)
+	later := c.after(time.Hour)
+
+	c.advance(30 * time.Second)
+	select {
+	case <-soon:
+		t.Fatal("timer fired before its time")
+	default:
+	}
+
+	c.advance(30 * time.Second)
+	select {
+	case got := <-soon:
+		if want := start.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("timer fired with %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("timer did not fire at its time")
+	}
+
+	if got := c.pendingTimers(); got != 1 {
+		t.Errorf("pendingTimers() = %d, want 1", got)
+	}
+
+	c.advance(2 * time.Hour)
+	select {
+	case <-later:
+	default:
+		t.Fatal("timer did not fire after advancing past its time")
+	}
+	if got, want := c.now(), start.Add(2*time.Hour+time.Minute); !got.Equal(want) {
+		t.Errorf("now() = %v, want %v", got, want)
+	}
+}
+
+func TestNextRefreshDuration(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		now  time.Time
+		want time.Duration
+	}{
+		{
+			desc: "middle of a trading day",
+			now:  time.Date(2020, 8, 27, 14, 30, 0, 0, newYorkLoc),
+			want: 30 * time.Minute,
+		},
+		{
+			desc: "friday night skips the weekend",
+			now:  time.Date(2020, 8, 28, 23, 15, 0, 0, newYorkLoc),
+			want: 48*time.Hour + 45*time.Minute,
+		},
+		{
+			desc: "weekend with the spring forward",
+			now:  time.Date(2020, 3, 6, 23, 0, 0, 0, newYorkLoc),
+			want: 49*time.Hour - time.Hour,
+		},
+		{
+			desc: "holiday",
+			now:  time.Date(2020, 12, 24, 23, 0, 0, 0, newYorkLoc),
+			want: 73 * time.Hour,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := nextRefreshDuration(tt.now); got != tt.want {
+				t.Errorf("nextRefreshDuration(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleRefreshes(t *testing.T) {
+	c := newFakeClock(time.Date(2020, 8, 28, 14, 30, 0, 0, newYorkLoc))
+	useClock(t, c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	refreshes := make(chan time.Time)
+	done := make(chan bool)
+	go func() {
+		scheduleRefreshes(ctx, func() { refreshes <- c.now() }, signals)
+		close(done)
+	}()
+
+	// Nothing happens until the top of the hour.
+	c.waitForTimers(1)
+	c.advance(29 * time.Minute)
+	select {
+	case got := <-refreshes:
+		t.Fatalf("refreshed early at %v", got)
+	default:
+	}
+
+	c.advance(time.Minute)
+	if got, want := <-refreshes, time.Date(2020, 8, 28, 15, 0, 0, 0, newYorkLoc); !got.Equal(want) {
+		t.Errorf("refreshed at %v, want %v", got, want)
+	}
+
+	// A signal refreshes right away without moving the clock.
+	c.waitForTimers(1)
+	signals <- os.Interrupt
+	if got, want := <-refreshes, time.Date(2020, 8, 28, 15, 0, 0, 0, newYorkLoc); !got.Equal(want) {
+		t.Errorf("refreshed on signal at %v, want %v", got, want)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestScheduleRefreshesSkipsWeekend(t *testing.T) {
+	c := newFakeClock(time.Date(2020, 8, 28, 23, 15, 0, 0, newYorkLoc))
+	useClock(t, c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	refreshes := make(chan time.Time, 1)
+	done := make(chan bool)
+	go func() {
+		scheduleRefreshes(ctx, func() { refreshes <- c.now() }, nil)
+		close(done)
+	}()
+
+	c.waitForTimers(1)
+	c.advance(24 * time.Hour)
+	select {
+	case got := <-refreshes:
+		t.Fatalf("refreshed on the weekend at %v", got)
+	default:
+	}
+
+	c.advance(24*time.Hour + 45*time.Minute)
+	if got, want := <-refreshes, time.Date(2020, 8, 31, 0, 0, 0, 0, newYorkLoc); !got.Equal(want) {
+		t.Errorf("refreshed at %v, want %v", got, want)
+	}
+
+	cancel()
+	<-done
+}
@@ -1,20 +1,22 @@
 package main
 
 import (
-	"encoding/csv"
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
+	"math"
 	"math/rand"
-	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
 // tradingSessionSource is a source of trading sessions.
@@ -34,7 +36,7 @@ var randomSources = []tradingSessionSource{
 }
 
 // tradingSessionFunc is a function that returns tradingSessions.
-type tradingSessionFunc func(symbol string, startDate, endDate time.Time) ([]tradingSession, error)
+type tradingSessionFunc func(ctx context.Context, symbol string, startDate, endDate time.Time) ([]tradingSession, error)
 
 func getTradingSessionFunc(source tradingSessionSource) (tradingSessionFunc, error) {
 	switch source {
@@ -45,10 +47,80 @@ func getTradingSessionFunc(source tradingSessionSource) (tradingSessionFunc, err
 	case random:
 		return getTradingSessionsFromRandom, nil
 	default:
+		if p, ok := getPluginSource(source); ok {
+			return p.getTradingSessions, nil
+		}
 		return nil, fmt.Errorf("unrecognized value: %s", source)
 	}
 }
 
+// defaultSource is the data source of the stocks without their own. It is set by the dataSource flag
+// and the source command. The UI changes it while the refresh goroutines read it, so it is guarded by a mutex.
+var defaultSource = struct {
+	sync.RWMutex
+	source tradingSessionSource
+	get    tradingSessionFunc
+}{
+	source: google,
+	get:    getTradingSessionsFromGoogle,
+}
+
+// setDefaultSource sets the data source of the stocks without their own.
+func setDefaultSource(source tradingSessionSource) error {
+	f, err := getTradingSessionFunc(source)
+	if err != nil {
+		return err
+	}
+	defaultSource.Lock()
+	defaultSource.source, defaultSource.get = source, f
+	defaultSource.Unlock()
+	return nil
+}
+
+// getDefaultSource returns the data source of the stocks without their own and its tradingSessionFunc.
+func getDefaultSource() (tradingSessionSource, tradingSessionFunc) {
+	defaultSource.RLock()
+	defer defaultSource.RUnlock()
+	return defaultSource.source, defaultSource.get
+}
+
+// stockSource returns the stock's data source or the default source
+// if it has none or an unrecognized one. If the default source can't serve
+// the symbol, like futures from Google, the first source that can is returned instead.
+func stockSource(symbol string, source tradingSessionSource) tradingSessionSource {
+	if source != "" {
+		if _, err := getTradingSessionFunc(source); err == nil {
+			return source
+		}
+	}
+
+	source, _ = getDefaultSource()
+	if checkSymbol(symbol, source) == nil {
+		return source
+	}
+	for _, s := range randomSources {
+		if checkSymbol(symbol, s) == nil {
+			return s
+		}
+	}
+	return source
+}
+
+// stockTradingSessionFunc returns the tradingSessionFunc of the stock's data source.
+// It returns the default source's if the stock has none or an unrecognized one.
+func stockTradingSessionFunc(source tradingSessionSource) tradingSessionFunc {
+	_, def := getDefaultSource()
+	if source == "" {
+		return def
+	}
+	f, err := getTradingSessionFunc(source)
+	if err != nil {
+		log.Printf("getTradingSessionFunc: %v", err)
+		return def
+	}
+	return f
+}
+
 // tradingSession contains stats from a single trading session.
 type tradingSession struct {
 	date   time.Time
@@ -59,7 +131,7 @@ type tradingSession struct {
 	volume int64
 }
 
-func getTradingSessionsFromRandom(symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
+func getTradingSessionsFromRandom(ctx context.Context, symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
 	for _, v := range rand.Perm(len(randomSources)) {
 		s := randomSources[v]
 		getTradingSessions, err := getTradingSessionFunc(s)
@@ -67,8 +139,11 @@ func getTradingSessionsFromRandom(symbol string, startDate, endDate time.Time) (
 			return nil, err
 		}
 
-		tss, err := getTradingSessions(symbol, startDate, endDate)
+		tss, err := getTradingSessions(ctx, symbol, startDate, endDate)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			log.Printf("tradingFunc %s: %v", s, err)
 			continue
 		}
@@ -77,13 +152,13 @@ func getTradingSessionsFromRandom(symbol string, startDate, endDate time.Time) (
 	return nil, fmt.Errorf("all %d tradingFuncs failed", len(randomSources))
 }
 
-func getTradingSessionsFromGoogle(symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
+func getTradingSessionsFromGoogle(ctx context.Context, symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
 	formatTime := func(date time.Time) string {
 		return date.Format("Jan 02, 2006")
 	}
 
 	v := url.Values{}
-	v.Set("q", symbol)
+	v.Set("q", providerSymbol(google, symbol))
 	v.Set("startdate", formatTime(startDate))
 	v.Set("enddate", formatTime(endDate))
 	v.Set("output", "csv")
@@ -93,94 +168,18 @@ func getTradingSessionsFromGoogle(symbol string, startDate, endDate time.Time) (
 		return nil, err
 	}
 	u.RawQuery = v.Encode()
-	log.Printf("GET %s", u)
-
-	resp, err := http.Get(u.String())
+	resp, err := httpGet(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var tss []tradingSession
-	r := csv.NewReader(resp.Body)
-	for i := 0; ; i++ {
-		record, err := r.Read()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
-		}
-
-		// format: Date, Open, High, Low, Close, Volume
-		if len(record) != 6 {
-			return nil, fmt.Errorf("record length should be 6, got %d", len(record))
-		}
-
-		// skip header row
-		if i != 0 {
-			parseRecordTime := func(i int) (time.Time, error) {
-				return time.Parse("2-Jan-06", record[i])
-			}
-
-			parseRecordFloat := func(i int) (float64, error) {
-				return parseFloat(record[i])
-			}
-
-			parseRecordInt := func(i int) (int64, error) {
-				return strconv.ParseInt(record[i], 10, 64)
-			}
-
-			date, err := parseRecordTime(0)
-			if err != nil {
-				return nil, err
-			}
-
-			open, err := parseRecordFloat(1)
-			if err != nil {
-				return nil, err
-			}
-
-			high, err := parseRecordFloat(2)
-			if err != nil {
-				return nil, err
-			}
-
-			low, err := parseRecordFloat(3)
-			if err != nil {
-				return nil, err
-			}
-
-			close, err := parseRecordFloat(4)
-			if err != nil {
-				return nil, err
-			}
-
-			volume, err := parseRecordInt(5)
-			if err != nil {
-				return nil, err
-			}
-
-			tss = append(tss, tradingSession{
-				date:   date,
-				open:   open,
-				high:   high,
-				low:    low,
-				close:  close,
-				volume: volume,
-			})
-		}
-	}
-
-	// Most recent trading sessions at the front.
-	sort.Reverse(sortableTradingSessions(tss))
-
-	return tss, nil
+	return readTradingSessionsCSV(resp.Body, "2-Jan-06")
 }
 
-func getTradingSessionsFromYahoo(symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
+func getTradingSessionsFromYahoo(ctx context.Context, symbol string, startDate, endDate time.Time) ([]tradingSession, error) {
 	v := url.Values{}
-	v.Set("s", symbol)
+	v.Set("s", providerSymbol(yahoo, symbol))
 	v.Set("a", strconv.Itoa(int(startDate.Month())-1))
 	v.Set("b", strconv.Itoa(startDate.Day()))
 	v.Set("c", strconv.Itoa(startDate.Year()))
@@ -195,139 +194,134 @@ func getTradingSessionsFromYahoo(symbol string, startDate, endDate time.Time) ([
 		return nil, err
 	}
 	u.RawQuery = v.Encode()
-	log.Printf("GET %s", u)
-
-	resp, err := http.Get(u.String())
+	resp, err := httpGet(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var tss []tradingSession
-	r := csv.NewReader(resp.Body)
-	for i := 0; ; i++ {
-		record, err := r.Read()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, err
-		}
-
-		// format: Date, Open, High, Low, Close, Volume, Adj. Close
-		if len(record) != 7 {
-			return nil, fmt.Errorf("record length should be 7, got %d", len(record))
-		}
-
-		// skip header row
-		if i != 0 {
-			parseRecordTime := func(i int) (time.Time, error) {
-				return time.Parse("2006-01-02", record[i])
-			}
-
-			parseRecordFloat := func(i int) (float64, error) {
-				return parseFloat(record[i])
-			}
-
-			parseRecordInt := func(i int) (int64, error) {
-				return strconv.ParseInt(record[i], 10, 64)
-			}
-
-			date, err := parseRecordTime(0)
-			if err != nil {
-				return nil, err
-			}
-
-			open, err := parseRecordFloat(1)
-			if err != nil {
-				return nil, err
-			}
-
-			high, err := parseRecordFloat(2)
-			if err != nil {
-				return nil, err
-			}
-
-			low, err := parseRecordFloat(3)
-			if err != nil {
-				return nil, err
-			}
+	// Ignore adjusted close value to keep Google and Yahoo APIs the same.
+	return readTradingSessionsCSV(resp.Body, "2006-01-02")
+}
 
-			close, err := parseRecordFloat(4)
-			if err != nil {
-				return nil, err
-			}
+// readTradingSessionsCSV reads the trading sessions from CSV with Date, Open, High, Low, Close,
+// and Volume columns. It returns the most recent trading sessions at the front.
+func readTradingSessionsCSV(r io.Reader, dateLayout string) ([]tradingSession, error) {
+	var (
+		tss []tradingSession
+		ts  tradingSession
+	)
+	columns := csvColumns{
+		"Date":   csvDate(&ts.date, dateLayout),
+		"Open":   csvFloat(&ts.open),
+		"High":   csvFloat(&ts.high),
+		"Low":    csvFloat(&ts.low),
+		"Close":  csvFloat(&ts.close),
+		"Volume": csvInt(&ts.volume),
+	}
+	if err := readCSV(r, columns, func() { tss = append(tss, ts) }); err != nil {
+		return nil, err
+	}
 
-			volume, err := parseRecordInt(5)
-			if err != nil {
-				return nil, err
-			}
+	// Most recent trading sessions at the front.
+	sort.Sort(sort.Reverse(sortableTradingSessions(tss)))
 
-			// Ignore adjusted close value to keep Google and Yahoo APIs the same.
+	return dropAnomalousSessions(tss), nil
+}
 
-			tss = append(tss, tradingSession{
-				date:   date,
-				open:   open,
-				high:   high,
-				low:    low,
-				close:  close,
-				volume: volume,
-			})
+// dropAnomalousSessions returns the sessions without the rows that providers sometimes get wrong,
+// which would otherwise show up as absurd changes. Each dropped row is logged as a warning.
+// The sessions must be sorted and only the first of any with the same date is kept.
+func dropAnomalousSessions(tss []tradingSession) []tradingSession {
+	var kept []tradingSession
+	for i, ts := range tss {
+		if reason := sessionAnomaly(ts); reason != "" {
+			log.Printf("dropping session %s: %s: %+v", ts.date.Format("2006-01-02"), reason, ts)
+			continue
+		}
+		if i > 0 && ts.date.Equal(tss[i-1].date) {
+			log.Printf("dropping session %s: duplicate date", ts.date.Format("2006-01-02"))
+			continue
 		}
+		kept = append(kept, ts)
 	}
+	return kept
+}
 
-	// Most recent trading sessions at the front.
-	sort.Reverse(sortableTradingSessions(tss))
-
-	return tss, nil
+// sessionAnomaly returns why the session is invalid or empty if it looks fine.
+// Sources without open, high, and low leave them zero, so only the close is required.
+func sessionAnomaly(ts tradingSession) string {
+	switch {
+	case ts.date.IsZero():
+		return "missing date"
+	case ts.close <= 0:
+		return "non-positive close"
+	case ts.open < 0 || ts.high < 0 || ts.low < 0:
+		return "negative price"
+	case ts.volume < 0:
+		return "negative volume"
+	case ts.high == 0 || ts.low == 0:
+		return ""
+	case ts.high < ts.low:
+		return "high below low"
+	case ts.close < ts.low || ts.close > ts.high:
+		return "close outside the day's range"
+	case ts.open != 0 && (ts.open < ts.low || ts.open > ts.high):
+		return "open outside the day's range"
+	}
+	return ""
 }
 
 type liveTradingSession struct {
 	symbol        string
+	exchange      string
 	timestamp     time.Time
 	price         float64
 	change        float64
 	percentChange float64
 }
 
-func getLiveTradingSessions(symbols []string) ([]liveTradingSession, error) {
+func getLiveTradingSessionsFromGoogle(ctx context.Context, symbols []string) ([]liveTradingSession, error) {
+	// Map the Google symbols in the response back to the requested ones.
+	gs, sm := providerSymbols(google, symbols)
+
 	v := url.Values{}
 	v.Set("client", "ig")
-	v.Set("q", strings.Join(symbols, ","))
+	v.Set("q", strings.Join(gs, ","))
 
 	u, err := url.Parse("http://www.google.com/finance/info")
 	if err != nil {
 		return nil, err
 	}
 	u.RawQuery = v.Encode()
-	log.Printf("GET %s", u)
-
-	resp, err := http.Get(u.String())
+	resp, err := httpGet(ctx, u.String())
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	return readLiveTradingSessionsJSON(resp.Body, sm)
+}
+
+// readLiveTradingSessionsJSON reads the live trading sessions from Google's JSON response.
+// The symbol map maps the Google symbols in the response back to the requested ones.
+func readLiveTradingSessionsJSON(r io.Reader, sm map[string]string) ([]liveTradingSession, error) {
 	parsed := []struct {
 		T      string // ticker symbol
+		E      string // exchange
 		L      string // price
 		C      string // change
 		Cp     string // percent change
 		Lt_dts string // time
 	}{}
 
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	// Skip the "//" comment string before the JSON if there is one.
+	br := bufio.NewReader(r)
+	if err := skipJSONPreamble(br); err != nil {
 		return nil, err
 	}
 
-	// Check that data has the expected "//" comment string to trim off.
-	if len(data) < 3 {
-		return nil, fmt.Errorf("expected data should be larger")
-	}
-
-	// Unmarshal the data after the "//" comment string.
-	if err := json.Unmarshal(data[3:], &parsed); err != nil {
+	if err := json.NewDecoder(br).Decode(&parsed); err != nil {
 		return nil, err
 	}
 
@@ -337,7 +331,9 @@ func getLiveTradingSessions(symbols []string) ([]liveTradingSession, error) {
 
 	var lts []liveTradingSession
 	for _, p := range parsed {
-		timestamp, err := time.Parse("2006-01-02T15:04:05Z", p.Lt_dts)
+		// The time is the exchange's local time despite the Z suffix, so parsing it as UTC
+		// would date the afternoon quotes of exchanges ahead of UTC on the next day.
+		timestamp, err := time.ParseInLocation("2006-01-02T15:04:05Z", p.Lt_dts, getExchangeHours(p.E).loc)
 		if err != nil {
 			return nil, fmt.Errorf("p: %+v timestamp: %v", p, err)
 		}
@@ -346,6 +342,9 @@ func getLiveTradingSessions(symbols []string) ([]liveTradingSession, error) {
 		if err != nil {
 			return nil, fmt.Errorf("p: %+v price: %v", p, err)
 		}
+		if price <= 0 {
+			return nil, fmt.Errorf("p: %+v price: not positive", p)
+		}
 
 		var change float64
 		if p.C != "" { // C is empty after market close.
@@ -364,9 +363,15 @@ func getLiveTradingSessions(symbols []string) ([]liveTradingSession, error) {
 			percentChange /= 100.0
 		}
 
+		symbol, ok := sm[p.T]
+		if !ok {
+			symbol = p.T
+		}
+
 		lts = append(lts, liveTradingSession{
-			symbol:        p.T,
-			timestamp:     timestamp,
+			symbol:        symbol,
+			exchange:      p.E,
+			timestamp:     timestamp.UTC(),
 			price:         price,
 			change:        change,
 			percentChange: percentChange,
@@ -376,9 +381,39 @@ func getLiveTradingSessions(symbols []string) ([]liveTradingSession, error) {
 	return lts, nil
 }
 
-// parseFloat removes commas and then calls parseFloat.
+// skipJSONPreamble skips any whitespace and a "//" at the start of the reader,
+// which Google puts before its JSON to prevent it from being run as a script.
+func skipJSONPreamble(br *bufio.Reader) error {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("expected data should be larger")
+			}
+			return err
+		}
+		if !unicode.IsSpace(rune(b[0])) {
+			break
+		}
+		br.Discard(1)
+	}
+	if p, _ := br.Peek(2); string(p) == "//" {
+		br.Discard(2)
+	}
+	return nil
+}
+
+// parseFloat removes commas and then calls parseFloat. It returns an error for values like
+// NaN and Inf, which are never valid quotes.
 func parseFloat(value string) (float64, error) {
-	return strconv.ParseFloat(strings.Replace(value, ",", "", -1), 64)
+	f, err := strconv.ParseFloat(strings.Replace(value, ",", "", -1), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("not a finite number: %q", value)
+	}
+	return f, nil
 }
 
 // sortableTradingSessions is a sortable tradingSession slice.
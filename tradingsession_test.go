@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkSessions reports sessions that should have been dropped or rejected by the readers.
+func checkSessions(t *testing.T, tss []tradingSession) {
+	t.Helper()
+	for _, ts := range tss {
+		for _, v := range []float64{ts.open, ts.high, ts.low, ts.close} {
+			if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+				t.Errorf("session has a bad price: %+v", ts)
+			}
+		}
+		if ts.close == 0 || ts.volume < 0 || ts.date.IsZero() {
+			t.Errorf("session should have been dropped: %+v", ts)
+		}
+	}
+}
+
+func FuzzReadTradingSessionsCSV(f *testing.F) {
+	f.Add([]byte("Date,Open,High,Low,Close,Volume\n2020-08-28,500.00,505.00,495.00,499.23,46907479\n"))
+	f.Add([]byte("\ufeffDate,Open,High,Low,Close,Volume\n2020-08-27,1,2,0.5,1.5,10\n2020-08-28,NaN,2,1,1.5,-3\n"))
+	f.Add([]byte("Date,Close,Volume\n2020-08-28,\"1,234.50\",1\n"))
+	f.Add([]byte(""))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tss, err := readTradingSessionsCSV(bytes.NewReader(data), "2006-01-02")
+		if err != nil {
+			return
+		}
+		checkSessions(t, tss)
+	})
+}
+
+func FuzzReadLiveTradingSessionsJSON(f *testing.F) {
+	f.Add([]byte(`// [{"t":"AAPL","e":"NASDAQ","l":"499.23","c":"-0.81","cp":"-0.16","lt_dts":"2020-08-28T16:00:00Z"}]`))
+	f.Add([]byte(`[{"t":"7203","e":"TYO","l":"6,870","c":"","cp":"","lt_dts":"2020-08-28T15:00:00Z"}]`))
+	f.Add([]byte(`  //`))
+	f.Add([]byte(`[]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		lts, err := readLiveTradingSessionsJSON(bytes.NewReader(data), nil)
+		if err != nil {
+			return
+		}
+		for _, l := range lts {
+			if math.IsNaN(l.price) || math.IsInf(l.price, 0) || l.price <= 0 {
+				t.Errorf("session has a bad price: %+v", l)
+			}
+			if math.IsNaN(l.change) || math.IsNaN(l.percentChange) {
+				t.Errorf("session has a bad change: %+v", l)
+			}
+			if l.timestamp.IsZero() {
+				t.Errorf("session has no time: %+v", l)
+			}
+		}
+	})
+}
+
+func TestReadLiveTradingSessionsJSON(t *testing.T) {
+	for _, tt := range []struct {
+		desc    string
+		data    string
+		wantErr bool
+	}{
+		{"comment preamble", `// [{"t":"AAPL","e":"NASDAQ","l":"499.23","lt_dts":"2020-08-28T16:00:00Z"}]`, false},
+		{"no preamble", `[{"t":"AAPL","e":"NASDAQ","l":"499.23","lt_dts":"2020-08-28T16:00:00Z"}]`, false},
+		{"empty", ``, true},
+		{"no entries", `[]`, true},
+		{"negative price", `[{"t":"AAPL","e":"NASDAQ","l":"-1","lt_dts":"2020-08-28T16:00:00Z"}]`, true},
+		{"not a number", `[{"t":"AAPL","e":"NASDAQ","l":"NaN","lt_dts":"2020-08-28T16:00:00Z"}]`, true},
+		{"bad time", `[{"t":"AAPL","e":"NASDAQ","l":"1","lt_dts":"yesterday"}]`, true},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			_, err := readLiveTradingSessionsJSON(strings.NewReader(tt.data), nil)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("readLiveTradingSessionsJSON() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadLiveTradingSessionsJSONTimes(t *testing.T) {
+	for _, tt := range []struct {
+		desc     string
+		exchange string
+		ltDTS    string
+		want     time.Time
+		wantDate time.Time
+	}{
+		{
+			desc:     "close on the day of the spring forward",
+			exchange: "NASDAQ",
+			ltDTS:    "2020-03-09T16:00:00Z",
+			want:     time.Date(2020, 3, 9, 20, 0, 0, 0, time.UTC),
+			wantDate: time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "close before the spring forward",
+			exchange: "NASDAQ",
+			ltDTS:    "2020-03-06T16:00:00Z",
+			want:     time.Date(2020, 3, 6, 21, 0, 0, 0, time.UTC),
+			wantDate: time.Date(2020, 3, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "after-hours quote before the fall back",
+			exchange: "NYSE",
+			ltDTS:    "2020-10-30T19:30:00Z",
+			want:     time.Date(2020, 10, 30, 23, 30, 0, 0, time.UTC),
+			wantDate: time.Date(2020, 10, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "after-hours quote after the fall back",
+			exchange: "NYSE",
+			ltDTS:    "2020-11-02T19:30:00Z",
+			want:     time.Date(2020, 11, 3, 0, 30, 0, 0, time.UTC),
+			wantDate: time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "tokyo afternoon quote",
+			exchange: "TYO",
+			ltDTS:    "2020-08-28T14:59:00Z",
+			want:     time.Date(2020, 8, 28, 5, 59, 0, 0, time.UTC),
+			wantDate: time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			data := fmt.Sprintf(`// [{"t":"X","e":%q,"l":"100.00","lt_dts":%q}]`, tt.exchange, tt.ltDTS)
+			lts, err := readLiveTradingSessionsJSON(strings.NewReader(data), nil)
+			if err != nil {
+				t.Fatalf("readLiveTradingSessionsJSON() error = %v", err)
+			}
+			if got := lts[0].timestamp; !got.Equal(tt.want) {
+				t.Errorf("timestamp = %v, want %v", got, tt.want)
+			}
+			if got := exchangeDate(tt.exchange, lts[0].timestamp); !got.Equal(tt.wantDate) {
+				t.Errorf("exchangeDate() = %v, want %v", got, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestSetDefaultSourceWhileRefreshing(t *testing.T) {
+	defer setDefaultSource(google)
+
+	if err := setDefaultSource("bogus"); err == nil {
+		t.Error("setDefaultSource(bogus) error = nil, want an error")
+	}
+
+	// The source command changes the source while the refresh goroutines read it.
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			stockSource("AAPL", "")
+			stockTradingSessionFunc("")
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		if err := setDefaultSource(randomSources[i%len(randomSources)]); err != nil {
+			t.Fatalf("setDefaultSource() error = %v", err)
+		}
+	}
+	<-done
+
+	if err := setDefaultSource(yahoo); err != nil {
+		t.Fatalf("setDefaultSource(yahoo) error = %v", err)
+	}
+	if got := stockSource("AAPL", ""); got != yahoo {
+		t.Errorf("stockSource() = %q, want %q", got, yahoo)
+	}
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// ui is the state of the terminal UI. The main loop renders it and passes it the input events,
+// while the refresh goroutines only change the stock data and interrupt the screen to repaint it.
+type ui struct {
+	// ctx is canceled when the UI exits to stop any requests still in flight.
+	ctx context.Context
+
+	// sd is the stock data shown in the UI.
+	sd *stockData
+
+	// colors converts the palette colors to the colors that the terminal supports.
+	colors colorMode
+
+	// fg and bg are the colors of the next cells printed.
+	fg, bg termbox.Attribute
+
+	// inputSymbol is the symbol the user is typing in.
+	inputSymbol string
+
+	// inputStatus is the error message shown for a symbol that failed validation.
+	inputStatus string
+
+	// commandOpen is whether the user is typing in a command after ':'.
+	commandOpen bool
+
+	// command is the command the user is typing in.
+	command string
+
+	// commandStatus is the error or help message shown under the command.
+	commandStatus string
+
+	// filterOpen is whether the user is typing in the watchlist filter.
+	filterOpen bool
+
+	// filter hides the stocks whose symbols do not contain it. Empty shows every stock.
+	filter string
+
+	// selectedIndex is the selected index of the user's stock list.
+	selectedIndex int
+
+	// prevHeight tracks the previous height to detect window height changes.
+	prevHeight int
+
+	// symbolOffset is the graphical offset to keep the selected index on-screen.
+	symbolOffset int
+
+	// orderOpen is whether the order popup is showing.
+	orderOpen bool
+
+	// orderSide is the side of the order being entered in the order popup.
+	orderSide tradeSide
+
+	// orderInput is the quantity and optional limit price the user is typing in.
+	orderInput string
+
+	// orderConfirm is the parsed order awaiting confirmation or nil.
+	orderConfirm *alpacaOrder
+
+	// orderStatus is the error or result message shown in the order popup.
+	orderStatus string
+
+	// detailOpen is whether the detail view with the chart of the selected stock is showing.
+	detailOpen bool
+
+	// detailLabel is the label of the detail view's chart range.
+	detailLabel string
+
+	// detailStart and detailEnd are the dates of the detail view's chart range.
+	detailStart, detailEnd time.Time
+
+	// detailInputOpen is whether the user is typing in a custom chart range.
+	detailInputOpen bool
+
+	// detailInput is the custom chart range the user is typing in.
+	detailInput string
+
+	// detailStatus is the error message shown for a bad custom chart range.
+	detailStatus string
+
+	// detailOptions are the indicators and scale of the detail view's chart.
+	detailOptions chartOptions
+
+	// detailMarkDate is the date highlighted in the detail view's chart or zero for none.
+	detailMarkDate time.Time
+
+	// selectedDate is the selected date column or zero if no column is selected.
+	selectedDate time.Time
+
+	// visibleDates are the date columns that fit on the screen during the last repaint.
+	visibleDates []time.Time
+
+	// highlightColumn is whether to highlight the selected date's cell for every stock.
+	highlightColumn bool
+
+	// sectorStrip is whether to show the sector ETFs' changes instead of the exchanges' delays.
+	sectorStrip bool
+
+	// gridAggregation is whether the grid's columns are daily, weekly, or monthly sessions.
+	gridAggregation aggregation
+
+	// layoutNameOpen is whether the user is typing in the name of a chart layout to save.
+	layoutNameOpen bool
+
+	// layoutName is the name of the chart layout the user is typing in.
+	layoutName string
+
+	// backtestOpen is whether the backtest popup is showing.
+	backtestOpen bool
+
+	// backtestInput is the allocation the user is typing in.
+	backtestInput string
+
+	// backtestLines are the results or error shown in the backtest popup.
+	backtestLines []string
+
+	// txOpen is whether the transaction popup is showing.
+	txOpen bool
+
+	// txInput is the transaction the user is typing in.
+	txInput string
+
+	// txStatus is the error message shown for a bad transaction.
+	txStatus string
+
+	// statsOpen is whether the cache and request stats overlay is showing.
+	statsOpen bool
+
+	// filingsOpen is whether the selected stock's SEC filings popup is showing.
+	filingsOpen bool
+
+	// timelineOpen is whether the selected stock's timeline of observed quotes is showing.
+	timelineOpen bool
+
+	// openView is the full screen view that is showing or nil if the grid is showing.
+	openView *view
+
+	// views is a map from function key to the full screen view it opens and closes.
+	views map[termbox.Key]*view
+
+	// screenerTitle, screenerQuotes, screenerStatus, and screenerIndex are the screener's
+	// name and filters, results, status of the last add, and selected result.
+	screenerTitle  string
+	screenerQuotes []screenerQuote
+	screenerStatus string
+	screenerIndex  int
+
+	// screenerView shows the results of the screen command.
+	screenerView *view
+
+	// snapshots are the daily snapshots loaded when the snapshots view was opened.
+	snapshots []snapshot
+
+	// snapshotsSeries is the statistic being charted in the snapshots view.
+	snapshotsSeries snapshotSeries
+
+	// snapshotsRange is the range of dates charted in the snapshots view.
+	snapshotsRange chartRange
+
+	// sectorsByIndustry is whether the sectors view groups the stocks by industry rather than sector.
+	sectorsByIndustry bool
+
+	// compareMarks are the symbols marked with '+' to compare in the compare view.
+	compareMarks map[string]bool
+
+	// compareRangeIndex is the index into chartRanges of the compare view's range.
+	compareRangeIndex int
+
+	// moversSymbols are the symbols listed in the top movers view and moversIndex is the selected one.
+	moversSymbols []string
+	moversIndex   int
+}
+
+// newUI returns the UI showing the stock data with the grid and nothing selected.
+func newUI(ctx context.Context, sd *stockData, colors colorMode) *ui {
+	u := &ui{
+		ctx:               ctx,
+		sd:                sd,
+		colors:            colors,
+		snapshotsRange:    chartRanges[len(chartRanges)-1],
+		compareMarks:      map[string]bool{},
+		compareRangeIndex: 1,
+	}
+	u.screenerView = u.newScreenerView()
+	u.views = u.newViews()
+	return u
+}
+
+// loadState restores where the user left off when ponzi last exited.
+func (u *ui) loadState() {
+	st, err := loadUIState()
+	if err != nil {
+		log.Printf("loadUIState: %v", err)
+	}
+	u.sd.RLock()
+	for i, s := range u.sd.stocks {
+		if s.symbol == st.SelectedSymbol {
+			u.selectedIndex = i
+		}
+	}
+	u.sd.RUnlock()
+	u.filter, u.symbolOffset, u.sectorStrip = st.Filter, st.SymbolOffset, st.SectorStrip
+	_, u.prevHeight = term.size()
+	if st.Aggregation > dailyAggregation && st.Aggregation < aggregationCount {
+		u.setAggregation(st.Aggregation)
+	}
+	for k, v := range u.views {
+		if viewKeyName(k) == st.View && (v.open == nil || v.open()) {
+			u.openView = v
+		}
+	}
+}
+
+// saveState saves where the user left off to restore at the next startup.
+func (u *ui) saveState() {
+	st := uiState{
+		SymbolOffset: u.symbolOffset,
+		Aggregation:  u.gridAggregation,
+		Filter:       u.filter,
+		SectorStrip:  u.sectorStrip,
+	}
+	u.sd.RLock()
+	if u.selectedIndex < len(u.sd.stocks) {
+		st.SelectedSymbol = u.sd.stocks[u.selectedIndex].symbol
+	}
+	u.sd.RUnlock()
+	for k, v := range u.views {
+		if v == u.openView {
+			st.View = viewKeyName(k)
+		}
+	}
+	if err := saveUIState(st); err != nil {
+		log.Printf("saveUIState: %v", err)
+	}
+}
+
+// setDetailRange sets the chart range and backfills the selected stock's data if needed.
+func (u *ui) setDetailRange(label string, start, end time.Time) {
+	u.detailLabel, u.detailStart, u.detailEnd = label, start, end
+
+	u.sd.RLock()
+	symbol := u.sd.stocks[u.selectedIndex].symbol
+	u.sd.RUnlock()
+
+	// Fetch extra data before the start for the moving averages.
+	start = u.detailOptions.warmupStart(start)
+
+	go func() {
+		if backfillStockData(u.ctx, u.sd, symbol, start, end) {
+			term.interrupt()
+		}
+	}()
+}
+
+// applyLayout applies the saved chart layout to the detail view.
+func (u *ui) applyLayout(cl chartLayout) {
+	u.detailOptions = cl.options
+	if cr, ok := findChartRange(cl.rangeLabel); ok {
+		end := chartToday()
+		u.setDetailRange(cr.label, cr.start(end), end)
+		return
+	}
+	u.setDetailRange(cl.rangeLabel, cl.start, cl.end)
+}
+
+// moveSelection selects the next stock in the direction, wrapping around at the ends,
+// and swaps the two stocks if moveStock is true.
+func (u *ui) moveSelection(direction int, moveStock bool) {
+	u.sd.Lock()
+	defer u.sd.Unlock()
+	if len(u.sd.stocks) == 0 {
+		return
+	}
+
+	// Skip over the stocks hidden by the filter.
+	swapIndex := u.selectedIndex
+	for range u.sd.stocks {
+		swapIndex = (swapIndex + direction + len(u.sd.stocks)) % len(u.sd.stocks)
+		if matchesFilter(u.sd.stocks[swapIndex], u.filter) {
+			break
+		}
+	}
+	if moveStock && !*readOnly {
+		// Keep the pinned stocks before the unpinned ones.
+		if u.sd.stocks[u.selectedIndex].pinned != u.sd.stocks[swapIndex].pinned {
+			return
+		}
+
+		u.sd.stocks[u.selectedIndex], u.sd.stocks[swapIndex] = u.sd.stocks[swapIndex], u.sd.stocks[u.selectedIndex]
+		saveStockData(u.sd)
+	}
+	u.selectedIndex = swapIndex
+}
+
+// selectMatch selects the first stock that matches the filter if the selected one is hidden.
+func (u *ui) selectMatch() {
+	u.sd.RLock()
+	defer u.sd.RUnlock()
+	if u.selectedIndex < len(u.sd.stocks) && matchesFilter(u.sd.stocks[u.selectedIndex], u.filter) {
+		return
+	}
+	for i, s := range u.sd.stocks {
+		if matchesFilter(s, u.filter) {
+			u.selectedIndex = i
+			return
+		}
+	}
+}
+
+// addSymbols validates and adds the comma or space separated symbols after the selected stock.
+// It returns the symbols that could not be added and an error message about them.
+func (u *ui) addSymbols(input string) (remaining, status string) {
+	if *readOnly {
+		return input, "Read-only mode"
+	}
+
+	symbols := parseSymbolList(input)
+
+	u.sd.RLock()
+	existing := map[string]int{}
+	for i, s := range u.sd.stocks {
+		existing[s.symbol] = i
+	}
+	u.sd.RUnlock()
+
+	// Jump to the existing stock rather than adding a single symbol again.
+	if len(symbols) == 1 {
+		if i, ok := existing[symbols[0]]; ok {
+			// Clear the filter if it hides the existing stock.
+			if !matchesFilter(stock{symbol: symbols[0]}, u.filter) {
+				u.filter = ""
+			}
+			u.selectedIndex = i
+			return "", ""
+		}
+	}
+
+	// Check that the new symbols have data before adding rows that would never fill in.
+	var added []stock
+	var failed []string
+	for _, symbol := range symbols {
+		if _, ok := existing[symbol]; ok {
+			continue
+		}
+		if err := validateSymbol(u.ctx, symbol); err != nil {
+			log.Printf("validateSymbol: %v", err)
+			failed = append(failed, symbol)
+			status = err.Error()
+			continue
+		}
+		added = append(added, stock{symbol: symbol})
+	}
+
+	// Return the failed symbols to fix or cancel.
+	remaining = strings.Join(failed, ",")
+	if len(failed) > 1 {
+		status = "Not found: " + remaining
+	}
+
+	if len(added) == 0 {
+		return remaining, status
+	}
+
+	u.sd.Lock()
+
+	// Insert the new stocks after the selected stock and after any pinned stocks.
+	i := 0
+	if len(u.sd.stocks) > 0 {
+		i = u.selectedIndex + 1
+	}
+	for i < len(u.sd.stocks) && u.sd.stocks[i].pinned {
+		i++
+	}
+	u.sd.stocks = append(u.sd.stocks[:i], append(added, u.sd.stocks[i:]...)...)
+
+	saveStockData(u.sd)
+	u.selectedIndex = i + len(added) - 1
+	u.sd.Unlock()
+
+	// Clear the filter if it hides the new stock.
+	if !matchesFilter(added[len(added)-1], u.filter) {
+		u.filter = ""
+	}
+
+	// Get initial data for the new stocks in one batch if there are several.
+	if len(added) == 1 {
+		refreshStockData(u.ctx, u.sd, added[0].symbol)
+	} else {
+		refreshStockData(u.ctx, u.sd, "")
+	}
+	return remaining, status
+}
+
+// deleteSelected deletes the selected stock unless the filter hides every stock.
+func (u *ui) deleteSelected() {
+	if *readOnly {
+		return
+	}
+
+	u.sd.Lock()
+	if len(u.sd.stocks) > 0 && matchesFilter(u.sd.stocks[u.selectedIndex], u.filter) {
+		u.sd.stocks = append(u.sd.stocks[:u.selectedIndex], u.sd.stocks[u.selectedIndex+1:]...)
+		saveStockData(u.sd)
+		if u.selectedIndex-1 >= 0 {
+			u.selectedIndex--
+		}
+	}
+	u.sd.Unlock()
+	u.selectMatch()
+}
+
+// setAggregation sets how the grid's columns are aggregated and fetches enough history to fill them.
+func (u *ui) setAggregation(a aggregation) {
+	u.gridAggregation, u.selectedDate = a, time.Time{}
+
+	u.sd.RLock()
+	var symbols []string
+	for _, s := range u.sd.stocks {
+		symbols = append(symbols, s.symbol)
+	}
+	u.sd.RUnlock()
+
+	end := chartToday()
+	start := u.gridAggregation.historyStart(end)
+	go func() {
+		var added bool
+		for _, symbol := range symbols {
+			if backfillStockData(u.ctx, u.sd, symbol, start, end) {
+				added = true
+			}
+		}
+		if added {
+			term.interrupt()
+		}
+	}()
+}
+
+// togglePinned pins or unpins the selected stock and moves it to the end of the pinned stocks.
+func (u *ui) togglePinned() {
+	if *readOnly {
+		return
+	}
+
+	u.sd.Lock()
+	defer u.sd.Unlock()
+	if len(u.sd.stocks) == 0 {
+		return
+	}
+
+	s := u.sd.stocks[u.selectedIndex]
+	s.pinned = !s.pinned
+	u.sd.stocks = append(u.sd.stocks[:u.selectedIndex], u.sd.stocks[u.selectedIndex+1:]...)
+
+	i := 0
+	for i < len(u.sd.stocks) && u.sd.stocks[i].pinned {
+		i++
+	}
+	u.sd.stocks = append(u.sd.stocks, stock{})
+	copy(u.sd.stocks[i+1:], u.sd.stocks[i:])
+	u.sd.stocks[i] = s
+
+	saveStockData(u.sd)
+	u.selectedIndex = i
+}
+
+// compareSymbols returns the marked symbols in watchlist order.
+func (u *ui) compareSymbols() []string {
+	u.sd.RLock()
+	defer u.sd.RUnlock()
+	var symbols []string
+	for _, s := range u.sd.stocks {
+		if u.compareMarks[s.symbol] {
+			symbols = append(symbols, s.symbol)
+		}
+	}
+	return symbols
+}
+
+// compareRange returns the label, start, and end of the compare view's range.
+func (u *ui) compareRange() (string, time.Time, time.Time) {
+	cr, end := chartRanges[u.compareRangeIndex], chartToday()
+	return cr.label, cr.start(end), end
+}
+
+// backfillCompare fetches the history of the compared symbols in the background.
+func (u *ui) backfillCompare() {
+	symbols := u.compareSymbols()
+	_, start, end := u.compareRange()
+	go func() {
+		var added bool
+		for _, symbol := range symbols {
+			if backfillStockData(u.ctx, u.sd, symbol, start, end) {
+				added = true
+			}
+		}
+		if added {
+			term.interrupt()
+		}
+	}()
+}
+
+// runCommand runs a command typed after ':' and returns a message to show if any.
+func (u *ui) runCommand(line string) (string, error) {
+	name, args := parseCommand(line)
+	switch name {
+	case "add":
+		if len(args) == 0 {
+			return "", errors.New("add needs symbols")
+		}
+		remaining, status := u.addSymbols(strings.Join(args, " "))
+		if remaining != "" {
+			return "", errors.New(status)
+		}
+
+	case "delete":
+		u.deleteSelected()
+
+	case "pin":
+		u.togglePinned()
+
+	case "open":
+		u.sd.RLock()
+		var link string
+		if len(u.sd.stocks) > 0 {
+			link = quoteURL(u.sd.quoteURL, u.sd.stocks[u.selectedIndex].symbol)
+		}
+		u.sd.RUnlock()
+		if link != "" {
+			return "", openBrowser(link)
+		}
+
+	case "refresh":
+		refreshStockData(u.ctx, u.sd, "")
+
+	case "filter":
+		u.filter = strings.ToUpper(strings.Join(args, ""))
+		u.selectMatch()
+
+	case "sort":
+		if *readOnly {
+			return "", errors.New("read-only mode")
+		}
+		if len(args) != 1 {
+			return "", errors.New("sort needs a key: symbol, change, percent, volume, or sector")
+		}
+		u.sd.Lock()
+		err := sortStocks(u.sd.stocks, args[0])
+		if err == nil {
+			saveStockData(u.sd)
+		}
+		u.sd.Unlock()
+		return "", err
+
+	case "sector", "industry":
+		if *readOnly {
+			return "", errors.New("read-only mode")
+		}
+		u.sd.Lock()
+		if len(u.sd.stocks) > 0 {
+			s := &u.sd.stocks[u.selectedIndex]
+			if name == "sector" {
+				s.sector = strings.Join(args, " ")
+			} else {
+				s.industry = strings.Join(args, " ")
+			}
+			saveStockData(u.sd)
+		}
+		u.sd.Unlock()
+
+	case "source":
+		if len(args) != 1 {
+			return "", errors.New("source needs a name: google, yahoo, or random")
+		}
+		if err := setDefaultSource(tradingSessionSource(args[0])); err != nil {
+			return "", err
+		}
+		refreshStockData(u.ctx, u.sd, "")
+
+	case "view":
+		if len(args) != 1 {
+			return "", errors.New("view needs daily, weekly, or monthly")
+		}
+		switch args[0] {
+		case "daily":
+			u.setAggregation(dailyAggregation)
+		case "weekly":
+			u.setAggregation(weeklyAggregation)
+		case "monthly":
+			u.setAggregation(monthlyAggregation)
+		default:
+			return "", fmt.Errorf("unknown view: %q", args[0])
+		}
+
+	case "theme":
+		if len(args) != 1 {
+			return "", errors.New("theme needs default or deuteranopia")
+		}
+		return "", setColorTheme(strings.ToLower(args[0]))
+
+	case "screen":
+		if len(args) == 0 {
+			return "", errors.New("screen needs gainers, losers, or active and optional filters like pe<15 cap>10B")
+		}
+		var filters []screenerFilter
+		for _, a := range args[1:] {
+			f, err := parseScreenerFilter(a)
+			if err != nil {
+				return "", err
+			}
+			filters = append(filters, f)
+		}
+		qs, err := getScreenerQuotes(u.ctx, strings.ToLower(args[0]), filters)
+		if err != nil {
+			return "", err
+		}
+		u.screenerTitle, u.screenerQuotes, u.screenerStatus, u.screenerIndex = strings.Join(args, " "), qs, "", 0
+		u.openView = u.screenerView
+
+	case "help":
+		return commandHelp, nil
+
+	case "":
+
+	default:
+		return "", fmt.Errorf("unknown command: %q, try help", name)
+	}
+	return "", nil
+}
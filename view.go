@@ -0,0 +1,252 @@
+package main
+
+import (
+	"log"
+
+	"github.com/nsf/termbox-go"
+)
+
+// view is a full screen view opened from the grid with a function key.
+// Views render themselves and handle their own keys, so the UI only dispatches to them.
+type view struct {
+	// open prepares the view and returns whether it can be shown. Optional.
+	open func() bool
+
+	// render draws the view on the screen.
+	render func(w, h int)
+
+	// handleKey handles the view's own keys and returns true if the key was handled. Optional.
+	// Unhandled Esc and function keys close the view and Ctrl+C quits.
+	handleKey func(ev termbox.Event) bool
+}
+
+// newScreenerView returns the view that shows the results of the screen command.
+func (u *ui) newScreenerView() *view {
+	return &view{
+		render: func(w, h int) {
+			watched := map[string]bool{}
+			u.sd.RLock()
+			for _, s := range u.sd.stocks {
+				watched[s.symbol] = true
+			}
+			u.sd.RUnlock()
+			printScreener(u.screenerTitle, u.screenerQuotes, u.screenerStatus, u.screenerIndex, watched, w, h)
+		},
+		handleKey: func(ev termbox.Event) bool {
+			switch {
+			case ev.Key == termbox.KeyArrowUp:
+				if u.screenerIndex > 0 {
+					u.screenerIndex--
+				}
+			case ev.Key == termbox.KeyArrowDown:
+				if u.screenerIndex+1 < len(u.screenerQuotes) {
+					u.screenerIndex++
+				}
+			case ev.Ch == 'a' || ev.Ch == 'A':
+				if u.screenerIndex >= len(u.screenerQuotes) {
+					break
+				}
+				symbol := u.screenerQuotes[u.screenerIndex].symbol
+				if _, status := u.addSymbols(symbol); status != "" {
+					u.screenerStatus = status
+				} else {
+					u.screenerStatus = "Added " + symbol
+				}
+			default:
+				return false
+			}
+			return true
+		},
+	}
+}
+
+// newViews returns a map from function key to the full screen view it opens and closes.
+func (u *ui) newViews() map[termbox.Key]*view {
+	return map[termbox.Key]*view{
+		termbox.KeyF12: {
+			open: func() bool {
+				u.moversIndex = 0
+				return true
+			},
+			render: func(w, h int) {
+				// Split the rows between the gainers and losers.
+				n := (h - 8) / 2
+				if n < 1 {
+					n = 1
+				}
+				u.sd.RLock()
+				gainers, losers := topMovers(u.sd.stocks, n)
+				u.sd.RUnlock()
+
+				u.moversSymbols = nil
+				for _, m := range append(gainers, losers...) {
+					u.moversSymbols = append(u.moversSymbols, m.symbol)
+				}
+				if u.moversIndex >= len(u.moversSymbols) {
+					u.moversIndex = len(u.moversSymbols) - 1
+				}
+				if u.moversIndex < 0 {
+					u.moversIndex = 0
+				}
+				printMovers(gainers, losers, u.moversIndex, w, h)
+			},
+			handleKey: func(ev termbox.Event) bool {
+				switch ev.Key {
+				case termbox.KeyArrowUp:
+					if u.moversIndex > 0 {
+						u.moversIndex--
+					}
+				case termbox.KeyArrowDown:
+					if u.moversIndex+1 < len(u.moversSymbols) {
+						u.moversIndex++
+					}
+				case termbox.KeyEnter:
+					if u.moversIndex >= len(u.moversSymbols) {
+						return true
+					}
+					symbol := u.moversSymbols[u.moversIndex]
+					u.sd.RLock()
+					for i, s := range u.sd.stocks {
+						if s.symbol == symbol {
+							u.selectedIndex = i
+							// Clear the filter if it hides the stock.
+							if !matchesFilter(s, u.filter) {
+								u.filter = ""
+							}
+						}
+					}
+					u.sd.RUnlock()
+					u.openView = nil
+				default:
+					return false
+				}
+				return true
+			},
+		},
+		termbox.KeyF2: {
+			open: func() bool {
+				// Get the dividends in the background and repaint when they arrive.
+				go func() {
+					refreshDividends(u.ctx, u.sd)
+					term.interrupt()
+				}()
+				return true
+			},
+			render: func(w, h int) {
+				u.sd.RLock()
+				printIncome(u.sd, w, h)
+				u.sd.RUnlock()
+			},
+		},
+		termbox.KeyF3: {
+			open: func() bool {
+				u.sd.RLock()
+				defer u.sd.RUnlock()
+				return len(u.sd.stocks) > 0
+			},
+			render: func(w, h int) {
+				u.sd.RLock()
+				printGains(u.sd, u.sd.stocks[u.selectedIndex], w, h)
+				u.sd.RUnlock()
+
+				if u.txOpen {
+					u.printTransactionPopup(w, h)
+				}
+			},
+			handleKey: func(ev termbox.Event) bool {
+				switch {
+				case u.txOpen:
+					u.handleTransactionKey(ev)
+				case ev.Key == termbox.KeyCtrlT:
+					u.txOpen, u.txInput, u.txStatus = true, "", ""
+				default:
+					return false
+				}
+				return true
+			},
+		},
+		termbox.KeyF4: {
+			render: func(w, h int) {
+				u.sd.RLock()
+				printRisk(u.sd, w, h)
+				u.sd.RUnlock()
+			},
+		},
+		termbox.KeyF6: {
+			render: func(w, h int) {
+				u.sd.RLock()
+				printCorrelations(u.sd, w, h, u.colors)
+				u.sd.RUnlock()
+			},
+		},
+		termbox.KeyF8: {
+			open: func() bool {
+				var err error
+				u.snapshots, err = loadSnapshots()
+				if err != nil {
+					log.Printf("loadSnapshots: %v", err)
+				}
+				return true
+			},
+			render: func(w, h int) {
+				// Chart the selected stock's position value in the position value series.
+				u.sd.RLock()
+				var symbol string
+				if len(u.sd.stocks) > 0 {
+					symbol = u.sd.stocks[u.selectedIndex].symbol
+				}
+				u.sd.RUnlock()
+				printSnapshots(u.snapshots, u.snapshotsSeries, symbol, u.snapshotsRange, w, h)
+			},
+			handleKey: func(ev termbox.Event) bool {
+				if ev.Key == termbox.KeyTab {
+					u.snapshotsSeries = (u.snapshotsSeries + 1) % snapshotSeriesCount
+					return true
+				}
+				for _, cr := range chartRanges {
+					if ev.Ch == cr.key {
+						u.snapshotsRange = cr
+						return true
+					}
+				}
+				return false
+			},
+		},
+		termbox.KeyF11: {
+			render: func(w, h int) {
+				u.sd.RLock()
+				printSectors(u.sd, u.sectorsByIndustry, w, h)
+				u.sd.RUnlock()
+			},
+			handleKey: func(ev termbox.Event) bool {
+				if ev.Key == termbox.KeyTab {
+					u.sectorsByIndustry = !u.sectorsByIndustry
+					return true
+				}
+				return false
+			},
+		},
+		termbox.KeyF10: {
+			open: func() bool {
+				u.backfillCompare()
+				return true
+			},
+			render: func(w, h int) {
+				symbols := u.compareSymbols()
+				label, start, end := u.compareRange()
+				u.sd.RLock()
+				printCompare(u.sd, symbols, label, start, end, w, h)
+				u.sd.RUnlock()
+			},
+			handleKey: func(ev termbox.Event) bool {
+				if ev.Key != termbox.KeyTab {
+					return false
+				}
+				// Cycle through the preset ranges except for MAX, which has no common start.
+				u.compareRangeIndex = (u.compareRangeIndex + 1) % (len(chartRanges) - 1)
+				u.backfillCompare()
+				return true
+			},
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"sort"
+	"time"
+)
+
+// relativeColors is a flag to scale the change colors by each stock's own volatility.
+var relativeColors = flag.Bool("relative_colors", false, "Color price changes by how unusual they are for the stock's own volatility instead of by fixed percentages.")
+
+const (
+	// volatilitySessions is the number of recent sessions that a stock's volatility is measured over.
+	volatilitySessions = 60
+
+	// minVolatilitySessions is the fewest sessions to measure the volatility with. Fixed colors are used with fewer.
+	minVolatilitySessions = 10
+)
+
+// volatility returns the standard deviation of the percent changes of the stock's recent sessions
+// or zero if it doesn't have enough sessions.
+func volatility(tsm map[time.Time]stockTradingSession) float64 {
+	if len(tsm) < minVolatilitySessions {
+		return 0
+	}
+
+	var dates []time.Time
+	for date := range tsm {
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].After(dates[j]) })
+	if len(dates) > volatilitySessions {
+		dates = dates[:volatilitySessions]
+	}
+
+	var sum, sumSquares float64
+	for _, date := range dates {
+		pc := tsm[date].percentChange
+		sum += pc
+		sumSquares += pc * pc
+	}
+	n := float64(len(dates))
+	mean := sum / n
+	return math.Sqrt(math.Max(sumSquares/n-mean*mean, 0))
+}
+
+// colorSession returns the session with its percent change rescaled for picking its color
+// if -relative_colors is set. A move of one standard deviation is colored like a move of the
+// second colorLevels step, so a 2% move in a quiet stock stands out like a 5% move in a
+// volatile one. The session is returned as is if the volatility is unknown.
+func colorSession(ts stockTradingSession, vol float64) stockTradingSession {
+	if !*relativeColors || vol == 0 {
+		return ts
+	}
+	ts.percentChange = ts.percentChange / vol * colorLevels[1]
+	return ts
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVolatility(t *testing.T) {
+	// sessions returns a map of sessions with the percent changes from the oldest to the most recent.
+	sessions := func(pcs ...float64) map[time.Time]stockTradingSession {
+		tsm := map[time.Time]stockTradingSession{}
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, pc := range pcs {
+			date := start.AddDate(0, 0, i)
+			tsm[date] = stockTradingSession{date: date, close: 100, percentChange: pc}
+		}
+		return tsm
+	}
+
+	repeat := func(n int, pcs ...float64) []float64 {
+		var all []float64
+		for i := 0; i < n; i++ {
+			all = append(all, pcs...)
+		}
+		return all
+	}
+
+	for _, tt := range []struct {
+		desc string
+		tsm  map[time.Time]stockTradingSession
+		want float64
+	}{
+		{
+			desc: "too few sessions",
+			tsm:  sessions(0.01, -0.01, 0.01),
+		},
+		{
+			desc: "unchanged",
+			tsm:  sessions(repeat(20, 0)...),
+		},
+		{
+			desc: "alternating moves",
+			tsm:  sessions(repeat(10, 0.02, -0.02)...),
+			want: 0.02,
+		},
+		{
+			desc: "only the recent sessions",
+			tsm:  sessions(append(repeat(50, 0.5, -0.5), repeat(30, 0.01, -0.01)...)...),
+			want: 0.01,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			if got := volatility(tt.tsm); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("volatility() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// watchSymbol is a flag to show a single symbol's price in large digits.
+var watchSymbol = flag.String("watch", "", "Show the symbol's price in large digits with its change and a sparkline.")
+
+// watchRefreshInterval is how often the watched symbol is refreshed during market hours.
+const watchRefreshInterval = time.Minute
+
+// bigGlyphs are the 3x5 glyphs of the characters that the large price can have.
+var bigGlyphs = map[rune][5]string{
+	'0': {"███", "█ █", "█ █", "█ █", "███"},
+	'1': {" █ ", "██ ", " █ ", " █ ", "███"},
+	'2': {"███", "  █", "███", "█  ", "███"},
+	'3': {"███", "  █", "███", "  █", "███"},
+	'4': {"█ █", "█ █", "███", "  █", "  █"},
+	'5': {"███", "█  ", "███", "  █", "███"},
+	'6': {"███", "█  ", "███", "█ █", "███"},
+	'7': {"███", "  █", "  █", "  █", "  █"},
+	'8': {"███", "█ █", "███", "█ █", "███"},
+	'9': {"███", "█ █", "███", "  █", "███"},
+	'.': {"   ", "   ", "   ", "   ", " █ "},
+	'-': {"   ", "   ", "███", "   ", "   "},
+}
+
+// bigText returns the five rows of the text in large glyphs with a space between characters.
+// Characters without glyphs are skipped.
+func bigText(text string) [5]string {
+	var rows [5]string
+	for _, ch := range text {
+		g, ok := bigGlyphs[ch]
+		if !ok {
+			continue
+		}
+		for i := range rows {
+			if rows[i] != "" {
+				rows[i] += " "
+			}
+			rows[i] += g[i]
+		}
+	}
+	return rows
+}
+
+// sparklineBlocks are the blocks of increasing height that draw a sparkline.
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns a line of blocks whose heights follow the values.
+func sparkline(values []float64) string {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > min {
+			i = int((v - min) / (max - min) * float64(len(sparklineBlocks)-1))
+		}
+		b.WriteRune(sparklineBlocks[i])
+	}
+	return b.String()
+}
+
+// runWatch shows the symbol's price in large digits until the user quits.
+// It refreshes every minute during market hours and every hour otherwise.
+func runWatch(ctx context.Context, sd *stockData, symbol string) {
+	sd.Lock()
+	sd.stocks = []stock{{symbol: symbol}}
+	sd.Unlock()
+
+	go func() {
+		for {
+			refreshStockData(ctx, sd, "")
+			term.interrupt()
+
+			d := time.Hour
+			if isMarketHours(clk.now()) {
+				d = watchRefreshInterval
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-clk.after(d):
+			}
+		}
+	}()
+
+	var fg termbox.Attribute
+	print := func(x, y int, format string, a ...interface{}) {
+		for _, ch := range fmt.Sprintf(format, a...) {
+			term.setCell(x, y, ch, fg, termbox.ColorDefault)
+			x++
+		}
+	}
+	printCentered := func(w, y int, s string) {
+		print(w/2-len([]rune(s))/2, y, "%s", s)
+	}
+
+	for {
+		if err := term.clear(); err != nil {
+			log.Fatalf("clear: %v", err)
+		}
+		w, h := term.size()
+		cy := h/2 - 4
+
+		sd.RLock()
+		tsm := sd.stocks[0].tradingSessionMap
+		precision := pricePrecision(sd.stocks[0], sd.assetPrecisions)
+		ts, ok := latestTradingSession(tsm)
+		tss := chartTradingSessions(tsm, time.Time{}, chartToday())
+		refreshTime := sd.refreshTime
+		sd.RUnlock()
+
+		fg = termbox.ColorDefault | termbox.AttrBold
+		printCentered(w, cy, symbol)
+
+		if ok {
+			fg = changeFgColor(ts.change)
+			for i, row := range bigText(fmt.Sprintf("%.*f", precision, ts.close)) {
+				printCentered(w, cy+2+i, row)
+			}
+			printCentered(w, cy+8, fmt.Sprintf("%s (%s%%)", markChange(formatChange(ts.change, precision), ts.change), markChange(fmt.Sprintf("%+.2f", ts.percentChange*100.0), ts.change)))
+
+			// Show as many of the most recent closes as fit in the sparkline.
+			var closes []float64
+			for _, ts := range tss {
+				closes = append(closes, ts.close)
+			}
+			if len(closes) > w-2 {
+				closes = closes[len(closes)-(w-2):]
+			}
+			printCentered(w, cy+10, sparkline(closes))
+		} else {
+			fg = termbox.ColorDefault
+			printCentered(w, cy+4, "No data")
+		}
+
+		fg = termbox.ColorDefault
+		if !refreshTime.IsZero() {
+			print(0, h-1, "Updated %s", formatDisplayTime(refreshTime, "1/2 3:04 PM"))
+		}
+		print(w-len("Esc: Quit"), h-1, "Esc: Quit")
+
+		if err := term.flush(); err != nil {
+			log.Fatalf("flush: %v", err)
+		}
+
+		ev := term.pollEvent()
+		if ev.Type == termbox.EventKey {
+			switch {
+			case ev.Key == termbox.KeyEsc, ev.Key == termbox.KeyCtrlC, ev.Ch == 'q':
+				return
+			}
+		}
+	}
+}